@@ -20,13 +20,34 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/configs": {
+        "/api/v1/admin/attempts/{client_id}": {
             "get": {
-                "description": "Returns the service configs",
+                "description": "Returns the lifetime verification attempts of a client",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Misc"
+                    "Admin"
+                ],
+                "summary": "Get Verification Attempts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "client_id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Get Service Configs",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -34,7 +55,29 @@ const docTemplate = `{
                             "type": "object",
                             "properties": {
                                 "result": {
-                                    "$ref": "#/definitions/responses.AppConfigsResponse"
+                                    "$ref": "#/definitions/responses.VerificationAttemptsResponse"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
                                 }
                             }
                         }
@@ -42,43 +85,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/data": {
-            "get": {
-                "description": "Returns the verification data for a client",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/attempts/{client_id}/reset": {
+            "post": {
+                "description": "Resets the lifetime verification attempts counter and the denial cooldown of a client",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Verification"
+                    "Admin"
                 ],
-                "summary": "Get Verification Data",
+                "summary": "Reset Verification Attempts",
                 "parameters": [
-                    {
-                        "maxLength": 48,
-                        "minLength": 48,
-                        "type": "string",
-                        "description": "TFChain SS58Address",
-                        "name": "X-Client-ID",
-                        "in": "header",
-                        "required": true
-                    },
                     {
                         "type": "string",
-                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}` + "`" + `",
-                        "name": "X-Challenge",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
                         "in": "header",
                         "required": true
                     },
                     {
-                        "maxLength": 128,
-                        "minLength": 128,
+                        "maxLength": 48,
+                        "minLength": 48,
                         "type": "string",
-                        "description": "hex-encoded sr25519|ed25519 signature",
-                        "name": "X-Signature",
-                        "in": "header",
+                        "description": "TFChain SS58Address",
+                        "name": "client_id",
+                        "in": "path",
                         "required": true
                     }
                 ],
@@ -89,13 +120,13 @@ const docTemplate = `{
                             "type": "object",
                             "properties": {
                                 "result": {
-                                    "$ref": "#/definitions/responses.VerificationDataResponse"
+                                    "$ref": "#/definitions/responses.VerificationAttemptsResponse"
                                 }
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -105,8 +136,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -115,9 +146,46 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/partner-keys": {
+            "get": {
+                "description": "Lists the partner API keys, including the revoked ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List Partner API Keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/responses.PartnerAPIKeyResponse"
+                                    }
+                                }
+                            }
+                        }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -139,23 +207,78 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/health": {
-            "get": {
-                "description": "Returns the health status of the service",
+            },
+            "post": {
+                "description": "Creates an API key for a partner service to query verification statuses. The key is only returned once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "Health"
+                    "Admin"
+                ],
+                "summary": "Create Partner API Key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Partner API key",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreatePartnerAPIKeyRequest"
+                        }
+                    }
                 ],
-                "summary": "Health Check",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "result": {
-                                    "$ref": "#/definitions/responses.HealthResponse"
+                                    "$ref": "#/definitions/responses.CreatedPartnerAPIKeyResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
                                 }
                             }
                         }
@@ -163,47 +286,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/status": {
-            "get": {
-                "description": "Returns the verification status for a client",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/admin/partner-keys/{id}": {
+            "delete": {
+                "description": "Revokes a partner API key",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Verification"
+                    "Admin"
                 ],
-                "summary": "Get Verification Status",
+                "summary": "Revoke Partner API Key",
                 "parameters": [
                     {
-                        "maxLength": 48,
-                        "minLength": 48,
                         "type": "string",
-                        "description": "TFChain SS58Address",
-                        "name": "client_id",
-                        "in": "query"
+                        "description": "Admin API key",
+                        "name": "X-Admin-Key",
+                        "in": "header",
+                        "required": true
                     },
                     {
-                        "minLength": 1,
                         "type": "string",
-                        "description": "Twin ID",
-                        "name": "twin_id",
-                        "in": "query"
+                        "description": "Partner API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "result": {
-                                    "$ref": "#/definitions/responses.VerificationStatusResponse"
-                                }
-                            }
-                        }
+                        "description": "OK"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -216,8 +327,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -227,8 +338,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -238,8 +349,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -252,19 +363,16 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/token": {
+        "/api/v1/auth/login": {
             "post": {
-                "description": "Returns a token for a client",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Verifies a signed challenge and returns a short-lived session token bound to the client, to send as ` + "`" + `Authorization: Bearer {token}` + "`" + ` instead of a signature",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Token"
+                    "Auth"
                 ],
-                "summary": "Get or Generate iDenfy Verification Token",
+                "summary": "Login",
                 "parameters": [
                     {
                         "maxLength": 48,
@@ -277,40 +385,35 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}` + "`" + `",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
                         "name": "X-Challenge",
                         "in": "header",
                         "required": true
                     },
                     {
-                        "maxLength": 128,
+                        "maxLength": 130,
                         "minLength": 128,
                         "type": "string",
-                        "description": "hex-encoded sr25519|ed25519 signature",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
                         "name": "X-Signature",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Existing token retrieved",
-                        "schema": {
-                            "type": "object",
-                            "properties": {
-                                "result": {
-                                    "$ref": "#/definitions/responses.TokenResponse"
-                                }
-                            }
-                        }
-                    },
                     "201": {
-                        "description": "New token created",
+                        "description": "Created",
                         "schema": {
                             "type": "object",
                             "properties": {
                                 "result": {
-                                    "$ref": "#/definitions/responses.TokenResponse"
+                                    "$ref": "#/definitions/responses.SessionTokenResponse"
                                 }
                             }
                         }
@@ -337,8 +440,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "402": {
-                        "description": "Payment Required",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -347,9 +450,34 @@ const docTemplate = `{
                                 }
                             }
                         }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    }
+                }
+            }
+        },
+        "/api/v1/challenge/nonce": {
+            "post": {
+                "description": "Issues a single-use nonce to sign as part of a ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + ` challenge",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Get Challenge Nonce",
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.ChallengeNonceResponse"
+                                }
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -369,9 +497,123 @@ const docTemplate = `{
                                 }
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/claims": {
+            "get": {
+                "description": "Returns facts derived from the verification of a client, such as whether the client is an adult, without the raw document data. Only the requested claims are returned, a claim is null if it can not be derived.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verification"
+                ],
+                "summary": "Get Verification Claims",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma-separated claims isAdult|nationality|issuingCountry|documentType|documentValid, all if not set",
+                        "name": "claims",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "type": "object"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "properties": {
@@ -384,13 +626,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/version": {
+        "/api/v1/configs": {
             "get": {
-                "description": "Returns the service version",
+                "description": "Returns the service configs",
                 "tags": [
                     "Misc"
                 ],
-                "summary": "Get Service Version",
+                "summary": "Get Service Configs",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -398,7 +640,7 @@ const docTemplate = `{
                             "type": "object",
                             "properties": {
                                 "result": {
-                                    "$ref": "#/definitions/responses.AppVersionResponse"
+                                    "$ref": "#/definitions/responses.AppConfigsResponse"
                                 }
                             }
                         }
@@ -406,29 +648,104 @@ const docTemplate = `{
                 }
             }
         },
-        "/webhooks/idenfy/id-expiration": {
-            "post": {
-                "description": "Processes the doc expiration notification for a client",
-                "consumes": [
-                    "application/json"
-                ],
+        "/api/v1/consents": {
+            "get": {
+                "description": "Lists the consent grants of the client, including the expired and revoked ones",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Webhooks"
+                    "Consent"
+                ],
+                "summary": "List Consent Grants",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    }
                 ],
-                "summary": "Process Doc Expiration Notification",
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/responses.ConsentGrantResponse"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
                     }
                 }
-            }
-        },
-        "/webhooks/idenfy/verification-update": {
+            },
             "post": {
-                "description": "Processes the verification update for a client",
+                "description": "Grants a partner service access to a subset of the verification data fields and claims of the client, for a limited time. The client signs the consent statement.",
                 "consumes": [
                     "application/json"
                 ],
@@ -436,134 +753,1915 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Webhooks"
+                    "Consent"
+                ],
+                "summary": "Create Consent Grant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Consent statement signed by the client",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateConsentGrantRequest"
+                        }
+                    }
                 ],
-                "summary": "Process Verification Update",
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.ConsentGrantResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/consents/{id}": {
+            "delete": {
+                "description": "Revokes a consent grant of the client",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Consent"
+                ],
+                "summary": "Revoke Consent Grant",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consent grant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/consents/{id}/accesses": {
+            "get": {
+                "description": "Lists the accesses of the partner to the verification data of the client under a consent grant",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Consent"
+                ],
+                "summary": "List Consent Accesses",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Consent grant ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/responses.ConsentAccessResponse"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/data": {
+            "get": {
+                "description": "Returns the verification data for a client",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verification"
+                ],
+                "summary": "Get Verification Data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.VerificationDataResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/health": {
+            "get": {
+                "description": "Returns the health status of the service, it is degraded while a dependency is unavailable or the iDenfy circuit breaker is not closed",
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Health Check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.HealthResponse"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links": {
+            "get": {
+                "description": "Lists the links of the client, as primary or linked account, including the revoked ones",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "List Account Links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/responses.AccountLinkResponse"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Links another account controlled by the verified client, the linked account inherits its verification status. Both accounts sign the link statement.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "Link Account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Link statement signed by both accounts",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateAccountLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.AccountLinkResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/events": {
+            "get": {
+                "description": "Lists the audit trail of the creations and revocations of the links of the client",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "List Account Link Events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "type": "array",
+                                    "items": {
+                                        "$ref": "#/definitions/responses.AccountLinkEventResponse"
+                                    }
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/links/{id}": {
+            "delete": {
+                "description": "Revokes a link of the client, either the primary or the linked account can revoke it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Links"
+                ],
+                "summary": "Revoke Account Link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Account link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/partner/data": {
+            "get": {
+                "description": "Returns the verification data fields and the claims a client granted the partner access to. Every access is logged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Consent"
+                ],
+                "summary": "Get Consented Verification Data",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Partner API key",
+                        "name": "X-API-Key",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "client_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.VerificationDataResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/status": {
+            "get": {
+                "description": "Returns the verification status for a client",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Verification"
+                ],
+                "summary": "Get Verification Status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "partner API key, required unless anonymous status queries are allowed",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "client_id",
+                        "in": "query"
+                    },
+                    {
+                        "minLength": 1,
+                        "type": "string",
+                        "description": "Twin ID",
+                        "name": "twin_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.VerificationStatusResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/token": {
+            "post": {
+                "description": "Returns a token for a client",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Token"
+                ],
+                "summary": "Get or Generate iDenfy Verification Token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "session token from /api/v1/auth/login, as ` + "`" + `Bearer {token}` + "`" + `, instead of a signed challenge",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 48,
+                        "minLength": 48,
+                        "type": "string",
+                        "description": "TFChain SS58Address",
+                        "name": "X-Client-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hex-encoded message ` + "`" + `{api-domain}:{timestamp}:{nonce}` + "`" + `",
+                        "name": "X-Challenge",
+                        "in": "header"
+                    },
+                    {
+                        "maxLength": 130,
+                        "minLength": 128,
+                        "type": "string",
+                        "description": "hex-encoded sr25519|ed25519|ecdsa signature",
+                        "name": "X-Signature",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "signature scheme sr25519|ed25519|ecdsa, detected if not set",
+                        "name": "X-Signature-Scheme",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Existing token retrieved",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.TokenResponse"
+                                }
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "New token created",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.TokenResponse"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "451": {
+                        "description": "Unavailable For Legal Reasons",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "error": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/version": {
+            "get": {
+                "description": "Returns the service version",
+                "tags": [
+                    "Misc"
+                ],
+                "summary": "Get Service Version",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "properties": {
+                                "result": {
+                                    "$ref": "#/definitions/responses.AppVersionResponse"
+                                }
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "description": "Returns the iDenfy client request counters and circuit breaker state in the Prometheus text format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Metrics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/idenfy/id-expiration": {
+            "post": {
+                "description": "Processes the doc expiration notification for a client",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Process Doc Expiration Notification",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        },
+        "/webhooks/idenfy/verification-update": {
+            "post": {
+                "description": "Processes the verification update for a client",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Process Verification Update",
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "config.Admin": {
+            "type": "object",
+            "properties": {
+                "apikey": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Challenge": {
+            "type": "object",
+            "properties": {
+                "allowEcdsa": {
+                    "type": "boolean"
+                },
+                "allowLegacyFormat": {
+                    "type": "boolean"
+                },
+                "allowedSS58Prefixes": {
+                    "description": "AllowedSS58Prefixes are the accepted client address prefixes, the first one is the canonical prefix client IDs are stored with",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "clockSkew": {
+                    "type": "integer"
+                },
+                "domain": {
+                    "type": "string"
+                },
+                "nonceRateLimit": {
+                    "description": "NonceRateLimit is the number of nonces an IP can request per minute, 0 disables the limit",
+                    "type": "integer"
+                },
+                "nonceTTL": {
+                    "type": "integer"
+                },
+                "window": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Consent": {
+            "type": "object",
+            "properties": {
+                "maxDuration": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Eligibility": {
+            "type": "object",
+            "properties": {
+                "countryHeader": {
+                    "type": "string"
+                },
+                "policyFile": {
+                    "type": "string"
+                },
+                "rules": {
+                    "description": "Rules are loaded from PolicyFile, in order. If no file is set, only the min_balance rule is applied.",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.EligibilityRule"
+                    }
+                }
+            }
+        },
+        "config.EligibilityRule": {
+            "type": "object",
+            "properties": {
+                "allowedCountries": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "clientIds": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "deniedCountries": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "maxAttempts": {
+                    "type": "integer"
+                },
+                "minBalance": {
+                    "description": "MinBalance is nil when not set, the min_balance rule then uses MinBalanceToVerifyAccount",
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Embedded": {
+            "type": "object",
+            "properties": {
+                "cleanupInterval": {
+                    "description": "CleanupInterval in minutes of the job deleting the expired entries, such as tokens and rate limiter entries",
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.IDLimiter": {
+            "type": "object",
+            "properties": {
+                "maxTokenRequests": {
+                    "type": "integer"
+                },
+                "tokenExpiration": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.IPLimiter": {
+            "type": "object",
+            "properties": {
+                "maxTokenRequests": {
+                    "type": "integer"
+                },
+                "tokenExpiration": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Idenfy": {
+            "type": "object",
+            "properties": {
+                "apikey": {
+                    "type": "string"
+                },
+                "apisecret": {
+                    "type": "string"
+                },
+                "baseURL": {
+                    "type": "string"
+                },
+                "breakerCooldown": {
+                    "description": "BreakerCooldown in seconds during which the open circuit breaker fails fast, before a probe request is allowed",
+                    "type": "integer"
+                },
+                "breakerThreshold": {
+                    "description": "BreakerThreshold of consecutive failures opening the circuit breaker, 0 disables it",
+                    "type": "integer"
+                },
+                "callbackSignKey": {
+                    "type": "string"
+                },
+                "callbackUrl": {
+                    "type": "string"
+                },
+                "devMode": {
+                    "type": "boolean"
+                },
+                "maxRetries": {
+                    "description": "MaxRetries of an idempotent request failing with a network error or a 5xx or 429 status, session creation is only\nretried if the connection failed or on a 429 status with a Retry-After header",
+                    "type": "integer"
+                },
+                "namespace": {
+                    "type": "string"
+                },
+                "retryBaseDelay": {
+                    "description": "RetryBaseDelay in milliseconds before the first retry, doubled on each retry and jittered",
+                    "type": "integer"
+                },
+                "whitelistedIPs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "config.Links": {
+            "type": "object",
+            "properties": {
+                "maxLinks": {
+                    "description": "MaxLinks is the number of accounts a verified client can link at the same time, 0 disables linking",
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Log": {
+            "type": "object",
+            "properties": {
+                "debug": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "config.Migrations": {
+            "type": "object",
+            "properties": {
+                "onStartup": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "config.MongoDB": {
+            "type": "object",
+            "properties": {
+                "databaseName": {
+                    "type": "string"
+                },
+                "uri": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Network": {
+            "type": "object",
+            "properties": {
+                "challengeDomain": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "ss58Prefixes": {
+                    "description": "SS58Prefixes are the accepted client address prefixes of the network, the first one being canonical.\nIf not set, the network uses CHALLENGE_ALLOWED_SS58_PREFIXES.",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "wsProviderUrl": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Networks": {
+            "type": "object",
+            "properties": {
+                "file": {
+                    "type": "string"
+                },
+                "header": {
+                    "description": "Header selects the network of a request, requests without it nor a /networks/:network path prefix are served by the default network",
+                    "type": "string"
+                },
+                "list": {
+                    "description": "List is loaded from File",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.Network"
+                    }
+                },
+                "trust": {
+                    "description": "Trust is loaded from File, it lists by network name the networks whose approved verifications it accepts",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "config.Partner": {
+            "type": "object",
+            "properties": {
+                "allowAnonymousStatus": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "config.Postgres": {
+            "type": "object",
+            "properties": {
+                "cleanupInterval": {
+                    "description": "CleanupInterval in minutes of the job deleting the expired entries, such as tokens and rate limiter entries",
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Server": {
+            "type": "object",
+            "properties": {
+                "port": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Session": {
+            "type": "object",
+            "properties": {
+                "signingKey": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Storage": {
+            "type": "object",
+            "properties": {
+                "backend": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.TFChain": {
+            "type": "object",
+            "properties": {
+                "wsProviderURL": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Verification": {
+            "type": "object",
+            "properties": {
+                "alwaysVerifiedIDs": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "denialCooldown": {
+                    "type": "integer"
+                },
+                "documentExpiryCheckInterval": {
+                    "type": "integer"
+                },
+                "documentExpiryWarningDays": {
+                    "type": "integer"
+                },
+                "duplicateIdentityOutcome": {
+                    "type": "string"
+                },
+                "expiredDocumentOutcome": {
+                    "type": "string"
+                },
+                "fingerprintKey": {
+                    "type": "string"
+                },
+                "maxAttempts": {
+                    "type": "integer"
+                },
+                "minBalanceToVerifyAccount": {
+                    "type": "integer"
+                },
+                "reconciliationInterval": {
+                    "description": "ReconciliationInterval in minutes of the job polling iDenfy for the sessions whose result never arrived, 0 disables it",
+                    "type": "integer"
+                },
+                "reconciliationMaxAge": {
+                    "description": "ReconciliationMaxAge in minutes after which a session without result is no longer polled",
+                    "type": "integer"
+                },
+                "reconciliationTimeout": {
+                    "description": "ReconciliationTimeout in minutes after which a session without result is polled",
+                    "type": "integer"
+                },
+                "rejectedAMLResultClasses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rejectedFraudTags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rejectedPEPSStatuses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "rejectedSanctionsStatuses": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
                     }
+                },
+                "rejectedSuspicionReasons": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "sessionLockTTL": {
+                    "type": "integer"
+                },
+                "suspiciousVerificationOutcome": {
+                    "type": "string"
                 }
             }
-        }
-    },
-    "definitions": {
-        "config.Challenge": {
+        },
+        "handlers.CreateAccountLinkRequest": {
             "type": "object",
             "properties": {
-                "domain": {
+                "linkedSignature": {
+                    "description": "LinkedSignature of the statement by the linked account",
                     "type": "string"
                 },
-                "window": {
-                    "type": "integer"
+                "linkedSignatureScheme": {
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "Signature of the statement by the authenticated primary account",
+                    "type": "string"
+                },
+                "signatureScheme": {
+                    "type": "string"
+                },
+                "statement": {
+                    "description": "Statement is the hex-encoded message ` + "`" + `{api-domain}:{timestamp}:link:{primaryClientId}:{linkedClientId}` + "`" + `",
+                    "type": "string"
                 }
             }
         },
-        "config.IDLimiter": {
+        "handlers.CreateConsentGrantRequest": {
             "type": "object",
             "properties": {
-                "maxTokenRequests": {
+                "duration": {
+                    "description": "Duration of the grant in minutes",
                     "type": "integer"
                 },
-                "tokenExpiration": {
-                    "type": "integer"
+                "signature": {
+                    "description": "Signature of the statement by the authenticated client",
+                    "type": "string"
+                },
+                "signatureScheme": {
+                    "type": "string"
+                },
+                "statement": {
+                    "description": "Statement is the hex-encoded message ` + "`" + `{api-domain}:{timestamp}:consent:{partnerId}:{fields}` + "`" + `, with comma-separated verification data fields and claims",
+                    "type": "string"
                 }
             }
         },
-        "config.IPLimiter": {
+        "handlers.CreatePartnerAPIKeyRequest": {
             "type": "object",
             "properties": {
-                "maxTokenRequests": {
-                    "type": "integer"
+                "allowedFields": {
+                    "description": "AllowedFields are the verification status fields returned to the partner, all if empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 },
-                "tokenExpiration": {
+                "name": {
+                    "type": "string"
+                },
+                "rateLimit": {
+                    "description": "RateLimit is the number of requests allowed per minute, 0 means unlimited",
                     "type": "integer"
                 }
             }
         },
-        "config.Idenfy": {
+        "responses.AccountLinkEventResponse": {
             "type": "object",
             "properties": {
-                "apikey": {
+                "action": {
                     "type": "string"
                 },
-                "apisecret": {
+                "actor": {
+                    "description": "Actor is the client that created or revoked the link",
                     "type": "string"
                 },
-                "baseURL": {
+                "at": {
                     "type": "string"
                 },
-                "callbackSignKey": {
+                "linkId": {
                     "type": "string"
                 },
-                "callbackUrl": {
+                "linkedClientId": {
                     "type": "string"
                 },
-                "devMode": {
-                    "type": "boolean"
-                },
-                "namespace": {
+                "primaryClientId": {
                     "type": "string"
-                },
-                "whitelistedIPs": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
-                }
-            }
-        },
-        "config.Log": {
-            "type": "object",
-            "properties": {
-                "debug": {
-                    "type": "boolean"
                 }
             }
         },
-        "config.MongoDB": {
+        "responses.AccountLinkResponse": {
             "type": "object",
             "properties": {
-                "databaseName": {
+                "createdAt": {
                     "type": "string"
                 },
-                "uri": {
-                    "type": "string"
-                }
-            }
-        },
-        "config.Server": {
-            "type": "object",
-            "properties": {
-                "port": {
+                "id": {
                     "type": "string"
-                }
-            }
-        },
-        "config.TFChain": {
-            "type": "object",
-            "properties": {
-                "wsProviderURL": {
+                },
+                "linkedClientId": {
                     "type": "string"
-                }
-            }
-        },
-        "config.Verification": {
-            "type": "object",
-            "properties": {
-                "alwaysVerifiedIDs": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
                 },
-                "expiredDocumentOutcome": {
+                "primaryClientId": {
                     "type": "string"
                 },
-                "minBalanceToVerifyAccount": {
-                    "type": "integer"
+                "revokedAt": {
+                    "type": "string"
                 },
-                "suspiciousVerificationOutcome": {
+                "revokedBy": {
                     "type": "string"
                 }
             }
@@ -571,9 +2669,21 @@ const docTemplate = `{
         "responses.AppConfigsResponse": {
             "type": "object",
             "properties": {
+                "admin": {
+                    "$ref": "#/definitions/config.Admin"
+                },
                 "challenge": {
                     "$ref": "#/definitions/config.Challenge"
                 },
+                "consent": {
+                    "$ref": "#/definitions/config.Consent"
+                },
+                "eligibility": {
+                    "$ref": "#/definitions/config.Eligibility"
+                },
+                "embedded": {
+                    "$ref": "#/definitions/config.Embedded"
+                },
                 "idenfy": {
                     "$ref": "#/definitions/config.Idenfy"
                 },
@@ -583,15 +2693,36 @@ const docTemplate = `{
                 "iplimiter": {
                     "$ref": "#/definitions/config.IPLimiter"
                 },
+                "links": {
+                    "$ref": "#/definitions/config.Links"
+                },
                 "log": {
                     "$ref": "#/definitions/config.Log"
                 },
+                "migrations": {
+                    "$ref": "#/definitions/config.Migrations"
+                },
                 "mongoDB": {
                     "$ref": "#/definitions/config.MongoDB"
                 },
+                "networks": {
+                    "$ref": "#/definitions/config.Networks"
+                },
+                "partner": {
+                    "$ref": "#/definitions/config.Partner"
+                },
+                "postgres": {
+                    "$ref": "#/definitions/config.Postgres"
+                },
                 "server": {
                     "$ref": "#/definitions/config.Server"
                 },
+                "session": {
+                    "$ref": "#/definitions/config.Session"
+                },
+                "storage": {
+                    "$ref": "#/definitions/config.Storage"
+                },
                 "tfchain": {
                     "$ref": "#/definitions/config.TFChain"
                 },
@@ -608,6 +2739,105 @@ const docTemplate = `{
                 }
             }
         },
+        "responses.ChallengeNonceResponse": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "nonce": {
+                    "type": "string"
+                }
+            }
+        },
+        "responses.ConsentAccessResponse": {
+            "type": "object",
+            "properties": {
+                "accessedAt": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "partnerId": {
+                    "type": "string"
+                },
+                "partnerName": {
+                    "type": "string"
+                }
+            }
+        },
+        "responses.ConsentGrantResponse": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "fields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "partnerId": {
+                    "type": "string"
+                },
+                "partnerName": {
+                    "type": "string"
+                },
+                "revokedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "responses.CreatedPartnerAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "allowedFields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Key is only returned once, on creation",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "rateLimit": {
+                    "type": "integer"
+                },
+                "revokedAt": {
+                    "type": "string"
+                }
+            }
+        },
         "responses.HealthResponse": {
             "type": "object",
             "properties": {
@@ -617,6 +2847,9 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "idenfy": {
+                    "$ref": "#/definitions/responses.IdenfyHealth"
+                },
                 "status": {
                     "$ref": "#/definitions/responses.HealthStatus"
                 },
@@ -636,6 +2869,20 @@ const docTemplate = `{
                 "HealthStatusDegraded"
             ]
         },
+        "responses.IdenfyHealth": {
+            "type": "object",
+            "properties": {
+                "breakerState": {
+                    "description": "BreakerState of the iDenfy circuit breaker, one of closed, open and half-open",
+                    "type": "string",
+                    "enum": [
+                        "closed",
+                        "open",
+                        "half-open"
+                    ]
+                }
+            }
+        },
         "responses.Outcome": {
             "type": "string",
             "enum": [
@@ -647,6 +2894,52 @@ const docTemplate = `{
                 "OutcomeRejected"
             ]
         },
+        "responses.PartnerAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "allowedFields": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "rateLimit": {
+                    "type": "integer"
+                },
+                "revokedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "responses.SessionTokenResponse": {
+            "type": "object",
+            "properties": {
+                "clientId": {
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "tokenType": {
+                    "type": "string"
+                }
+            }
+        },
         "responses.TokenResponse": {
             "type": "object",
             "properties": {
@@ -676,6 +2969,32 @@ const docTemplate = `{
                 }
             }
         },
+        "responses.VerificationAttemptsResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "clientId": {
+                    "type": "string"
+                },
+                "lastAttemptAt": {
+                    "type": "string"
+                },
+                "lastDeniedAt": {
+                    "type": "string"
+                },
+                "lastScanRef": {
+                    "type": "string"
+                },
+                "maxAttempts": {
+                    "type": "integer"
+                },
+                "resetAt": {
+                    "type": "string"
+                }
+            }
+        },
         "responses.VerificationDataResponse": {
             "type": "object",
             "properties": {
@@ -803,14 +3122,28 @@ const docTemplate = `{
                 "clientId": {
                     "type": "string"
                 },
+                "documentExpiresAt": {
+                    "type": "string"
+                },
                 "final": {
                     "type": "boolean"
                 },
                 "idenfyRef": {
                     "type": "string"
                 },
+                "linkedTo": {
+                    "description": "LinkedTo is set when the status is inherited from the primary account the client is linked to",
+                    "type": "string"
+                },
+                "reverificationRequired": {
+                    "type": "boolean"
+                },
                 "status": {
                     "$ref": "#/definitions/responses.Outcome"
+                },
+                "trustedNetwork": {
+                    "description": "TrustedNetwork is set when the status was accepted from the verification of the same key on a linked network",
+                    "type": "string"
                 }
             }
         }
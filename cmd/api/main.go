@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log/slog"
 	"os"
+	"time"
 
 	_ "github.com/threefoldtech/tf-kyc-verifier/api/docs"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/server"
 )
 
+const MIGRATE_TIMEOUT = 10 * time.Minute
+
 func main() {
 	config, err := config.LoadConfigFromEnv()
 	if err != nil {
@@ -22,6 +27,14 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
 	logger.Debug("Configuration loaded successfully", "config", config.GetPublicConfig())
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(config, logger, os.Args[2:]); err != nil {
+			logger.Error("Migration failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	server, err := server.New(config, logger)
 	if err != nil {
 		logger.Error("Failed to create server:", "error", err)
@@ -35,3 +48,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// migrate applies the pending database migrations, or only lists them with -dry-run
+func migrate(config *config.Config, logger *slog.Logger, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "list the pending migrations without applying them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), MIGRATE_TIMEOUT)
+	defer cancel()
+	return server.Migrate(ctx, config, logger, *dryRun)
+}
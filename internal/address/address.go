@@ -0,0 +1,40 @@
+/*
+Package address contains the SS58 address handling for the application.
+This layer is responsible for enforcing the SS58 network prefixes allowed by a deployment
+and for normalizing client IDs to a canonical encoding, so the same key always maps to the same client ID.
+*/
+package address
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/vedhavyas/go-subkey/v2"
+)
+
+// Normalize decodes the SS58 address, checks its network prefix is allowed
+// and re-encodes it with the canonical prefix, the first allowed one.
+// Without allowed prefixes, any prefix is accepted and the address is returned as is.
+func Normalize(address string, allowedPrefixes []uint16) (string, error) {
+	prefix, publicKey, err := subkey.SS58Decode(address)
+	if err != nil {
+		return "", errors.NewValidationError("malformed address: failed to decode ss58 address", err)
+	}
+	if len(allowedPrefixes) == 0 {
+		return address, nil
+	}
+	if !slices.Contains(allowedPrefixes, prefix) {
+		return "", errors.NewValidationError(fmt.Sprintf("bad address: ss58 prefix %d is not allowed on this network, expected one of %v", prefix, allowedPrefixes), nil)
+	}
+	return subkey.SS58Encode(publicKey, allowedPrefixes[0]), nil
+}
+
+// Reencode returns the SS58 address of the same public key with another network prefix
+func Reencode(address string, prefix uint16) (string, error) {
+	_, publicKey, err := subkey.SS58Decode(address)
+	if err != nil {
+		return "", errors.NewValidationError("malformed address: failed to decode ss58 address", err)
+	}
+	return subkey.SS58Encode(publicKey, prefix), nil
+}
@@ -0,0 +1,56 @@
+package address
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vedhavyas/go-subkey/v2"
+)
+
+func TestNormalize(t *testing.T) {
+	// the Alice development account with the generic substrate (42) and polkadot (0) prefixes
+	substrateAddress := "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+	_, publicKey, err := subkey.SS58Decode(substrateAddress)
+	assert.NoError(t, err)
+	polkadotAddress := subkey.SS58Encode(publicKey, 0)
+
+	tests := []struct {
+		name            string
+		address         string
+		allowedPrefixes []uint16
+		expected        string
+		expectedError   string
+	}{
+		{name: "canonical address", address: substrateAddress, allowedPrefixes: []uint16{42}, expected: substrateAddress},
+		{name: "other allowed encoding is normalized", address: polkadotAddress, allowedPrefixes: []uint16{42, 0}, expected: substrateAddress},
+		{name: "prefix not allowed", address: polkadotAddress, allowedPrefixes: []uint16{42}, expectedError: "ss58 prefix 0 is not allowed"},
+		{name: "any prefix without allowed prefixes", address: polkadotAddress, expected: polkadotAddress},
+		{name: "malformed address", address: "not-an-address", allowedPrefixes: []uint16{42}, expectedError: "malformed address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized, err := Normalize(tt.address, tt.allowedPrefixes)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, normalized)
+		})
+	}
+}
+
+func TestReencode(t *testing.T) {
+	substrateAddress := "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+	polkadotAddress, err := Reencode(substrateAddress, 0)
+	assert.NoError(t, err)
+	assert.NotEqual(t, substrateAddress, polkadotAddress)
+
+	// both encode the same public key
+	reencoded, err := Reencode(polkadotAddress, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, substrateAddress, reencoded)
+
+	_, err = Reencode("not-an-address", 42)
+	assert.ErrorContains(t, err, "malformed address")
+}
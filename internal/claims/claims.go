@@ -0,0 +1,94 @@
+/*
+Package claims derives yes/no and coarse facts from a stored iDenfy verification, so consumers can learn what they need
+about a client without receiving the raw personal data of its document.
+*/
+package claims
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/outcome"
+)
+
+// ADULT_AGE is the age in years from which a client is an adult
+const ADULT_AGE = 18
+
+const (
+	IsAdult        = "isAdult"
+	Nationality    = "nationality"
+	IssuingCountry = "issuingCountry"
+	DocumentType   = "documentType"
+	DocumentValid  = "documentValid"
+)
+
+// Supported are the claims that can be derived from a verification
+var Supported = []string{IsAdult, Nationality, IssuingCountry, DocumentType, DocumentValid}
+
+// Claims maps the requested claims to their values, a claim is nil if it can not be derived from the verification
+type Claims map[string]any
+
+// Parse splits a comma-separated list of claims, all supported claims are returned if the list is empty
+func Parse(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Supported, nil
+	}
+	requested := []string{}
+	for _, claim := range strings.Split(list, ",") {
+		claim = strings.TrimSpace(claim)
+		if !slices.Contains(Supported, claim) {
+			return nil, fmt.Errorf("unknown claim %q, supported claims are %v", claim, Supported)
+		}
+		requested = append(requested, claim)
+	}
+	return requested, nil
+}
+
+// Derive computes the requested claims from the verification at the given time, requested names that are not claims are skipped
+func Derive(verification *models.Verification, requested []string, now time.Time) Claims {
+	data := verification.Data
+	claims := Claims{}
+	for _, claim := range requested {
+		switch claim {
+		case IsAdult:
+			claims[claim] = isAdult(data.DocDOB, now)
+		case Nationality:
+			claims[claim] = country(data.DocNationality)
+		case IssuingCountry:
+			claims[claim] = country(data.DocIssuingCountry)
+		case DocumentType:
+			if data.DocType != nil {
+				claims[claim] = *data.DocType
+			} else {
+				claims[claim] = nil
+			}
+		case DocumentValid:
+			// a document is valid until the end of its expiry day
+			if expiresAt := outcome.ParseDocExpiry(data.DocExpiry); expiresAt != nil {
+				claims[claim] = now.Before(expiresAt.AddDate(0, 0, 1))
+			} else {
+				claims[claim] = nil
+			}
+		}
+	}
+	return claims
+}
+
+func isAdult(dob string, now time.Time) any {
+	birthDate, err := time.Parse(outcome.DocExpiryLayout, strings.TrimSpace(dob))
+	if err != nil {
+		return nil
+	}
+	return !now.Before(birthDate.AddDate(ADULT_AGE, 0, 0))
+}
+
+func country(code string) any {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil
+	}
+	return code
+}
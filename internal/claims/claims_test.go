@@ -0,0 +1,81 @@
+package claims
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	requested, err := Parse("")
+	assert.NoError(t, err)
+	assert.Equal(t, Supported, requested)
+
+	requested, err = Parse("isAdult, nationality")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{IsAdult, Nationality}, requested)
+
+	_, err = Parse("isAdult,docFirstName")
+	assert.ErrorContains(t, err, `unknown claim "docFirstName"`)
+}
+
+func TestDerive(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.UTC)
+	passport := models.PASSPORT
+	tests := []struct {
+		name      string
+		data      models.PersonData
+		requested []string
+		expected  Claims
+	}{
+		{
+			name:      "adult on 18th birthday",
+			data:      models.PersonData{DocDOB: "2008-06-15"},
+			requested: []string{IsAdult},
+			expected:  Claims{IsAdult: true},
+		},
+		{
+			name:      "minor the day before 18th birthday",
+			data:      models.PersonData{DocDOB: "2008-06-16"},
+			requested: []string{IsAdult},
+			expected:  Claims{IsAdult: false},
+		},
+		{
+			name:      "unknown date of birth",
+			data:      models.PersonData{DocDOB: "unknown"},
+			requested: []string{IsAdult},
+			expected:  Claims{IsAdult: nil},
+		},
+		{
+			name:      "only requested claims",
+			data:      models.PersonData{DocDOB: "1990-01-01", DocNationality: "be", DocIssuingCountry: "NL", DocType: &passport, DocExpiry: "2030-01-01"},
+			requested: []string{Nationality, DocumentType},
+			expected:  Claims{Nationality: "BE", DocumentType: models.PASSPORT},
+		},
+		{
+			name:      "names other than claims are skipped",
+			data:      models.PersonData{DocDOB: "1990-01-01", DocFirstName: "Jane"},
+			requested: []string{"docFirstName", IsAdult, "docDob"},
+			expected:  Claims{IsAdult: true},
+		},
+		{
+			name:      "document valid until the end of its expiry day",
+			data:      models.PersonData{DocExpiry: "2026-06-15", DocIssuingCountry: "NL"},
+			requested: []string{DocumentValid, IssuingCountry, DocumentType},
+			expected:  Claims{DocumentValid: true, IssuingCountry: "NL", DocumentType: nil},
+		},
+		{
+			name:      "expired document",
+			data:      models.PersonData{DocExpiry: "2026-06-14"},
+			requested: []string{DocumentValid, Nationality},
+			expected:  Claims{DocumentValid: false, Nationality: nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Derive(&models.Verification{Data: tt.data}, tt.requested, now))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package idenfy
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	// BreakerClosed lets the requests through
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen fails the requests fast until the cooldown is over
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe request through, its outcome closes or reopens the breaker
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// ErrCircuitOpen is returned without contacting iDenfy while the circuit breaker is open
+var ErrCircuitOpen = errors.New("iDenfy circuit breaker is open")
+
+// breaker is a circuit breaker opened by consecutive failed requests to iDenfy.
+// A zero threshold disables it.
+type breaker struct {
+	mu        sync.Mutex
+	threshold uint
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    BreakerState
+	failures uint
+	openedAt time.Time
+	probing  bool
+}
+
+func newBreaker(threshold uint, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     BreakerClosed,
+	}
+}
+
+// allow reports whether a request can be sent, once the cooldown is over a single probe request is allowed
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.state = BreakerHalfOpen
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	}
+	return nil
+}
+
+// success closes the breaker, iDenfy answered the request
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = BreakerClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// failure counts a failed request and reports whether it opened the breaker
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold == 0 {
+		return false
+	}
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		opened := b.state != BreakerOpen
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+		b.probing = false
+		return opened
+	}
+	return false
+}
+
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
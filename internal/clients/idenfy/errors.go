@@ -0,0 +1,78 @@
+package idenfy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrAuthFailed is returned when iDenfy rejects the API key and secret of the service
+	ErrAuthFailed = errors.New("iDenfy rejected the API credentials")
+	// ErrBadRequest is returned when iDenfy rejects the request parameters
+	ErrBadRequest = errors.New("iDenfy rejected the request")
+	// ErrQuotaExceeded is returned when the iDenfy account has no verifications left
+	ErrQuotaExceeded = errors.New("iDenfy verification quota exceeded")
+	// ErrRateLimited is returned when iDenfy still rate limits the request after the retries
+	ErrRateLimited = errors.New("iDenfy rate limit exceeded")
+)
+
+// APIError is a non-2xx response of the iDenfy API, with the error payload returned by iDenfy if any.
+// It wraps one of the sentinel errors depending on the status code, the server errors wrap none.
+type APIError struct {
+	StatusCode int
+	Identifier string
+	Message    string
+	// Parameters are the request parameters the error is about, such as the missing ones
+	Parameters []string
+	// RetryAfter is the delay requested by the Retry-After header of the response, nil if it has none
+	RetryAfter *time.Duration
+}
+
+// newAPIError builds the error of a non-2xx response, a body that is not an iDenfy error payload is ignored
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiError := &APIError{StatusCode: statusCode}
+	var payload struct {
+		Identifier string          `json:"identifier"`
+		Message    string          `json:"message"`
+		Parameters json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return apiError
+	}
+	apiError.Identifier = payload.Identifier
+	apiError.Message = payload.Message
+	// the parameters are only reported when they are a list of names
+	_ = json.Unmarshal(payload.Parameters, &apiError.Parameters)
+	return apiError
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("unexpected status code from iDenfy: %d", e.StatusCode)
+	if e.Identifier != "" {
+		msg += ": " + e.Identifier
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if len(e.Parameters) > 0 {
+		msg += fmt.Sprintf(" %v", e.Parameters)
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrAuthFailed
+	case e.StatusCode == http.StatusPaymentRequired:
+		return ErrQuotaExceeded
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= 400 && e.StatusCode < 500:
+		return ErrBadRequest
+	}
+	return nil
+}
@@ -2,7 +2,17 @@
 Package idenfy contains the iDenfy client for the application.
 This layer is responsible for interacting with the iDenfy API. the main operations are:
 - creating a verification session
+- expiring a verification session
+- getting the status and data of a verification session
 - verifying the callback signature
+
+The non-2xx responses are returned as an APIError holding the iDenfy error payload, wrapping a sentinel error
+such as ErrAuthFailed or ErrRateLimited depending on the status code.
+The idempotent requests failing with a network error or a 5xx or 429 status are retried with a jittered exponential
+backoff. Creating a verification session is only retried when iDenfy can't have processed the failed attempt, that is
+when the connection could not be established or on a 429 status with a Retry-After header. A timeout or a 5xx status
+may still have created a paid session.
+A circuit breaker fails the requests fast while iDenfy keeps failing.
 */
 package idenfy
 
@@ -16,6 +26,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
@@ -23,30 +38,168 @@ import (
 )
 
 type Idenfy struct {
-	client *fasthttp.Client // TODO: Interface
-	config IdenfyConfig     // TODO: Interface
-	logger *slog.Logger
+	client  *fasthttp.Client // TODO: Interface
+	config  IdenfyConfig     // TODO: Interface
+	logger  *slog.Logger
+	breaker *breaker
+
+	requests        atomic.Uint64
+	retries         atomic.Uint64
+	failures        atomic.Uint64
+	rejected        atomic.Uint64
+	breakerOpenings atomic.Uint64
+}
+
+// Stats are the counters of the requests sent to iDenfy since the start and the state of the circuit breaker
+type Stats struct {
+	BreakerState BreakerState
+	// Requests sent to iDenfy, each retry included
+	Requests uint64
+	Retries  uint64
+	// Failures are the requests failing with a network error or a 5xx or 429 status
+	Failures uint64
+	// Rejected are the requests failed fast by the open circuit breaker
+	Rejected        uint64
+	BreakerOpenings uint64
 }
 
 const (
 	VerificationSessionEndpoint = "/api/v2/token"
+	ExpireSessionEndpoint       = "/api/v2/expire"
+	SessionStatusEndpoint       = "/api/v2/status"
+	SessionDataEndpoint         = "/api/v2/data"
 )
 
 func New(config IdenfyConfig, logger *slog.Logger) *Idenfy {
 	return &Idenfy{
-		client: &fasthttp.Client{},
-		config: config,
-		logger: logger,
+		client:  &fasthttp.Client{},
+		config:  config,
+		logger:  logger,
+		breaker: newBreaker(config.GetBreakerThreshold(), config.GetBreakerCooldown()),
 	}
 }
 
-func (c *Idenfy) CreateVerificationSession(ctx context.Context, clientID string) (models.Token, error) { // TODO: Refactor
-	url := c.config.GetBaseURL() + VerificationSessionEndpoint
+func (c *Idenfy) Stats() Stats {
+	return Stats{
+		BreakerState:    c.breaker.State(),
+		Requests:        c.requests.Load(),
+		Retries:         c.retries.Load(),
+		Failures:        c.failures.Load(),
+		Rejected:        c.rejected.Load(),
+		BreakerOpenings: c.breakerOpenings.Load(),
+	}
+}
+
+func (c *Idenfy) CreateVerificationSession(ctx context.Context, clientID string) (models.Token, error) {
+	RequestBody := c.createVerificationSessionRequestBody(clientID, c.config.GetDevMode())
+	c.logger.Debug("Preparing iDenfy verification session request", "request", RequestBody)
+	body, err := c.post(ctx, VerificationSessionEndpoint, RequestBody, false)
+	if err != nil {
+		return models.Token{}, fmt.Errorf("sending token request to iDenfy: %w", err)
+	}
+	c.logger.Debug("Received response from iDenfy", "response", string(body))
+
+	var result models.Token
+	if err := json.Unmarshal(body, &result); err != nil {
+		return models.Token{}, fmt.Errorf("decoding token response from iDenfy: %w", err)
+	}
 
+	return result, nil
+}
+
+// ExpireVerificationSession expires the auth token of a verification session, so it can no longer be used to start the verification
+func (c *Idenfy) ExpireVerificationSession(ctx context.Context, authToken string) error {
+	_, err := c.post(ctx, ExpireSessionEndpoint, map[string]interface{}{"authToken": authToken}, true)
+	if err != nil {
+		return fmt.Errorf("sending expire token request to iDenfy: %w", err)
+	}
+	return nil
+}
+
+// GetSessionStatus returns the status of the verification session, it is final once the verification webhook is sent
+func (c *Idenfy) GetSessionStatus(ctx context.Context, scanRef string) (models.SessionStatus, error) {
+	body, err := c.post(ctx, SessionStatusEndpoint, map[string]interface{}{"scanRef": scanRef}, true)
+	if err != nil {
+		return models.SessionStatus{}, fmt.Errorf("sending status request to iDenfy: %w", err)
+	}
+	var status models.SessionStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return models.SessionStatus{}, fmt.Errorf("decoding status response from iDenfy: %w", err)
+	}
+	return status, nil
+}
+
+// GetSessionData returns the person data extracted from the document of the verification session
+func (c *Idenfy) GetSessionData(ctx context.Context, scanRef string) (models.PersonData, error) {
+	body, err := c.post(ctx, SessionDataEndpoint, map[string]interface{}{"scanRef": scanRef}, true)
+	if err != nil {
+		return models.PersonData{}, fmt.Errorf("sending data request to iDenfy: %w", err)
+	}
+	var data models.PersonData
+	if err := json.Unmarshal(body, &data); err != nil {
+		return models.PersonData{}, fmt.Errorf("decoding data response from iDenfy: %w", err)
+	}
+	return data, nil
+}
+
+// post sends an authenticated JSON request to the iDenfy API and returns the response body.
+// The failures are counted by the circuit breaker, and retried while the context deadline allows it if the request is idempotent
+// or if iDenfy didn't process it.
+func (c *Idenfy) post(ctx context.Context, endpoint string, requestBody interface{}, idempotent bool) ([]byte, error) {
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request body: %w", err)
+	}
+	for attempt := uint(0); ; attempt++ {
+		if err := c.breaker.allow(); err != nil {
+			c.rejected.Add(1)
+			return nil, err
+		}
+		c.requests.Add(1)
+		body, status, err := c.send(ctx, endpoint, jsonBody)
+		if !failed(status, err) {
+			// iDenfy answered, even a client error means it is up
+			c.breaker.success()
+			if err != nil {
+				return nil, err
+			}
+			return body, nil
+		}
+		c.failures.Add(1)
+		if c.breaker.failure() {
+			// the following requests fail fast, retrying this one would too
+			c.breakerOpenings.Add(1)
+			c.logger.Warn("iDenfy circuit breaker opened", "endpoint", endpoint, "error", err)
+			return nil, err
+		}
+		if !idempotent && !unprocessed(err) || attempt >= c.config.GetMaxRetries() {
+			return nil, err
+		}
+		delay := backoff(c.config.GetRetryBaseDelay(), attempt)
+		if retryAfter, ok := retryAfter(err); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			return nil, err
+		}
+		c.logger.Debug("Retrying iDenfy request", "endpoint", endpoint, "attempt", attempt+1, "delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		c.retries.Add(1)
+	}
+}
+
+// send sends a single request to the iDenfy API and returns the response body and status code
+func (c *Idenfy) send(ctx context.Context, endpoint string, jsonBody []byte) ([]byte, int, error) {
 	req := fasthttp.AcquireRequest()
 	defer fasthttp.ReleaseRequest(req)
 
-	req.SetRequestURI(url)
+	req.SetRequestURI(c.config.GetBaseURL() + endpoint)
 	req.Header.SetMethod(fasthttp.MethodPost)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -55,12 +208,6 @@ func (c *Idenfy) CreateVerificationSession(ctx context.Context, clientID string)
 	auth := base64.StdEncoding.EncodeToString([]byte(authStr))
 	req.Header.Set("Authorization", "Basic "+auth)
 
-	RequestBody := c.createVerificationSessionRequestBody(clientID, c.config.GetDevMode())
-
-	jsonBody, err := json.Marshal(RequestBody)
-	if err != nil {
-		return models.Token{}, fmt.Errorf("marshaling request body: %w", err)
-	}
 	req.SetBody(jsonBody)
 	// Set deadline from context
 	deadline, ok := ctx.Deadline()
@@ -70,24 +217,80 @@ func (c *Idenfy) CreateVerificationSession(ctx context.Context, clientID string)
 
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
-	c.logger.Debug("Preparing iDenfy verification session request", "request", jsonBody)
-	err = c.client.Do(req, resp)
+	err := c.client.Do(req, resp)
 	if err != nil {
-		return models.Token{}, fmt.Errorf("sending token request to iDenfy: %w", err)
+		return nil, 0, err
 	}
 
 	if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
-		c.logger.Debug("Received unexpected status code from iDenfy", "status", resp.StatusCode(), "error", string(resp.Body()))
-		return models.Token{}, fmt.Errorf("unexpected status code from iDenfy: %d", resp.StatusCode())
+		c.logger.Debug("Received unexpected status code from iDenfy", "endpoint", endpoint, "status", resp.StatusCode(), "error", string(resp.Body()))
+		apiError := newAPIError(resp.StatusCode(), resp.Body())
+		apiError.RetryAfter = parseRetryAfter(string(resp.Header.Peek(fasthttp.HeaderRetryAfter)))
+		return nil, resp.StatusCode(), apiError
 	}
-	c.logger.Debug("Received response from iDenfy", "response", string(resp.Body()))
+	// the body is released with the response
+	return append([]byte(nil), resp.Body()...), resp.StatusCode(), nil
+}
 
-	var result models.Token
-	if err := json.Unmarshal(resp.Body(), &result); err != nil {
-		return models.Token{}, fmt.Errorf("decoding token response from iDenfy: %w", err)
+// failed reports whether a request failed with a network error or a 5xx or 429 status, the failures are counted by the
+// circuit breaker. iDenfy may have processed such a request, so the other requests are only retried if it is unprocessed.
+func failed(status int, err error) bool {
+	if err == nil {
+		return false
 	}
+	return status == 0 || status == fasthttp.StatusTooManyRequests || status >= 500
+}
 
-	return result, nil
+// unprocessed reports whether iDenfy can't have processed the failed request, so that retrying it can't create a second
+// session: the connection was never established, or the request was rate limited with a Retry-After header
+func unprocessed(err error) bool {
+	if errors.Is(err, fasthttp.ErrDialTimeout) || errors.Is(err, fasthttp.ErrNoFreeConns) {
+		return true
+	}
+	var opError *net.OpError
+	if errors.As(err, &opError) && opError.Op == "dial" {
+		return true
+	}
+	var dnsError *net.DNSError
+	if errors.As(err, &dnsError) {
+		return true
+	}
+	var apiError *APIError
+	return errors.As(err, &apiError) && apiError.StatusCode == fasthttp.StatusTooManyRequests && apiError.RetryAfter != nil
+}
+
+// retryAfter returns the delay requested by the Retry-After header of a failed request
+func retryAfter(err error) (time.Duration, bool) {
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.RetryAfter == nil {
+		return 0, false
+	}
+	return *apiError.RetryAfter, true
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date, it returns nil if the header is missing or invalid
+func parseRetryAfter(header string) *time.Duration {
+	if header == "" {
+		return nil
+	}
+	var delay time.Duration
+	if seconds, err := strconv.ParseUint(header, 10, 32); err == nil {
+		delay = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		delay = max(time.Until(date), 0)
+	} else {
+		return nil
+	}
+	return &delay
+}
+
+// backoff returns the delay before the retry following the given attempt, the doubled base delay jittered down to its half
+func backoff(base time.Duration, attempt uint) time.Duration {
+	delay := base << min(attempt, 16)
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + rand.N(delay/2)
 }
 
 // verify signature of the callback
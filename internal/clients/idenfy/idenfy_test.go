@@ -4,9 +4,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
@@ -106,3 +112,230 @@ func TestClient_DecodeReaderIdentityCallback(t *testing.T) {
 	// Additional data
 	assert.Empty(t, resp.AdditionalStepPdfUrls)
 }
+
+func TestClient_GetSession(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "key:secret", user+":"+password)
+		var request map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "scan-ref", request["scanRef"])
+		switch r.URL.Path {
+		case SessionStatusEndpoint:
+			fmt.Fprint(w, `{"scanRef":"scan-ref","clientId":"123:main","status":"SUSPECTED","suspicionReasons":["FACE_SUSPECTED"],"fraudTags":[],"mismatchTags":[],"autoDocument":"DOC_VALIDATED"}`)
+		case SessionDataEndpoint:
+			fmt.Fprint(w, `{"docFirstName":"FIRST-NAME-EXAMPLE","docNumber":"XXXXXXXXX","docIssuingCountry":"LT"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := New(&config.Idenfy{BaseURL: server.URL, APIKey: "key", APISecret: "secret"}, slog.Default())
+
+	status, err := client.GetSessionStatus(context.Background(), "scan-ref")
+	assert.NoError(t, err)
+	assert.True(t, status.Final())
+	data, err := client.GetSessionData(context.Background(), "scan-ref")
+	assert.NoError(t, err)
+
+	// the polled session is shaped as the verification webhook
+	verification := status.Verification(data)
+	assert.Equal(t, "123:main", verification.ClientID)
+	assert.Equal(t, "scan-ref", verification.IdenfyRef)
+	assert.True(t, *verification.Final)
+	assert.Equal(t, models.OverallSuspected, *verification.Status.Overall)
+	assert.Equal(t, []models.SuspicionReason{models.SuspicionFaceSuspected}, verification.Status.SuspicionReasons)
+	assert.Equal(t, "XXXXXXXXX", verification.Data.DocNumber)
+}
+
+func TestClient_Retries(t *testing.T) {
+	var requests atomic.Int32
+	var status atomic.Int32
+	var retryAfter atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first two requests fail with the status under test
+		if requests.Add(1) <= 2 {
+			if header := retryAfter.Load().(string); header != "" {
+				w.Header().Set("Retry-After", header)
+			}
+			w.WriteHeader(int(status.Load()))
+			return
+		}
+		fmt.Fprint(w, `{"authToken":"token","scanRef":"scan-ref","status":"APPROVED"}`)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name             string
+		status           int
+		retryAfter       string
+		createSession    bool
+		expectedError    string
+		expectedRequests int32
+		expectedFailures uint64
+	}{
+		{name: "server errors are retried", status: http.StatusServiceUnavailable, expectedRequests: 3, expectedFailures: 2},
+		{name: "rate limits are retried", status: http.StatusTooManyRequests, expectedRequests: 3, expectedFailures: 2},
+		{name: "client errors are not retried", status: http.StatusBadRequest, expectedError: "unexpected status code from iDenfy: 400", expectedRequests: 1},
+		// the failed attempt may have created a paid session
+		{name: "session creation is not retried", status: http.StatusServiceUnavailable, createSession: true, expectedError: "unexpected status code from iDenfy: 503", expectedRequests: 1, expectedFailures: 1},
+		{name: "session creation is not retried on rate limits without retry after", status: http.StatusTooManyRequests, createSession: true, expectedError: "unexpected status code from iDenfy: 429", expectedRequests: 1, expectedFailures: 1},
+		// iDenfy rejected the request without processing it
+		{name: "session creation is retried on rate limits with retry after", status: http.StatusTooManyRequests, retryAfter: "0", createSession: true, expectedRequests: 3, expectedFailures: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			status.Store(int32(tt.status))
+			retryAfter.Store(tt.retryAfter)
+			client := New(&config.Idenfy{BaseURL: server.URL, MaxRetries: 2, RetryBaseDelay: 1}, slog.Default())
+
+			var err error
+			if tt.createSession {
+				_, err = client.CreateVerificationSession(context.Background(), "123")
+			} else {
+				var sessionStatus models.SessionStatus
+				sessionStatus, err = client.GetSessionStatus(context.Background(), "scan-ref")
+				if tt.expectedError == "" {
+					assert.Equal(t, models.OverallApproved, sessionStatus.Status)
+				}
+			}
+			assert.Equal(t, tt.expectedRequests, requests.Load())
+			stats := client.Stats()
+			assert.Equal(t, uint64(tt.expectedRequests), stats.Requests)
+			assert.Equal(t, uint64(tt.expectedRequests-1), stats.Retries)
+			assert.Equal(t, tt.expectedFailures, stats.Failures)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestClient_RetriesUnsentSessionCreation(t *testing.T) {
+	// nothing listens on the address, the connection is refused before iDenfy receives the request
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	assert.NoError(t, listener.Close())
+	client := New(&config.Idenfy{BaseURL: "http://" + address, MaxRetries: 2, RetryBaseDelay: 1}, slog.Default())
+
+	_, err = client.CreateVerificationSession(context.Background(), "123")
+	assert.Error(t, err)
+	assert.True(t, unprocessed(err))
+	stats := client.Stats()
+	assert.Equal(t, uint64(3), stats.Requests)
+	assert.Equal(t, uint64(2), stats.Retries)
+	assert.Equal(t, uint64(3), stats.Failures)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Nil(t, parseRetryAfter(""))
+	assert.Nil(t, parseRetryAfter("soon"))
+	assert.Equal(t, 2*time.Second, *parseRetryAfter("2"))
+	assert.Zero(t, *parseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)))
+	assert.InDelta(t, float64(time.Minute), float64(*parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))), float64(2*time.Second))
+}
+
+func TestClient_Breaker(t *testing.T) {
+	var requests atomic.Int32
+	var down atomic.Bool
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"scanRef":"scan-ref","status":"APPROVED"}`)
+	}))
+	defer server.Close()
+	client := New(&config.Idenfy{BaseURL: server.URL, MaxRetries: 1, RetryBaseDelay: 1, BreakerThreshold: 3, BreakerCooldown: 30}, slog.Default())
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// the first request fails twice, the third failure opens the breaker and stops the retries of the second request
+	_, err := client.GetSessionStatus(ctx, "scan-ref")
+	assert.ErrorContains(t, err, "unexpected status code from iDenfy: 502")
+	_, err = client.GetSessionStatus(ctx, "scan-ref")
+	assert.ErrorContains(t, err, "unexpected status code from iDenfy: 502")
+	assert.Equal(t, int32(3), requests.Load())
+	assert.Equal(t, BreakerOpen, client.Stats().BreakerState)
+
+	// iDenfy is not contacted while the breaker is open
+	_, err = client.GetSessionStatus(ctx, "scan-ref")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Equal(t, int32(3), requests.Load())
+
+	// a failed probe after the cooldown reopens the breaker right away
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, client.Stats().BreakerState)
+	_, err = client.GetSessionStatus(ctx, "scan-ref")
+	assert.ErrorContains(t, err, "unexpected status code from iDenfy: 502")
+	assert.Equal(t, int32(4), requests.Load())
+	assert.Equal(t, BreakerOpen, client.Stats().BreakerState)
+
+	// a successful probe closes it
+	now = now.Add(30 * time.Second)
+	down.Store(false)
+	status, err := client.GetSessionStatus(ctx, "scan-ref")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OverallApproved, status.Status)
+	assert.Equal(t, BreakerClosed, client.Stats().BreakerState)
+
+	stats := client.Stats()
+	assert.Equal(t, uint64(5), stats.Requests)
+	assert.Equal(t, uint64(4), stats.Failures)
+	assert.Equal(t, uint64(1), stats.Rejected)
+	assert.Equal(t, uint64(2), stats.BreakerOpenings)
+}
+
+func TestClient_Errors(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		expectedErr   error
+		expectedError string
+	}{
+		{name: "auth failure", status: http.StatusUnauthorized, body: `{"identifier":"UNAUTHORIZED","message":"Authentication credentials were not provided."}`, expectedErr: ErrAuthFailed, expectedError: "unexpected status code from iDenfy: 401: UNAUTHORIZED: Authentication credentials were not provided."},
+		{name: "bad request", status: http.StatusBadRequest, body: `{"identifier":"MISSING_PARAMETER","message":"Missing mandatory parameters.","parameters":["clientId"]}`, expectedErr: ErrBadRequest, expectedError: "unexpected status code from iDenfy: 400: MISSING_PARAMETER: Missing mandatory parameters. [clientId]"},
+		{name: "quota exceeded", status: http.StatusPaymentRequired, body: `{"identifier":"NOT_ENOUGH_TOKENS","message":"Not enough tokens."}`, expectedErr: ErrQuotaExceeded, expectedError: "unexpected status code from iDenfy: 402: NOT_ENOUGH_TOKENS: Not enough tokens."},
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"message":"Request was throttled."}`, expectedErr: ErrRateLimited, expectedError: "unexpected status code from iDenfy: 429: Request was throttled."},
+		{name: "body that is not an error payload", status: http.StatusForbidden, body: `<html>Forbidden</html>`, expectedErr: ErrAuthFailed, expectedError: "unexpected status code from iDenfy: 403"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+			client := New(&config.Idenfy{BaseURL: server.URL}, slog.Default())
+
+			_, err := client.CreateVerificationSession(context.Background(), "123")
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.EqualError(t, err, "sending token request to iDenfy: "+tt.expectedError)
+			var apiError *APIError
+			assert.ErrorAs(t, err, &apiError)
+			assert.Equal(t, tt.status, apiError.StatusCode)
+		})
+	}
+
+	// the server errors are not typed
+	assert.NoError(t, (&APIError{StatusCode: http.StatusInternalServerError}).Unwrap())
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := uint(0); attempt < 4; attempt++ {
+		delay := backoff(100*time.Millisecond, attempt)
+		expected := 100 * time.Millisecond << attempt
+		assert.GreaterOrEqual(t, delay, expected/2)
+		assert.Less(t, delay, expected)
+	}
+	assert.Zero(t, backoff(0, 3))
+}
@@ -2,6 +2,7 @@ package idenfy
 
 import (
 	"context"
+	"time"
 
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
 )
@@ -15,9 +16,16 @@ type IdenfyConfig interface {
 	GetAPIKey() string
 	GetAPISecret() string
 	GetCallbackSignKey() string
+	GetMaxRetries() uint
+	GetRetryBaseDelay() time.Duration
+	GetBreakerThreshold() uint
+	GetBreakerCooldown() time.Duration
 }
 
 type IdenfyClient interface {
 	CreateVerificationSession(ctx context.Context, clientID string) (models.Token, error)
+	ExpireVerificationSession(ctx context.Context, authToken string) error
+	GetSessionStatus(ctx context.Context, scanRef string) (models.SessionStatus, error)
+	GetSessionData(ctx context.Context, scanRef string) (models.PersonData, error)
 	VerifyCallbackSignature(ctx context.Context, body []byte, sigHeader string) error
 }
@@ -5,6 +5,7 @@ This layer is responsible for interacting with the Substrate API. It wraps the t
 package substrate
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 
@@ -18,6 +19,7 @@ type WsProviderURLGetter interface {
 type SubstrateClient interface {
 	GetChainName() (string, error)
 	GetAddressByTwinID(twinID uint32) (string, error)
+	GetTwinIDByAddress(address string) (uint32, error)
 	GetAccountBalance(address string) (uint64, error)
 }
 
@@ -64,6 +66,22 @@ func (c *Substrate) GetAddressByTwinID(twinID uint32) (string, error) {
 	return twin.Account.String(), nil
 }
 
+// GetTwinIDByAddress returns the twin ID bound to the given address, or 0 if the account has no twin
+func (c *Substrate) GetTwinIDByAddress(address string) (uint32, error) {
+	pubkeyBytes, err := tfchain.FromAddress(address)
+	if err != nil {
+		return 0, fmt.Errorf("decoding ss58 address: %w", err)
+	}
+	twinID, err := c.api.GetTwinByPubKey(pubkeyBytes.PublicKey())
+	if err != nil {
+		if errors.Is(err, tfchain.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("getting twin id from tfchain: %w", err)
+	}
+	return twinID, nil
+}
+
 // get chain name from ws provider url
 func (c *Substrate) GetChainName() (string, error) {
 	api, _, err := c.api.GetClient()
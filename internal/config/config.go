@@ -8,28 +8,58 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"net/url"
 	"slices"
+	"strings"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Config struct {
+	Storage      Storage
 	MongoDB      MongoDB
+	Postgres     Postgres
+	Embedded     Embedded
 	Server       Server
 	Idenfy       Idenfy
 	TFChain      TFChain
+	Networks     Networks
 	Verification Verification
+	Eligibility  Eligibility
 	IPLimiter    IPLimiter
 	IDLimiter    IDLimiter
 	Challenge    Challenge
+	Session      Session
+	Partner      Partner
+	Consent      Consent
+	Links        Links
+	Admin        Admin
+	Migrations   Migrations
 	Log          Log
 }
 
+// Storage selects the database backend of all the data, MongoDB is only connected with the mongodb one
+type Storage struct {
+	Backend string `env:"STORAGE_BACKEND" env-default:"mongodb"`
+}
 type MongoDB struct {
 	URI          string `env:"MONGO_URI" env-default:"mongodb://localhost:27017"`
 	DatabaseName string `env:"DATABASE_NAME" env-default:"tf-kyc-db"`
 }
+type Postgres struct {
+	URL string `env:"POSTGRES_URL" env-default:""`
+	// CleanupInterval in minutes of the job deleting the expired entries, such as tokens and rate limiter entries
+	CleanupInterval uint `env:"POSTGRES_CLEANUP_INTERVAL" env-default:"60"`
+}
+
+// Embedded is the bbolt database file of the embedded storage backend, it can only be opened by a single instance
+type Embedded struct {
+	Path string `env:"EMBEDDED_PATH" env-default:"tf-kyc.db"`
+	// CleanupInterval in minutes of the job deleting the expired entries, such as tokens and rate limiter entries
+	CleanupInterval uint `env:"EMBEDDED_CLEANUP_INTERVAL" env-default:"60"`
+}
 type Server struct {
 	Port string `env:"PORT" env-default:"8080"`
 }
@@ -42,6 +72,15 @@ type Idenfy struct {
 	DevMode         bool     `env:"IDENFY_DEV_MODE" env-default:"false"`
 	CallbackUrl     string   `env:"IDENFY_CALLBACK_URL" env-required:"false"`
 	Namespace       string   `env:"IDENFY_NAMESPACE" env-default:""`
+	// MaxRetries of an idempotent request failing with a network error or a 5xx or 429 status, session creation is only
+	// retried if the connection failed or on a 429 status with a Retry-After header
+	MaxRetries uint `env:"IDENFY_MAX_RETRIES" env-default:"2"`
+	// RetryBaseDelay in milliseconds before the first retry, doubled on each retry and jittered
+	RetryBaseDelay uint `env:"IDENFY_RETRY_BASE_DELAY" env-default:"200"`
+	// BreakerThreshold of consecutive failures opening the circuit breaker, 0 disables it
+	BreakerThreshold uint `env:"IDENFY_BREAKER_THRESHOLD" env-default:"5"`
+	// BreakerCooldown in seconds during which the open circuit breaker fails fast, before a probe request is allowed
+	BreakerCooldown uint `env:"IDENFY_BREAKER_COOLDOWN" env-default:"30"`
 }
 
 // implement getter for Idenfy
@@ -69,6 +108,18 @@ func (c *Idenfy) GetBaseURL() string {
 func (c *Idenfy) GetCallbackSignKey() string {
 	return c.CallbackSignKey
 }
+func (c *Idenfy) GetMaxRetries() uint {
+	return c.MaxRetries
+}
+func (c *Idenfy) GetRetryBaseDelay() time.Duration {
+	return time.Duration(c.RetryBaseDelay) * time.Millisecond
+}
+func (c *Idenfy) GetBreakerThreshold() uint {
+	return c.BreakerThreshold
+}
+func (c *Idenfy) GetBreakerCooldown() time.Duration {
+	return time.Duration(c.BreakerCooldown) * time.Second
+}
 
 type TFChain struct {
 	WsProviderURL string `env:"TFCHAIN_WS_PROVIDER_URL" env-default:"wss://tfchain.grid.tf"`
@@ -79,12 +130,84 @@ func (c *TFChain) GetWsProviderURL() string {
 	return c.WsProviderURL
 }
 
+// Networks are the TFChain networks served next to the default one, configured by TFChain and Challenge
+type Networks struct {
+	File string `env:"NETWORKS_FILE" env-default:""`
+	// Header selects the network of a request, requests without it nor a /networks/:network path prefix are served by the default network
+	Header string `env:"NETWORKS_HEADER" env-default:"X-TFChain-Network"`
+	// List is loaded from File
+	List []Network
+	// Trust is loaded from File, it lists by network name the networks whose approved verifications it accepts
+	Trust map[string][]string
+}
+
+// Network is a single entry of the networks file
+type Network struct {
+	Name            string `yaml:"name" json:"name"`
+	WsProviderURL   string `yaml:"wsProviderUrl" json:"wsProviderUrl"`
+	ChallengeDomain string `yaml:"challengeDomain" json:"challengeDomain"`
+	// SS58Prefixes are the accepted client address prefixes of the network, the first one being canonical.
+	// If not set, the network uses CHALLENGE_ALLOWED_SS58_PREFIXES.
+	SS58Prefixes []uint16 `yaml:"ss58Prefixes" json:"ss58Prefixes,omitempty"`
+}
+
+// implement getter for Network
+func (c *Network) GetWsProviderURL() string {
+	return c.WsProviderURL
+}
+
+type NetworksFile struct {
+	Networks []Network           `yaml:"networks" json:"networks"`
+	Trust    map[string][]string `yaml:"trust" json:"trust"`
+}
+
 type Verification struct {
 	SuspiciousVerificationOutcome string   `env:"VERIFICATION_SUSPICIOUS_VERIFICATION_OUTCOME" env-default:"APPROVED"`
 	ExpiredDocumentOutcome        string   `env:"VERIFICATION_EXPIRED_DOCUMENT_OUTCOME" env-default:"REJECTED"`
 	MinBalanceToVerifyAccount     uint64   `env:"VERIFICATION_MIN_BALANCE_TO_VERIFY_ACCOUNT" env-default:"10000000"`
 	AlwaysVerifiedIDs             []string `env:"VERIFICATION_ALWAYS_VERIFIED_IDS" env-separator:","`
+	MaxAttempts                   uint     `env:"VERIFICATION_MAX_ATTEMPTS" env-default:"0"`
+	DenialCooldown                uint     `env:"VERIFICATION_DENIAL_COOLDOWN" env-default:"0"`
+	DuplicateIdentityOutcome      string   `env:"VERIFICATION_DUPLICATE_IDENTITY_OUTCOME" env-default:"FLAG"`
+	FingerprintKey                string   `env:"VERIFICATION_FINGERPRINT_KEY" env-default:""`
+	RejectedSuspicionReasons      []string `env:"VERIFICATION_REJECTED_SUSPICION_REASONS" env-separator:","`
+	RejectedFraudTags             []string `env:"VERIFICATION_REJECTED_FRAUD_TAGS" env-separator:","`
+	RejectedAMLResultClasses      []string `env:"VERIFICATION_REJECTED_AML_RESULT_CLASSES" env-separator:","`
+	RejectedPEPSStatuses          []string `env:"VERIFICATION_REJECTED_PEPS_STATUSES" env-separator:","`
+	RejectedSanctionsStatuses     []string `env:"VERIFICATION_REJECTED_SANCTIONS_STATUSES" env-separator:","`
+	DocumentExpiryWarningDays     uint     `env:"VERIFICATION_DOCUMENT_EXPIRY_WARNING_DAYS" env-default:"30"`
+	DocumentExpiryCheckInterval   uint     `env:"VERIFICATION_DOCUMENT_EXPIRY_CHECK_INTERVAL" env-default:"1440"`
+	SessionLockTTL                uint     `env:"VERIFICATION_SESSION_LOCK_TTL" env-default:"30"`
+	// ReconciliationInterval in minutes of the job polling iDenfy for the sessions whose result never arrived, 0 disables it
+	ReconciliationInterval uint `env:"VERIFICATION_RECONCILIATION_INTERVAL" env-default:"15"`
+	// ReconciliationTimeout in minutes after which a session without result is polled
+	ReconciliationTimeout uint `env:"VERIFICATION_RECONCILIATION_TIMEOUT" env-default:"60"`
+	// ReconciliationMaxAge in minutes after which a session without result is no longer polled
+	ReconciliationMaxAge uint `env:"VERIFICATION_RECONCILIATION_MAX_AGE" env-default:"10080"`
+}
+type Eligibility struct {
+	PolicyFile    string `env:"ELIGIBILITY_POLICY_FILE" env-default:""`
+	CountryHeader string `env:"ELIGIBILITY_COUNTRY_HEADER" env-default:""`
+	// Rules are loaded from PolicyFile, in order. If no file is set, only the min_balance rule is applied.
+	Rules []EligibilityRule
 }
+
+// EligibilityRule is a single entry of the eligibility policy file.
+// Only the fields relevant to the rule type are used.
+type EligibilityRule struct {
+	Type string `yaml:"type" json:"type"`
+	// MinBalance is nil when not set, the min_balance rule then uses MinBalanceToVerifyAccount
+	MinBalance       *uint64  `yaml:"minBalance" json:"minBalance,omitempty"`
+	ClientIDs        []string `yaml:"clientIds" json:"clientIds,omitempty"`
+	MaxAttempts      uint     `yaml:"maxAttempts" json:"maxAttempts,omitempty"`
+	AllowedCountries []string `yaml:"allowedCountries" json:"allowedCountries,omitempty"`
+	DeniedCountries  []string `yaml:"deniedCountries" json:"deniedCountries,omitempty"`
+}
+
+type EligibilityPolicy struct {
+	Rules []EligibilityRule `yaml:"rules" json:"rules"`
+}
+
 type IPLimiter struct {
 	MaxTokenRequests uint `env:"IP_LIMITER_MAX_TOKEN_REQUESTS" env-default:"4"`
 	TokenExpiration  uint `env:"IP_LIMITER_TOKEN_EXPIRATION" env-default:"1440"`
@@ -93,12 +216,40 @@ type IDLimiter struct {
 	MaxTokenRequests uint `env:"ID_LIMITER_MAX_TOKEN_REQUESTS" env-default:"4"`
 	TokenExpiration  uint `env:"ID_LIMITER_TOKEN_EXPIRATION" env-default:"1440"`
 }
+type Migrations struct {
+	OnStartup bool `env:"MIGRATIONS_ON_STARTUP" env-default:"true"`
+}
 type Log struct {
 	Debug bool `env:"DEBUG" env-default:"false"`
 }
+type Session struct {
+	SigningKey string `env:"SESSION_SIGNING_KEY" env-default:""`
+	TTL        uint   `env:"SESSION_TTL" env-default:"15"`
+}
+type Partner struct {
+	AllowAnonymousStatus bool `env:"PARTNER_ALLOW_ANONYMOUS_STATUS" env-default:"true"`
+}
+type Consent struct {
+	MaxDuration uint `env:"CONSENT_MAX_DURATION" env-default:"43200"`
+}
+type Links struct {
+	// MaxLinks is the number of accounts a verified client can link at the same time, 0 disables linking
+	MaxLinks uint `env:"LINKS_MAX_LINKS" env-default:"5"`
+}
+type Admin struct {
+	APIKey string `env:"ADMIN_API_KEY" env-default:""`
+}
 type Challenge struct {
-	Window int64  `env:"CHALLENGE_WINDOW" env-default:"8"`
-	Domain string `env:"CHALLENGE_DOMAIN" env-required:"true"`
+	Window    int64  `env:"CHALLENGE_WINDOW" env-default:"8"`
+	Domain    string `env:"CHALLENGE_DOMAIN" env-required:"true"`
+	ClockSkew int64  `env:"CHALLENGE_CLOCK_SKEW" env-default:"2"`
+	NonceTTL  int64  `env:"CHALLENGE_NONCE_TTL" env-default:"60"`
+	// NonceRateLimit is the number of nonces an IP can request per minute, 0 disables the limit
+	NonceRateLimit    uint `env:"CHALLENGE_NONCE_RATE_LIMIT" env-default:"30"`
+	AllowLegacyFormat bool `env:"CHALLENGE_ALLOW_LEGACY_FORMAT" env-default:"false"`
+	AllowEcdsa        bool `env:"CHALLENGE_ALLOW_ECDSA" env-default:"false"`
+	// AllowedSS58Prefixes are the accepted client address prefixes, the first one is the canonical prefix client IDs are stored with
+	AllowedSS58Prefixes []uint16 `env:"CHALLENGE_ALLOWED_SS58_PREFIXES" env-separator:"," env-default:"42"`
 }
 
 func LoadConfigFromEnv() (*Config, error) {
@@ -107,10 +258,76 @@ func LoadConfigFromEnv() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("loading config: %w", err)
 	}
+	rules, err := loadEligibilityRules(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("loading eligibility policy: %w", err)
+	}
+	cfg.Eligibility.Rules = rules
+	networks, err := loadNetworks(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("loading networks: %w", err)
+	}
+	cfg.Networks.List = networks.Networks
+	cfg.Networks.Trust = networks.Trust
 	// cfg.Validate()
+	if err := cfg.validateEligibility(); err != nil {
+		return nil, fmt.Errorf("validating eligibility policy: %w", err)
+	}
 	return cfg, nil
 }
 
+// loadEligibilityRules loads the rules of the policy file, the min_balance and max_attempts rules default to the
+// VERIFICATION_MIN_BALANCE_TO_VERIFY_ACCOUNT and VERIFICATION_MAX_ATTEMPTS variables
+func loadEligibilityRules(cfg *Config) ([]EligibilityRule, error) {
+	var policy EligibilityPolicy
+	if cfg.Eligibility.PolicyFile == "" {
+		policy.Rules = []EligibilityRule{{Type: "min_balance"}}
+	} else if err := cleanenv.ReadConfig(cfg.Eligibility.PolicyFile, &policy); err != nil {
+		return nil, err
+	}
+	hasMaxAttempts := false
+	for i := range policy.Rules {
+		switch policy.Rules[i].Type {
+		case "min_balance":
+			// an explicit minBalance, 0 included, is kept
+			if policy.Rules[i].MinBalance == nil {
+				policy.Rules[i].MinBalance = &cfg.Verification.MinBalanceToVerifyAccount
+			}
+		case "max_attempts":
+			hasMaxAttempts = true
+			// maxAttempts can't be 0, it is only missing
+			if policy.Rules[i].MaxAttempts == 0 {
+				policy.Rules[i].MaxAttempts = cfg.Verification.MaxAttempts
+			}
+		}
+	}
+	if !hasMaxAttempts && cfg.Verification.MaxAttempts > 0 {
+		policy.Rules = append(policy.Rules, EligibilityRule{Type: "max_attempts", MaxAttempts: cfg.Verification.MaxAttempts})
+	}
+	return policy.Rules, nil
+}
+
+// MaxAttempts returns the verification attempts cap of the max_attempts rule, 0 if there is none
+func (e Eligibility) MaxAttempts() uint {
+	for _, rule := range e.Rules {
+		if rule.Type == "max_attempts" {
+			return rule.MaxAttempts
+		}
+	}
+	return 0
+}
+
+func loadNetworks(cfg *Config) (*NetworksFile, error) {
+	if cfg.Networks.File == "" {
+		return &NetworksFile{}, nil
+	}
+	var file NetworksFile
+	if err := cleanenv.ReadConfig(cfg.Networks.File, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
 func (c Config) GetPublicConfig() Config {
 	// deducting the secret fields
 	config := c
@@ -118,11 +335,35 @@ func (c Config) GetPublicConfig() Config {
 	config.Idenfy.APISecret = "[REDACTED]"
 	config.Idenfy.CallbackSignKey = "[REDACTED]"
 	config.MongoDB.URI = "[REDACTED]"
+	config.Postgres.URL = "[REDACTED]"
+	config.Admin.APIKey = "[REDACTED]"
+	config.Session.SigningKey = "[REDACTED]"
+	config.Verification.FingerprintKey = "[REDACTED]"
 	return config
 }
 
 // validate config
 func (c *Config) Validate() error {
+	// Storage backend
+	switch c.Storage.Backend {
+	case "mongodb":
+	case "postgres":
+		if c.Postgres.URL == "" {
+			return errors.New("invalid Postgres URL. it is required when the storage backend is postgres")
+		}
+		if c.Postgres.CleanupInterval == 0 {
+			return errors.New("invalid Postgres CleanupInterval. it should be greater than 0")
+		}
+	case "embedded":
+		if c.Embedded.Path == "" {
+			return errors.New("invalid Embedded Path. it is required when the storage backend is embedded")
+		}
+		if c.Embedded.CleanupInterval == 0 {
+			return errors.New("invalid Embedded CleanupInterval. it should be greater than 0")
+		}
+	default:
+		return errors.New("invalid Storage Backend. it should be one of mongodb, postgres, embedded")
+	}
 	// iDenfy base URL should be https://ivs.idenfy.com. This is the only supported base URL for now.
 	if c.Idenfy.BaseURL != "https://ivs.idenfy.com" {
 		return errors.New("invalid iDenfy base URL. it should be https://ivs.idenfy.com")
@@ -140,6 +381,10 @@ func (c *Config) Validate() error {
 	if u, err := url.ParseRequestURI(c.TFChain.WsProviderURL); err != nil || u.Scheme != "wss" {
 		return errors.New("invalid WsProviderURL")
 	}
+	// Networks
+	if err := c.validateNetworks(); err != nil {
+		return err
+	}
 	// domain should not be empty and same as domain in CallbackUrl
 	if parsedCallbackUrl.Host != c.Challenge.Domain {
 		return errors.New("invalid Challenge Domain. It should be same as domain in CallbackUrl")
@@ -148,6 +393,22 @@ func (c *Config) Validate() error {
 	if c.Challenge.Window < 2 {
 		return errors.New("invalid Challenge Window. It should be greater than 2 otherwise it will be too short and verification can fail in slow networks")
 	}
+	// ClockSkew should not be negative nor exceed the window, otherwise future-dated challenges stay valid too long
+	if c.Challenge.ClockSkew < 0 || c.Challenge.ClockSkew > c.Challenge.Window {
+		return errors.New("invalid Challenge ClockSkew. It should be between 0 and the Challenge Window")
+	}
+	// AllowedSS58Prefixes should not be empty, otherwise client IDs are not normalized
+	if len(c.Challenge.AllowedSS58Prefixes) == 0 {
+		return errors.New("invalid Challenge AllowedSS58Prefixes. It should contain at least the canonical prefix of the network")
+	}
+	// AllowLegacyFormat
+	if c.Challenge.AllowLegacyFormat {
+		slog.Warn("Challenge AllowLegacyFormat is enabled. Legacy challenges without nonce can be replayed within the challenge window, it should be disabled once all clients sign nonce challenges.")
+	}
+	// NonceTTL should leave the client enough time to sign the challenge
+	if c.Challenge.NonceTTL < c.Challenge.Window {
+		return errors.New("invalid Challenge NonceTTL. It should be greater than or equal to the Challenge Window")
+	}
 	// SuspiciousVerificationOutcome should be either APPROVED or REJECTED
 	if !slices.Contains([]string{"APPROVED", "REJECTED"}, c.Verification.SuspiciousVerificationOutcome) {
 		return errors.New("invalid SuspiciousVerificationOutcome. should be either APPROVED or REJECTED")
@@ -156,17 +417,99 @@ func (c *Config) Validate() error {
 	if !slices.Contains([]string{"APPROVED", "REJECTED"}, c.Verification.ExpiredDocumentOutcome) {
 		return errors.New("invalid ExpiredDocumentOutcome. should be either APPROVED or REJECTED")
 	}
+	// DuplicateIdentityOutcome should be either REJECT, FLAG or LINK
+	if !slices.Contains([]string{"REJECT", "FLAG", "LINK"}, c.Verification.DuplicateIdentityOutcome) {
+		return errors.New("invalid DuplicateIdentityOutcome. should be either REJECT, FLAG or LINK")
+	}
+	// FingerprintKey, the server warns when it is not set
+	if c.Verification.FingerprintKey != "" && len(c.Verification.FingerprintKey) < 32 {
+		return errors.New("invalid FingerprintKey. it should be at least 32 characters long")
+	}
 	// MinBalanceToVerifyAccount
 	if c.Verification.MinBalanceToVerifyAccount < 20000000 {
 		slog.Warn("Verification MinBalanceToVerifyAccount is less than 20000000. This is not recommended and can lead to security issues. If you are sure about this, you can ignore this message.")
 	}
+	// Reconciliation
+	if c.Verification.ReconciliationInterval > 0 && c.Verification.ReconciliationMaxAge <= c.Verification.ReconciliationTimeout {
+		return errors.New("invalid Verification ReconciliationMaxAge. it should be greater than ReconciliationTimeout")
+	}
+	// SessionLockTTL
+	if c.Verification.SessionLockTTL == 0 {
+		return errors.New("invalid Verification SessionLockTTL. it should be greater than 0")
+	}
+	// iDenfy circuit breaker
+	if c.Idenfy.BreakerThreshold > 0 && c.Idenfy.BreakerCooldown == 0 {
+		return errors.New("invalid iDenfy BreakerCooldown. it should be greater than 0 when the circuit breaker is enabled")
+	}
 	// DevMode
 	if c.Idenfy.DevMode {
 		slog.Warn("iDenfy DevMode is enabled. This is not intended for environments other than development. If you are sure about this, you can ignore this message.")
 	}
+	// Session signing key
+	if c.Session.SigningKey != "" && len(c.Session.SigningKey) < 32 {
+		return errors.New("invalid Session SigningKey. it should be at least 32 characters long")
+	}
+	if c.Session.SigningKey != "" && c.Session.TTL == 0 {
+		return errors.New("invalid Session TTL. it should be greater than 0")
+	}
+	// Partner API keys can only be managed with the admin API key
+	if !c.Partner.AllowAnonymousStatus && c.Admin.APIKey == "" {
+		return errors.New("invalid Partner config. anonymous status queries can only be disabled when an Admin APIKey is set to manage partner API keys")
+	}
+	// Consent
+	if c.Consent.MaxDuration == 0 {
+		return errors.New("invalid Consent MaxDuration. it should be greater than 0")
+	}
+	// Admin API key
+	if c.Admin.APIKey != "" && len(c.Admin.APIKey) < 32 {
+		return errors.New("invalid Admin APIKey. it should be at least 32 characters long")
+	}
 	// Namespace
 	if c.Idenfy.Namespace != "" {
 		slog.Warn("iDenfy Namespace is set. This ideally should be empty. If you are sure about this, you can ignore this message.")
 	}
 	return nil
 }
+
+// validateEligibility checks the policy rules and the attempt limits, the rule types are checked when the policy is built
+func (c *Config) validateEligibility() error {
+	for i, rule := range c.Eligibility.Rules {
+		if rule.Type == "max_attempts" && rule.MaxAttempts == 0 {
+			return fmt.Errorf("invalid Eligibility rule %d. max_attempts should set maxAttempts or VERIFICATION_MAX_ATTEMPTS greater than 0", i)
+		}
+	}
+	// DenialCooldown is in minutes, it should fit in a duration
+	if c.Verification.DenialCooldown > uint(math.MaxInt64/int64(time.Minute)) {
+		return errors.New("invalid Verification DenialCooldown. it is too large")
+	}
+	return nil
+}
+
+// validateNetworks checks the networks of the networks file, the default network is validated with TFChain and Challenge
+func (c *Config) validateNetworks() error {
+	if c.Networks.Header == "" {
+		return errors.New("invalid Networks Header. it should not be empty")
+	}
+	names := map[string]bool{}
+	for _, network := range c.Networks.List {
+		if network.Name == "" || strings.ContainsAny(network.Name, "/: ") {
+			return fmt.Errorf("invalid network name %q. it should not be empty nor contain '/', ':' or spaces", network.Name)
+		}
+		if names[network.Name] {
+			return fmt.Errorf("invalid networks. network %s is configured twice", network.Name)
+		}
+		names[network.Name] = true
+		if u, err := url.ParseRequestURI(network.WsProviderURL); err != nil || u.Scheme != "wss" {
+			return fmt.Errorf("invalid WsProviderURL of network %s", network.Name)
+		}
+		if network.ChallengeDomain == "" {
+			return fmt.Errorf("invalid ChallengeDomain of network %s. it should not be empty", network.Name)
+		}
+		for _, prefix := range network.SS58Prefixes {
+			if prefix > 16383 {
+				return fmt.Errorf("invalid SS58Prefixes of network %s. %d is not an SS58 prefix", network.Name, prefix)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setRequiredEnv sets the required variables, the other ones keep their defaults
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("IDENFY_API_KEY", "key")
+	t.Setenv("IDENFY_API_SECRET", "secret")
+	t.Setenv("IDENFY_CALLBACK_SIGN_KEY", "callback-sign-key-of-32-characters")
+	t.Setenv("IDENFY_CALLBACK_URL", "https://kyc.dev.grid.tf/webhooks/idenfy/verification-update")
+	t.Setenv("CHALLENGE_DOMAIN", "kyc.dev.grid.tf")
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(policyFile, []byte("rules:\n  - type: max_attempts\n"), 0o600))
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedError string
+	}{
+		{name: "defaults are valid"},
+		// the other options are not validated when loading, deployments relying on it keep starting
+		{name: "callback url of another domain", env: map[string]string{"CHALLENGE_DOMAIN": "kyc.qa.grid.tf"}},
+		{name: "missing policy file", env: map[string]string{"ELIGIBILITY_POLICY_FILE": policyFile + ".missing"}, expectedError: "loading eligibility policy"},
+		{name: "max_attempts rule without cap", env: map[string]string{"ELIGIBILITY_POLICY_FILE": policyFile}, expectedError: "invalid Eligibility rule 0"},
+		{name: "max_attempts rule with the env cap", env: map[string]string{"ELIGIBILITY_POLICY_FILE": policyFile, "VERIFICATION_MAX_ATTEMPTS": "3"}},
+		{name: "denial cooldown overflowing", env: map[string]string{"VERIFICATION_DENIAL_COOLDOWN": "1000000000"}, expectedError: "invalid Verification DenialCooldown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg, err := LoadConfigFromEnv()
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "mongodb", cfg.Storage.Backend)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedError string
+	}{
+		{name: "defaults are valid"},
+		{name: "short admin key", env: map[string]string{"ADMIN_API_KEY": "short"}, expectedError: "invalid Admin APIKey"},
+		{name: "short session signing key", env: map[string]string{"SESSION_SIGNING_KEY": "short"}, expectedError: "invalid Session SigningKey"},
+		{name: "nonce ttl shorter than the window", env: map[string]string{"CHALLENGE_NONCE_TTL": "4"}, expectedError: "invalid Challenge NonceTTL"},
+		{name: "clock skew larger than the window", env: map[string]string{"CHALLENGE_CLOCK_SKEW": "10"}, expectedError: "invalid Challenge ClockSkew"},
+		{name: "breaker without cooldown", env: map[string]string{"IDENFY_BREAKER_COOLDOWN": "0"}, expectedError: "invalid iDenfy BreakerCooldown"},
+		{name: "callback url of another domain", env: map[string]string{"CHALLENGE_DOMAIN": "kyc.qa.grid.tf"}, expectedError: "invalid Challenge Domain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			cfg, err := LoadConfigFromEnv()
+			assert.NoError(t, err)
+			err = cfg.Validate()
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLoadEligibilityRules(t *testing.T) {
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	policy := "rules:\n  - type: min_balance\n    minBalance: 0\n  - type: min_balance\n"
+	assert.NoError(t, os.WriteFile(policyFile, []byte(policy), 0o600))
+
+	cfg := &Config{
+		Eligibility:  Eligibility{PolicyFile: policyFile},
+		Verification: Verification{MinBalanceToVerifyAccount: 10000000},
+	}
+	rules, err := loadEligibilityRules(cfg)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 2)
+	// the explicit 0 is kept, the missing minBalance uses the env default
+	assert.Equal(t, uint64(0), *rules[0].MinBalance)
+	assert.Equal(t, uint64(10000000), *rules[1].MinBalance)
+}
+
+func TestLoadEligibilityRulesMaxAttempts(t *testing.T) {
+	// VERIFICATION_MAX_ATTEMPTS is enforced by the max_attempts rule
+	cfg := &Config{Verification: Verification{MaxAttempts: 3}}
+	rules, err := loadEligibilityRules(cfg)
+	assert.NoError(t, err)
+	cfg.Eligibility.Rules = rules
+	assert.Equal(t, []string{"min_balance", "max_attempts"}, []string{rules[0].Type, rules[1].Type})
+	assert.Equal(t, uint(3), cfg.Eligibility.MaxAttempts())
+
+	// the max_attempts rule of the policy file is the only cap
+	policyFile := filepath.Join(t.TempDir(), "policy.yaml")
+	assert.NoError(t, os.WriteFile(policyFile, []byte("rules:\n  - type: max_attempts\n    maxAttempts: 5\n"), 0o600))
+	cfg.Eligibility.PolicyFile = policyFile
+	rules, err = loadEligibilityRules(cfg)
+	assert.NoError(t, err)
+	cfg.Eligibility.Rules = rules
+	assert.Len(t, rules, 1)
+	assert.Equal(t, uint(5), cfg.Eligibility.MaxAttempts())
+}
@@ -0,0 +1,96 @@
+/*
+Package eligibility contains the eligibility policy for issuing verification tokens.
+This layer is responsible for deciding whether a client may start a new (paid) iDenfy verification session.
+The policy is an ordered chain of rules loaded from the configuration; the first rule that rejects the client wins.
+*/
+package eligibility
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/substrate"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+const (
+	RuleMinBalance  = "min_balance"
+	RuleDenylist    = "denylist"
+	RuleMaxAttempts = "max_attempts"
+	RuleTwinExists  = "twin_exists"
+	RuleCountry     = "country"
+)
+
+// Subject is the client asking for a verification token
+type Subject struct {
+	ClientID string
+	// Country is the ISO 3166-1 alpha-2 country code of the request, if known
+	Country string
+}
+
+// Rule is a single eligibility check.
+// Check returns nil if the subject passes the rule, or a *errors.ServiceError whose type describes the rejection reason.
+type Rule interface {
+	Name() string
+	Check(ctx context.Context, subject Subject) error
+}
+
+type Policy struct {
+	rules  []Rule
+	logger *slog.Logger
+}
+
+// New builds the policy of the rules, the denylisted addresses are normalized with the allowed SS58 prefixes of the network
+func New(rules []config.EligibilityRule, allowedSS58Prefixes []uint16, verificationRepo repository.VerificationRepository, attemptRepo repository.AttemptRepository, substrateClient substrate.SubstrateClient, logger *slog.Logger) (*Policy, error) {
+	policy := &Policy{logger: logger}
+	for i, ruleConfig := range rules {
+		rule, err := newRule(ruleConfig, allowedSS58Prefixes, verificationRepo, attemptRepo, substrateClient)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if ruleConfig.Type == RuleMinBalance && *ruleConfig.MinBalance == 0 {
+			logger.Warn("Minimum balance to verify account is 0 which is not recommended")
+		}
+		policy.rules = append(policy.rules, rule)
+	}
+	return policy, nil
+}
+
+func newRule(ruleConfig config.EligibilityRule, allowedSS58Prefixes []uint16, verificationRepo repository.VerificationRepository, attemptRepo repository.AttemptRepository, substrateClient substrate.SubstrateClient) (Rule, error) {
+	switch ruleConfig.Type {
+	case RuleMinBalance:
+		if ruleConfig.MinBalance == nil {
+			return nil, fmt.Errorf("%s: minBalance is required", RuleMinBalance)
+		}
+		return &minBalanceRule{minBalance: *ruleConfig.MinBalance, substrate: substrateClient}, nil
+	case RuleDenylist:
+		return newDenylistRule(ruleConfig.ClientIDs, allowedSS58Prefixes)
+	case RuleMaxAttempts:
+		if ruleConfig.MaxAttempts == 0 {
+			return nil, fmt.Errorf("%s: maxAttempts should be greater than 0", RuleMaxAttempts)
+		}
+		return &maxAttemptsRule{maxAttempts: ruleConfig.MaxAttempts, attemptRepo: attemptRepo}, nil
+	case RuleTwinExists:
+		return &twinExistsRule{substrate: substrateClient}, nil
+	case RuleCountry:
+		if len(ruleConfig.AllowedCountries) > 0 && len(ruleConfig.DeniedCountries) > 0 {
+			return nil, fmt.Errorf("%s: allowedCountries and deniedCountries are mutually exclusive", RuleCountry)
+		}
+		return newCountryRule(ruleConfig.AllowedCountries, ruleConfig.DeniedCountries, verificationRepo), nil
+	default:
+		return nil, fmt.Errorf("unknown rule type %q", ruleConfig.Type)
+	}
+}
+
+// Evaluate runs the rules in order and returns the error of the first rule that rejects the subject
+func (p *Policy) Evaluate(ctx context.Context, subject Subject) error {
+	for _, rule := range p.rules {
+		if err := rule.Check(ctx, subject); err != nil {
+			p.logger.Info("Client rejected by eligibility rule", "clientID", subject.ClientID, "rule", rule.Name(), "error", err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package eligibility
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+type fakeSubstrate struct {
+	balance uint64
+	twinID  uint32
+}
+
+func (f *fakeSubstrate) GetChainName() (string, error)                    { return "TFChain Devnet", nil }
+func (f *fakeSubstrate) GetAddressByTwinID(twinID uint32) (string, error) { return "", nil }
+func (f *fakeSubstrate) GetTwinIDByAddress(address string) (uint32, error) {
+	return f.twinID, nil
+}
+func (f *fakeSubstrate) GetAccountBalance(address string) (uint64, error) {
+	return f.balance, nil
+}
+
+type fakeVerificationRepo struct {
+	latest *models.Verification
+}
+
+func (f *fakeVerificationRepo) SaveVerification(ctx context.Context, verification *models.Verification) error {
+	return nil
+}
+func (f *fakeVerificationRepo) GetVerification(ctx context.Context, clientID string) (*models.Verification, error) {
+	return f.latest, nil
+}
+func (f *fakeVerificationRepo) GetVerificationsByScanRefs(ctx context.Context, scanRefs []string) ([]models.Verification, error) {
+	return nil, nil
+}
+
+func (f *fakeVerificationRepo) FlagExpiringDocuments(ctx context.Context, from string, until string, flaggedAt time.Time) ([]string, error) {
+	return nil, nil
+}
+
+type fakeAttemptRepo struct {
+	count uint
+}
+
+func (f *fakeAttemptRepo) GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	return &models.VerificationAttempts{ClientID: clientID, Count: f.count}, nil
+}
+func (f *fakeAttemptRepo) RecordAttempt(ctx context.Context, clientID string, scanRef string) error {
+	return nil
+}
+func (f *fakeAttemptRepo) RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error {
+	return nil
+}
+func (f *fakeAttemptRepo) ResetAttempts(ctx context.Context, clientID string) error {
+	return nil
+}
+
+const (
+	// client and denied are the SS58 addresses of the Alice and Bob development accounts, with the generic prefix 42
+	client = "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+	denied = "5FHneW46xGXgs5mUiveU4sbTyGBzmstUspZC92UhjJM694ty"
+)
+
+var testSS58Prefixes = []uint16{42}
+
+func TestPolicyEvaluate(t *testing.T) {
+	minBalance := uint64(10 * TFT_CONVERSION_FACTOR)
+	rules := []config.EligibilityRule{
+		{Type: RuleDenylist, ClientIDs: []string{denied}},
+		{Type: RuleMinBalance, MinBalance: &minBalance},
+		{Type: RuleMaxAttempts, MaxAttempts: 2},
+		{Type: RuleTwinExists},
+		{Type: RuleCountry, DeniedCountries: []string{"xx"}},
+	}
+	tests := []struct {
+		name         string
+		subject      Subject
+		substrate    *fakeSubstrate
+		repo         *fakeVerificationRepo
+		attempts     *fakeAttemptRepo
+		expectedType errors.ErrorType
+	}{
+		{
+			name:      "eligible",
+			subject:   Subject{ClientID: client, Country: "BE"},
+			substrate: &fakeSubstrate{balance: 10 * TFT_CONVERSION_FACTOR, twinID: 1},
+			repo:      &fakeVerificationRepo{},
+			attempts:  &fakeAttemptRepo{count: 1},
+		},
+		{
+			name:         "denylisted before balance check",
+			subject:      Subject{ClientID: denied},
+			substrate:    &fakeSubstrate{},
+			repo:         &fakeVerificationRepo{},
+			expectedType: errors.ErrorTypeDenied,
+		},
+		{
+			name:         "not sufficient balance",
+			subject:      Subject{ClientID: client},
+			substrate:    &fakeSubstrate{balance: 1, twinID: 1},
+			repo:         &fakeVerificationRepo{},
+			expectedType: errors.ErrorTypeNotSufficientBalance,
+		},
+		{
+			name:         "too many attempts",
+			subject:      Subject{ClientID: client},
+			substrate:    &fakeSubstrate{balance: 10 * TFT_CONVERSION_FACTOR, twinID: 1},
+			repo:         &fakeVerificationRepo{},
+			attempts:     &fakeAttemptRepo{count: 2},
+			expectedType: errors.ErrorTypeTooManyAttempts,
+		},
+		{
+			name:         "no twin",
+			subject:      Subject{ClientID: client},
+			substrate:    &fakeSubstrate{balance: 10 * TFT_CONVERSION_FACTOR},
+			repo:         &fakeVerificationRepo{},
+			expectedType: errors.ErrorTypeTwinNotFound,
+		},
+		{
+			name:         "denied request country",
+			subject:      Subject{ClientID: client, Country: "XX"},
+			substrate:    &fakeSubstrate{balance: 10 * TFT_CONVERSION_FACTOR, twinID: 1},
+			repo:         &fakeVerificationRepo{},
+			expectedType: errors.ErrorTypeCountryRestricted,
+		},
+		{
+			name:      "denied document country",
+			subject:   Subject{ClientID: client},
+			substrate: &fakeSubstrate{balance: 10 * TFT_CONVERSION_FACTOR, twinID: 1},
+			repo: &fakeVerificationRepo{latest: &models.Verification{
+				Data: models.PersonData{DocIssuingCountry: "XX"},
+			}},
+			expectedType: errors.ErrorTypeCountryRestricted,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := tt.attempts
+			if attempts == nil {
+				attempts = &fakeAttemptRepo{}
+			}
+			policy, err := New(rules, testSS58Prefixes, tt.repo, attempts, tt.substrate, slog.Default())
+			assert.NoError(t, err)
+			err = policy.Evaluate(context.Background(), tt.subject)
+			if tt.expectedType == "" {
+				assert.NoError(t, err)
+				return
+			}
+			serviceErr, ok := err.(*errors.ServiceError)
+			assert.True(t, ok, "expected a service error, got %v", err)
+			if ok {
+				assert.Equal(t, tt.expectedType, serviceErr.Type)
+			}
+		})
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	invalid := [][]config.EligibilityRule{
+		{{Type: "unknown"}},
+		{{Type: RuleMinBalance}},
+		{{Type: RuleMaxAttempts}},
+		{{Type: RuleCountry, AllowedCountries: []string{"BE"}, DeniedCountries: []string{"XX"}}},
+	}
+	for _, rules := range invalid {
+		_, err := New(rules, testSS58Prefixes, &fakeVerificationRepo{}, &fakeAttemptRepo{}, &fakeSubstrate{}, slog.Default())
+		assert.Error(t, err)
+	}
+}
+
+func TestDenylistNormalizesClientIDs(t *testing.T) {
+	// the denied account encoded with another prefix of the network
+	reencoded, err := address.Reencode(denied, 2)
+	require.NoError(t, err)
+	policy, err := New([]config.EligibilityRule{{Type: RuleDenylist, ClientIDs: []string{reencoded}}}, []uint16{42, 2}, nil, nil, nil, slog.Default())
+	require.NoError(t, err)
+	assert.Error(t, policy.Evaluate(context.Background(), Subject{ClientID: denied}))
+	assert.NoError(t, policy.Evaluate(context.Background(), Subject{ClientID: client}))
+
+	// entries that aren't addresses of the network are rejected when the rules load
+	for _, clientID := range []string{"denied", reencoded} {
+		_, err := New([]config.EligibilityRule{{Type: RuleDenylist, ClientIDs: []string{clientID}}}, testSS58Prefixes, nil, nil, nil, slog.Default())
+		assert.ErrorContains(t, err, clientID)
+	}
+}
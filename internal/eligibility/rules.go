@@ -0,0 +1,153 @@
+package eligibility
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/substrate"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+const TFT_CONVERSION_FACTOR = 10000000
+
+type minBalanceRule struct {
+	minBalance uint64
+	substrate  substrate.SubstrateClient
+}
+
+func (r *minBalanceRule) Name() string {
+	return RuleMinBalance
+}
+
+func (r *minBalanceRule) Check(ctx context.Context, subject Subject) error {
+	if r.minBalance == 0 {
+		return nil
+	}
+	balance, err := r.substrate.GetAccountBalance(subject.ClientID)
+	if err != nil {
+		return errors.NewExternalError("getting account balance", err)
+	}
+	if balance < r.minBalance {
+		return errors.NewNotSufficientBalanceError(fmt.Sprintf("account does not have the minimum required balance to verify (%d) TFT", r.minBalance/TFT_CONVERSION_FACTOR), nil)
+	}
+	return nil
+}
+
+type denylistRule struct {
+	clientIDs map[string]struct{}
+}
+
+// newDenylistRule normalizes the denied addresses as the clientIds of the requests are, so any encoding of a denied account matches
+func newDenylistRule(clientIDs []string, allowedSS58Prefixes []uint16) (*denylistRule, error) {
+	rule := &denylistRule{clientIDs: make(map[string]struct{}, len(clientIDs))}
+	for _, clientID := range clientIDs {
+		normalized, err := address.Normalize(clientID, allowedSS58Prefixes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: clientId %q: %w", RuleDenylist, clientID, err)
+		}
+		rule.clientIDs[normalized] = struct{}{}
+	}
+	return rule, nil
+}
+
+func (r *denylistRule) Name() string {
+	return RuleDenylist
+}
+
+func (r *denylistRule) Check(ctx context.Context, subject Subject) error {
+	if _, ok := r.clientIDs[subject.ClientID]; ok {
+		return errors.NewDeniedError("account is not allowed to verify", nil)
+	}
+	return nil
+}
+
+type maxAttemptsRule struct {
+	maxAttempts uint
+	attemptRepo repository.AttemptRepository
+}
+
+func (r *maxAttemptsRule) Name() string {
+	return RuleMaxAttempts
+}
+
+func (r *maxAttemptsRule) Check(ctx context.Context, subject Subject) error {
+	attempts, err := r.attemptRepo.GetAttempts(ctx, subject.ClientID)
+	if err != nil {
+		return errors.NewInternalError("getting verification attempts from database", err)
+	}
+	if attempts != nil && attempts.Count >= r.maxAttempts {
+		return errors.NewTooManyAttemptsError(fmt.Sprintf("account reached the maximum number of verification attempts (%d), contact support to reset it", r.maxAttempts), nil)
+	}
+	return nil
+}
+
+type twinExistsRule struct {
+	substrate substrate.SubstrateClient
+}
+
+func (r *twinExistsRule) Name() string {
+	return RuleTwinExists
+}
+
+func (r *twinExistsRule) Check(ctx context.Context, subject Subject) error {
+	twinID, err := r.substrate.GetTwinIDByAddress(subject.ClientID)
+	if err != nil {
+		return errors.NewExternalError("looking up account twin from TFChain", err)
+	}
+	if twinID == 0 {
+		return errors.NewTwinNotFoundError("account has no twin on TFChain, create a twin before verifying", nil)
+	}
+	return nil
+}
+
+// countryRule checks the request country and, for returning clients, the countries of their latest verification document.
+// Unknown countries are not rejected.
+type countryRule struct {
+	allowed          []string
+	denied           []string
+	verificationRepo repository.VerificationRepository
+}
+
+func newCountryRule(allowed, denied []string, verificationRepo repository.VerificationRepository) *countryRule {
+	return &countryRule{
+		allowed:          normalizeCountries(allowed),
+		denied:           normalizeCountries(denied),
+		verificationRepo: verificationRepo,
+	}
+}
+
+func (r *countryRule) Name() string {
+	return RuleCountry
+}
+
+func (r *countryRule) Check(ctx context.Context, subject Subject) error {
+	countries := []string{subject.Country}
+	verification, err := r.verificationRepo.GetVerification(ctx, subject.ClientID)
+	if err != nil {
+		return errors.NewInternalError("getting verification from database", err)
+	}
+	if verification != nil {
+		countries = append(countries, verification.Data.DocIssuingCountry, verification.Data.DocNationality)
+	}
+	for _, country := range normalizeCountries(countries) {
+		if slices.Contains(r.denied, country) || (len(r.allowed) > 0 && !slices.Contains(r.allowed, country)) {
+			return errors.NewCountryRestrictedError(fmt.Sprintf("verification is not available for country %s", country), nil)
+		}
+	}
+	return nil
+}
+
+func normalizeCountries(countries []string) []string {
+	normalized := make([]string, 0, len(countries))
+	for _, country := range countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if country != "" {
+			normalized = append(normalized, country)
+		}
+	}
+	return normalized
+}
@@ -18,6 +18,16 @@ const (
 	ErrorTypeInternal             ErrorType = "INTERNAL_ERROR"
 	ErrorTypeExternal             ErrorType = "EXTERNAL_SERVICE_ERROR"
 	ErrorTypeNotSufficientBalance ErrorType = "NOT_SUFFICIENT_BALANCE"
+	ErrorTypeDenied               ErrorType = "DENIED"
+	ErrorTypeTooManyAttempts      ErrorType = "TOO_MANY_ATTEMPTS"
+	ErrorTypeTwinNotFound         ErrorType = "TWIN_NOT_FOUND"
+	ErrorTypeCountryRestricted    ErrorType = "COUNTRY_RESTRICTED"
+	ErrorTypeRateLimited          ErrorType = "RATE_LIMITED"
+	// External service error types, the external service rejected the request of the service
+	ErrorTypeExternalAuth          ErrorType = "EXTERNAL_SERVICE_AUTH_FAILED"
+	ErrorTypeExternalBadRequest    ErrorType = "EXTERNAL_SERVICE_BAD_REQUEST"
+	ErrorTypeExternalQuotaExceeded ErrorType = "EXTERNAL_SERVICE_QUOTA_EXCEEDED"
+	ErrorTypeExternalRateLimited   ErrorType = "EXTERNAL_SERVICE_RATE_LIMITED"
 )
 
 // ServiceError represents a service-level error
@@ -25,6 +35,8 @@ type ServiceError struct {
 	Type ErrorType
 	Msg  string
 	Err  error
+	// Details is optional structured information returned to the client next to the error message
+	Details map[string]any
 }
 
 func (e *ServiceError) Error() string {
@@ -34,6 +46,12 @@ func (e *ServiceError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Type, e.Msg)
 }
 
+// WithDetails attaches structured details to the error
+func (e *ServiceError) WithDetails(details map[string]any) *ServiceError {
+	e.Details = details
+	return e
+}
+
 // Error constructors
 func NewValidationError(msg string, err error) *ServiceError {
 	return &ServiceError{
@@ -90,3 +108,75 @@ func NewNotSufficientBalanceError(msg string, err error) *ServiceError {
 		Err:  err,
 	}
 }
+
+func NewDeniedError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeDenied,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewTooManyAttemptsError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeTooManyAttempts,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewTwinNotFoundError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeTwinNotFound,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewCountryRestrictedError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeCountryRestricted,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewRateLimitedError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeRateLimited,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewExternalAuthError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeExternalAuth,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewExternalBadRequestError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeExternalBadRequest,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewExternalQuotaExceededError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeExternalQuotaExceeded,
+		Msg:  msg,
+		Err:  err,
+	}
+}
+
+func NewExternalRateLimitedError(msg string, err error) *ServiceError {
+	return &ServiceError{
+		Type: ErrorTypeExternalRateLimited,
+		Msg:  msg,
+		Err:  err,
+	}
+}
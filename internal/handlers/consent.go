@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/claims"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/responses"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/services"
+)
+
+// CreateConsentGrantRequest is the request body to grant a partner access to verification data fields and claims, the statement is signed by the client
+type CreateConsentGrantRequest struct {
+	// Statement is the hex-encoded message `{api-domain}:{timestamp}:consent:{partnerId}:{fields}`, with comma-separated verification data fields and claims
+	Statement string `json:"statement"`
+	// Signature of the statement by the authenticated client
+	Signature       string `json:"signature"`
+	SignatureScheme string `json:"signatureScheme,omitempty"`
+	// Duration of the grant in minutes
+	Duration uint `json:"duration"`
+}
+
+// @Summary		Create Consent Grant
+// @Description	Grants a partner service access to a subset of the verification data fields and claims of the client, for a limited time. The client signs the consent statement.
+// @Tags			Consent
+// @Accept			json
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Param			request		body		handlers.CreateConsentGrantRequest	true	"Consent statement signed by the client"
+// @Success		201			{object}	object{result=responses.ConsentGrantResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		404			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/consents [post]
+func (h *Handler) CreateConsentGrant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request CreateConsentGrantRequest
+		if err := json.Unmarshal(c.Body(), &request); err != nil {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		}
+		if request.Statement == "" || request.Signature == "" {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("statement and signature are required"))
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		grant, err := h.network(c).Consent.CreateGrant(ctx, authenticatedClientID(c), services.ConsentRequest{
+			Statement:       request.Statement,
+			Signature:       request.Signature,
+			SignatureScheme: request.SignatureScheme,
+			Duration:        time.Duration(request.Duration) * time.Minute,
+		})
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusCreated, responses.NewConsentGrantResponse(grant))
+	}
+}
+
+// @Summary		List Consent Grants
+// @Description	Lists the consent grants of the client, including the expired and revoked ones
+// @Tags			Consent
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Success		200			{object}	object{result=[]responses.ConsentGrantResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/consents [get]
+func (h *Handler) ListConsentGrants() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		grants, err := h.network(c).Consent.ListGrants(ctx, authenticatedClientID(c))
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, responses.NewConsentGrantsResponse(grants))
+	}
+}
+
+// @Summary		Revoke Consent Grant
+// @Description	Revokes a consent grant of the client
+// @Tags			Consent
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Param			id			path		string	true	"Consent grant ID"
+// @Success		200
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		404			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/consents/{id} [delete]
+func (h *Handler) RevokeConsentGrant() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if err := h.network(c).Consent.RevokeGrant(ctx, authenticatedClientID(c), c.Params("id")); err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, nil)
+	}
+}
+
+// @Summary		List Consent Accesses
+// @Description	Lists the accesses of the partner to the verification data of the client under a consent grant
+// @Tags			Consent
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Param			id			path		string	true	"Consent grant ID"
+// @Success		200			{object}	object{result=[]responses.ConsentAccessResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		404			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/consents/{id}/accesses [get]
+func (h *Handler) ListConsentAccesses() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		accesses, err := h.network(c).Consent.ListAccesses(ctx, authenticatedClientID(c), c.Params("id"))
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, responses.NewConsentAccessesResponse(accesses))
+	}
+}
+
+// @Summary		Get Consented Verification Data
+// @Description	Returns the verification data fields and the claims a client granted the partner access to. Every access is logged.
+// @Tags			Consent
+// @Produce		json
+// @Param			X-API-Key	header		string	true	"Partner API key"
+// @Param			client_id	query		string	true	"TFChain SS58Address"	minlength(48)	maxlength(48)
+// @Success		200			{object}	object{result=responses.VerificationDataResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		403			{object}	object{error=string}
+// @Failure		404			{object}	object{error=string}
+// @Failure		429			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/partner/data [get]
+func (h *Handler) GetConsentedVerificationData() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		partnerKey, ok := c.Locals(PARTNER_KEY_LOCAL_KEY).(*models.PartnerAPIKey)
+		if !ok {
+			return responses.RespondWithError(c, fiber.StatusUnauthorized, fmt.Errorf("missing API key"))
+		}
+		clientID, err := address.Normalize(c.Query("client_id"), h.network(c).Challenge.AllowedSS58Prefixes)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		verification, grant, err := h.network(c).Consent.GetGrantedVerificationData(ctx, partnerKey, clientID)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		scoped, err := responses.FilterFields(responses.NewVerificationDataResponse(verification), grant.Fields)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		// only the granted claims are derived, the other granted names are raw fields
+		for claim, value := range claims.Derive(verification, grant.Fields, time.Now()) {
+			scoped[claim] = value
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, scoped)
+	}
+}
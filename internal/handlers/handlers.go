@@ -14,13 +14,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/readpref"
 
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/build"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/claims"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/idenfy"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
@@ -28,10 +31,27 @@ import (
 	"github.com/threefoldtech/tf-kyc-verifier/internal/services"
 )
 
+// CLIENT_ID_LOCAL_KEY is the request locals key holding the client ID authenticated by the auth middleware
+const CLIENT_ID_LOCAL_KEY = "clientID"
+
+// PARTNER_KEY_LOCAL_KEY is the request locals key holding the partner API key authenticated by the partner middleware
+const PARTNER_KEY_LOCAL_KEY = "partnerKey"
+
+// NETWORK_LOCAL_KEY is the request locals key holding the network selected by the network middleware
+const NETWORK_LOCAL_KEY = "network"
+
+// DependencyCheck returns an error if a dependency of the service, such as a database, is unavailable
+type DependencyCheck func(ctx context.Context) error
+
+// IdenfyStats returns the request counters and circuit breaker state of the iDenfy client
+type IdenfyStats func() idenfy.Stats
+
 type Handler struct {
-	kycService *services.KYCService
-	config     *config.Config
-	logger     *slog.Logger
+	networks         *services.Networks
+	challengeService *services.ChallengeService
+	partnerService   *services.PartnerService
+	config           *config.Config
+	logger           *slog.Logger
 }
 
 //	@title			TFGrid KYC API
@@ -43,8 +63,66 @@ type Handler struct {
 // @contact.url		https://threefold.io
 // @contact.email	info@threefold.io
 // @BasePath		/
-func NewHandler(kycService *services.KYCService, config *config.Config, logger *slog.Logger) *Handler {
-	return &Handler{kycService: kycService, config: config, logger: logger}
+func NewHandler(networks *services.Networks, challengeService *services.ChallengeService, partnerService *services.PartnerService, config *config.Config, logger *slog.Logger) *Handler {
+	return &Handler{networks: networks, challengeService: challengeService, partnerService: partnerService, config: config, logger: logger}
+}
+
+// RequestNetwork returns the network selected by the network middleware, nil if the route has none
+func RequestNetwork(c *fiber.Ctx) *services.Network {
+	network, _ := c.Locals(NETWORK_LOCAL_KEY).(*services.Network)
+	return network
+}
+
+// network returns the network serving the request
+func (h *Handler) network(c *fiber.Ctx) *services.Network {
+	if network := RequestNetwork(c); network != nil {
+		return network
+	}
+	return h.networks.Default
+}
+
+// @Summary		Get Challenge Nonce
+// @Description	Issues a single-use nonce to sign as part of a `{api-domain}:{timestamp}:{nonce}` challenge
+// @Tags			Auth
+// @Produce		json
+// @Success		201	{object}	object{result=responses.ChallengeNonceResponse}
+// @Failure		429	{object}	object{error=string}
+// @Failure		500	{object}	object{error=string}
+// @Router			/api/v1/challenge/nonce [post]
+func (h *Handler) IssueChallengeNonce() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		nonce, err := h.challengeService.IssueNonce(ctx)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		response := responses.NewChallengeNonceResponse(nonce, h.network(c).Challenge.Domain)
+		return responses.RespondWithData(c, fiber.StatusCreated, response)
+	}
+}
+
+// @Summary		Login
+// @Description	Verifies a signed challenge and returns a short-lived session token bound to the client, to send as `Authorization: Bearer {token}` instead of a signature
+// @Tags			Auth
+// @Produce		json
+// @Param			X-Client-ID	header		string	true	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge	header		string	true	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature	header		string	true	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Success		201			{object}		object{result=responses.SessionTokenResponse}
+// @Failure		400			{object}		object{error=string}
+// @Failure		401			{object}		object{error=string}
+// @Failure		500			{object}		object{error=string}
+// @Router			/api/v1/auth/login [post]
+func (h *Handler) Login() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, err := h.network(c).Sessions.Issue(authenticatedClientID(c))
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusCreated, responses.NewSessionTokenResponse(token))
+	}
 }
 
 // @Summary		Get or Generate iDenfy Verification Token
@@ -52,24 +130,36 @@ func NewHandler(kycService *services.KYCService, config *config.Config, logger *
 // @Tags			Token
 // @Accept			json
 // @Produce		json
-// @Param			X-Client-ID	header		string	true	"TFChain SS58Address"								minlength(48)	maxlength(48)
-// @Param			X-Challenge	header		string	true	"hex-encoded message `{api-domain}:{timestamp}`"
-// @Param			X-Signature	header		string	true	"hex-encoded sr25519|ed25519 signature"				minlength(128)	maxlength(128)
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
 // @Success		200			{object}		object{result=responses.TokenResponse} "Existing token retrieved"
 // @Success		201			{object}		object{result=responses.TokenResponse} "New token created"
 // @Failure		400			{object}		object{error=string}
 // @Failure		401			{object}		object{error=string}
 // @Failure		402			{object}		object{error=string}
+// @Failure		403			{object}		object{error=string}
 // @Failure		409			{object}		object{error=string}
+// @Failure		412			{object}		object{error=string}
+// @Failure		422			{object}		object{error=string}
+// @Failure		429			{object}		object{error=string}
+// @Failure		451			{object}		object{error=string}
 // @Failure		500			{object}		object{error=string}
+// @Failure		502			{object}		object{error=string}
 // @Failure		503			{object}		object{error=string}
 // @Router			/api/v1/token [post]
 func (h *Handler) GetOrCreateVerificationToken() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		clientID := c.Get("X-Client-ID")
+		clientID := authenticatedClientID(c)
+		var country string
+		if h.config.Eligibility.CountryHeader != "" {
+			country = c.Get(h.config.Eligibility.CountryHeader)
+		}
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
-		token, isNewToken, err := h.kycService.GetOrCreateVerificationToken(ctx, clientID)
+		token, isNewToken, err := h.network(c).KYC.GetOrCreateVerificationToken(ctx, clientID, country)
 		if err != nil {
 			return HandleError(c, err)
 		}
@@ -86,9 +176,11 @@ func (h *Handler) GetOrCreateVerificationToken() fiber.Handler {
 // @Tags			Verification
 // @Accept			json
 // @Produce		json
-// @Param			X-Client-ID	header		string	true	"TFChain SS58Address"								minlength(48)	maxlength(48)
-// @Param			X-Challenge	header		string	true	"hex-encoded message `{api-domain}:{timestamp}`"
-// @Param			X-Signature	header		string	true	"hex-encoded sr25519|ed25519 signature"				minlength(128)	maxlength(128)
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
 // @Success		200			{object}		object{result=responses.VerificationDataResponse}
 // @Failure		400			{object}		object{error=string}
 // @Failure		401			{object}		object{error=string}
@@ -97,10 +189,10 @@ func (h *Handler) GetOrCreateVerificationToken() fiber.Handler {
 // @Router			/api/v1/data [get]
 func (h *Handler) GetVerificationData() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		clientID := c.Get("X-Client-ID")
+		clientID := authenticatedClientID(c)
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
-		verification, err := h.kycService.GetVerificationData(ctx, clientID)
+		verification, err := h.network(c).KYC.GetVerificationData(ctx, clientID)
 		if err != nil {
 			return HandleError(c, err)
 		}
@@ -112,16 +204,53 @@ func (h *Handler) GetVerificationData() fiber.Handler {
 	}
 }
 
+// @Summary		Get Verification Claims
+// @Description	Returns facts derived from the verification of a client, such as whether the client is an adult, without the raw document data. Only the requested claims are returned, a claim is null if it can not be derived.
+// @Tags			Verification
+// @Accept			json
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Param			claims		query		string	false	"comma-separated claims isAdult|nationality|issuingCountry|documentType|documentValid, all if not set"
+// @Success		200			{object}		object{result=object}
+// @Failure		400			{object}		object{error=string}
+// @Failure		401			{object}		object{error=string}
+// @Failure		403			{object}		object{error=string}
+// @Failure		404			{object}		object{error=string}
+// @Failure		500			{object}		object{error=string}
+// @Router			/api/v1/claims [get]
+func (h *Handler) GetVerificationClaims() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requested, err := claims.Parse(c.Query("claims"))
+		if err != nil {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, err)
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		derived, err := h.network(c).KYC.GetVerificationClaims(ctx, authenticatedClientID(c), requested)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, derived)
+	}
+}
+
 // @Summary		Get Verification Status
 // @Description	Returns the verification status for a client
 // @Tags			Verification
 // @Accept			json
 // @Produce		json
+// @Param			X-API-Key	header		string	false	"partner API key, required unless anonymous status queries are allowed"
 // @Param			client_id	query		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
 // @Param			twin_id		query		string	false	"Twin ID"											minlength(1)
 // @Success		200			{object}		object{result=responses.VerificationStatusResponse}
 // @Failure		400			{object}		object{error=string}
 // @Failure		404			{object}		object{error=string}
+// @Failure		401			{object}		object{error=string}
+// @Failure		429			{object}		object{error=string}
 // @Failure		500			{object}		object{error=string}
 // @Failure		503			{object}		object{error=string}
 // @Router			/api/v1/status [get]
@@ -136,12 +265,18 @@ func (h *Handler) GetVerificationStatus() fiber.Handler {
 		}
 		var verification *models.VerificationOutcome
 		var err error
+		if clientID != "" {
+			clientID, err = address.Normalize(clientID, h.network(c).Challenge.AllowedSS58Prefixes)
+			if err != nil {
+				return HandleError(c, err)
+			}
+		}
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
 		if clientID != "" {
-			verification, err = h.kycService.GetVerificationStatus(ctx, clientID)
+			verification, err = h.network(c).KYC.GetVerificationStatus(ctx, clientID)
 		} else {
-			verification, err = h.kycService.GetVerificationStatusByTwinID(ctx, twinID)
+			verification, err = h.network(c).KYC.GetVerificationStatusByTwinID(ctx, twinID)
 		}
 		if err != nil {
 			h.logger.Error("Failed to get verification status", "clientID", clientID, "twinID", twinID, "error", err)
@@ -152,6 +287,14 @@ func (h *Handler) GetVerificationStatus() fiber.Handler {
 			return responses.RespondWithError(c, fiber.StatusNotFound, fmt.Errorf("verification not found"))
 		}
 		response := responses.NewVerificationStatusResponse(verification)
+		// partners only get the fields their API key is scoped to
+		if partnerKey, ok := c.Locals(PARTNER_KEY_LOCAL_KEY).(*models.PartnerAPIKey); ok {
+			scoped, err := responses.FilterFields(response, partnerKey.AllowedFields)
+			if err != nil {
+				return HandleError(c, err)
+			}
+			return responses.RespondWithData(c, fiber.StatusOK, scoped)
+		}
 		return responses.RespondWithData(c, fiber.StatusOK, response)
 	}
 }
@@ -184,7 +327,8 @@ func (h *Handler) ProcessVerificationResult() fiber.Handler {
 		h.logger.Debug("Verification update after decoding", "result", result)
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
-		err = h.kycService.ProcessVerificationResult(ctx, body, sigHeader, result)
+		// the clientId suffix tells the network the verification was created by
+		err = h.networks.ForClientID(result.ClientID).KYC.ProcessVerificationResult(ctx, body, sigHeader, result)
 		if err != nil {
 			return HandleError(c, err)
 		}
@@ -207,35 +351,131 @@ func (h *Handler) ProcessDocExpirationNotification() fiber.Handler {
 	}
 }
 
+// @Summary		Get Verification Attempts
+// @Description	Returns the lifetime verification attempts of a client
+// @Tags			Admin
+// @Produce		json
+// @Param			X-Admin-Key	header		string	true	"Admin API key"
+// @Param			client_id	path		string	true	"TFChain SS58Address"	minlength(48)	maxlength(48)
+// @Success		200			{object}	object{result=responses.VerificationAttemptsResponse}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/admin/attempts/{client_id} [get]
+func (h *Handler) GetVerificationAttempts() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clientID, err := address.Normalize(c.Params("client_id"), h.network(c).Challenge.AllowedSS58Prefixes)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		attempts, err := h.network(c).KYC.GetVerificationAttempts(ctx, clientID)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		response := responses.NewVerificationAttemptsResponse(clientID, attempts, h.config.Eligibility.MaxAttempts())
+		return responses.RespondWithData(c, fiber.StatusOK, response)
+	}
+}
+
+// @Summary		Reset Verification Attempts
+// @Description	Resets the lifetime verification attempts counter and the denial cooldown of a client
+// @Tags			Admin
+// @Produce		json
+// @Param			X-Admin-Key	header		string	true	"Admin API key"
+// @Param			client_id	path		string	true	"TFChain SS58Address"	minlength(48)	maxlength(48)
+// @Success		200			{object}	object{result=responses.VerificationAttemptsResponse}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/admin/attempts/{client_id}/reset [post]
+func (h *Handler) ResetVerificationAttempts() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		clientID, err := address.Normalize(c.Params("client_id"), h.network(c).Challenge.AllowedSS58Prefixes)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		err = h.network(c).KYC.ResetVerificationAttempts(ctx, clientID)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		attempts, err := h.network(c).KYC.GetVerificationAttempts(ctx, clientID)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		response := responses.NewVerificationAttemptsResponse(clientID, attempts, h.config.Eligibility.MaxAttempts())
+		return responses.RespondWithData(c, fiber.StatusOK, response)
+	}
+}
+
 // @Summary		Health Check
-// @Description	Returns the health status of the service
+// @Description	Returns the health status of the service, it is degraded while a dependency is unavailable or the iDenfy circuit breaker is not closed
 // @Tags			Health
 // @Success		200	{object}	object{result=responses.HealthResponse}
 // @Router			/api/v1/health [get]
-func (h *Handler) HealthCheck(dbClient *mongo.Client) fiber.Handler {
+func (h *Handler) HealthCheck(idenfyStats IdenfyStats, checks ...DependencyCheck) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
 		defer cancel()
-		err := dbClient.Ping(ctx, readpref.Primary())
-		if err != nil {
-			// status degraded
-			health := responses.HealthResponse{
-				Status:    responses.HealthStatusDegraded,
-				Timestamp: time.Now().UTC().Format(time.RFC3339),
-				Errors:    []string{err.Error()},
+		errs := []string{}
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				errs = append(errs, err.Error())
 			}
-			return responses.RespondWithData(c, fiber.StatusOK, health)
+		}
+		breakerState := idenfyStats().BreakerState
+		if breakerState != idenfy.BreakerClosed {
+			errs = append(errs, fmt.Sprintf("iDenfy circuit breaker is %s", breakerState))
+		}
+		status := responses.HealthStatusHealthy
+		if len(errs) > 0 {
+			status = responses.HealthStatusDegraded
 		}
 		health := responses.HealthResponse{
-			Status:    responses.HealthStatusHealthy,
+			Status:    status,
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
-			Errors:    []string{},
+			Errors:    errs,
+			Idenfy: responses.IdenfyHealth{
+				BreakerState: string(breakerState),
+			},
 		}
 
 		return responses.RespondWithData(c, fiber.StatusOK, health)
 	}
 }
 
+// @Summary		Metrics
+// @Description	Returns the iDenfy client request counters and circuit breaker state in the Prometheus text format
+// @Tags			Health
+// @Produce		plain
+// @Success		200	{string}	string
+// @Router			/metrics [get]
+func (h *Handler) Metrics(idenfyStats IdenfyStats) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		stats := idenfyStats()
+		var b strings.Builder
+		writeCounter := func(name, help string, value uint64) {
+			fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", name, help, name, name, value)
+		}
+		writeCounter("tf_kyc_idenfy_requests_total", "Requests sent to iDenfy, retries included.", stats.Requests)
+		writeCounter("tf_kyc_idenfy_retries_total", "Retried iDenfy requests.", stats.Retries)
+		writeCounter("tf_kyc_idenfy_failures_total", "iDenfy requests failed with a network error or a 5xx or 429 status.", stats.Failures)
+		writeCounter("tf_kyc_idenfy_rejected_total", "iDenfy requests failed fast by the open circuit breaker.", stats.Rejected)
+		writeCounter("tf_kyc_idenfy_breaker_openings_total", "Openings of the iDenfy circuit breaker.", stats.BreakerOpenings)
+		b.WriteString("# HELP tf_kyc_idenfy_breaker_state State of the iDenfy circuit breaker, 1 for the current state.\n# TYPE tf_kyc_idenfy_breaker_state gauge\n")
+		for _, state := range []idenfy.BreakerState{idenfy.BreakerClosed, idenfy.BreakerOpen, idenfy.BreakerHalfOpen} {
+			value := 0
+			if state == stats.BreakerState {
+				value = 1
+			}
+			fmt.Fprintf(&b, "tf_kyc_idenfy_breaker_state{state=%q} %d\n", state, value)
+		}
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return c.SendString(b.String())
+	}
+}
+
 // @Summary		Get Service Configs
 // @Description	Returns the service configs
 // @Tags			Misc
@@ -259,6 +499,107 @@ func (h *Handler) GetServiceVersion() fiber.Handler {
 	}
 }
 
+// CreatePartnerAPIKeyRequest is the request body to create a partner API key
+type CreatePartnerAPIKeyRequest struct {
+	Name string `json:"name"`
+	// RateLimit is the number of requests allowed per minute, 0 means unlimited
+	RateLimit uint `json:"rateLimit"`
+	// AllowedFields are the verification status fields returned to the partner, all if empty
+	AllowedFields []string `json:"allowedFields"`
+}
+
+// @Summary		Create Partner API Key
+// @Description	Creates an API key for a partner service to query verification statuses. The key is only returned once.
+// @Tags			Admin
+// @Accept			json
+// @Produce		json
+// @Param			X-Admin-Key	header		string								true	"Admin API key"
+// @Param			request		body		handlers.CreatePartnerAPIKeyRequest	true	"Partner API key"
+// @Success		201			{object}	object{result=responses.CreatedPartnerAPIKeyResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/admin/partner-keys [post]
+func (h *Handler) CreatePartnerAPIKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request CreatePartnerAPIKeyRequest
+		if err := json.Unmarshal(c.Body(), &request); err != nil {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		}
+		if request.Name == "" {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("name is required"))
+		}
+		if len(request.AllowedFields) == 0 {
+			request.AllowedFields = responses.VerificationStatusFields
+		}
+		for _, field := range request.AllowedFields {
+			if !slices.Contains(responses.VerificationStatusFields, field) {
+				return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("unknown field %q, allowed fields are %v", field, responses.VerificationStatusFields))
+			}
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		partnerKey, key, err := h.partnerService.CreateKey(ctx, request.Name, request.RateLimit, request.AllowedFields)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		response := responses.CreatedPartnerAPIKeyResponse{PartnerAPIKeyResponse: *responses.NewPartnerAPIKeyResponse(partnerKey), Key: key}
+		return responses.RespondWithData(c, fiber.StatusCreated, response)
+	}
+}
+
+// @Summary		List Partner API Keys
+// @Description	Lists the partner API keys, including the revoked ones
+// @Tags			Admin
+// @Produce		json
+// @Param			X-Admin-Key	header		string	true	"Admin API key"
+// @Success		200			{object}	object{result=[]responses.PartnerAPIKeyResponse}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/admin/partner-keys [get]
+func (h *Handler) ListPartnerAPIKeys() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		keys, err := h.partnerService.ListKeys(ctx)
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, responses.NewPartnerAPIKeysResponse(keys))
+	}
+}
+
+// @Summary		Revoke Partner API Key
+// @Description	Revokes a partner API key
+// @Tags			Admin
+// @Produce		json
+// @Param			X-Admin-Key	header		string	true	"Admin API key"
+// @Param			id			path		string	true	"Partner API key ID"
+// @Success		200
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		404			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/admin/partner-keys/{id} [delete]
+func (h *Handler) RevokePartnerAPIKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if err := h.partnerService.RevokeKey(ctx, c.Params("id")); err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, nil)
+	}
+}
+
+// authenticatedClientID returns the client ID authenticated by the auth middleware
+func authenticatedClientID(c *fiber.Ctx) string {
+	if clientID, ok := c.Locals(CLIENT_ID_LOCAL_KEY).(string); ok {
+		return clientID
+	}
+	return c.Get("X-Client-ID")
+}
+
 func HandleError(c *fiber.Ctx, err error) error {
 	if serviceErr, ok := err.(*errors.ServiceError); ok {
 		return HandleServiceError(c, serviceErr)
@@ -268,6 +609,9 @@ func HandleError(c *fiber.Ctx, err error) error {
 
 func HandleServiceError(c *fiber.Ctx, err *errors.ServiceError) error {
 	statusCode := getStatusCode(err.Type)
+	if err.Details != nil {
+		return responses.RespondWithErrorDetails(c, statusCode, err, err.Details)
+	}
 	return responses.RespondWithError(c, statusCode, err)
 }
 
@@ -285,6 +629,25 @@ func getStatusCode(errorType errors.ErrorType) int {
 		return fiber.StatusServiceUnavailable
 	case errors.ErrorTypeNotSufficientBalance:
 		return fiber.StatusPaymentRequired
+	case errors.ErrorTypeDenied:
+		return fiber.StatusForbidden
+	case errors.ErrorTypeTooManyAttempts:
+		return fiber.StatusTooManyRequests
+	case errors.ErrorTypeTwinNotFound:
+		return fiber.StatusPreconditionFailed
+	case errors.ErrorTypeCountryRestricted:
+		return fiber.StatusUnavailableForLegalReasons
+	case errors.ErrorTypeRateLimited:
+		return fiber.StatusTooManyRequests
+	// requests rejected by an external service, the credentials and quota are on the operator side
+	case errors.ErrorTypeExternalAuth:
+		return fiber.StatusBadGateway
+	case errors.ErrorTypeExternalBadRequest:
+		return fiber.StatusUnprocessableEntity
+	case errors.ErrorTypeExternalQuotaExceeded:
+		return fiber.StatusServiceUnavailable
+	case errors.ErrorTypeExternalRateLimited:
+		return fiber.StatusTooManyRequests
 	default:
 		return fiber.StatusInternalServerError
 	}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/responses"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/services"
+)
+
+// CreateAccountLinkRequest is the request body to link another account, the statement is signed by both accounts
+type CreateAccountLinkRequest struct {
+	// Statement is the hex-encoded message `{api-domain}:{timestamp}:link:{primaryClientId}:{linkedClientId}`
+	Statement string `json:"statement"`
+	// Signature of the statement by the authenticated primary account
+	Signature       string `json:"signature"`
+	SignatureScheme string `json:"signatureScheme,omitempty"`
+	// LinkedSignature of the statement by the linked account
+	LinkedSignature       string `json:"linkedSignature"`
+	LinkedSignatureScheme string `json:"linkedSignatureScheme,omitempty"`
+}
+
+// @Summary		Link Account
+// @Description	Links another account controlled by the verified client, the linked account inherits its verification status. Both accounts sign the link statement.
+// @Tags			Links
+// @Accept			json
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Param			request		body		handlers.CreateAccountLinkRequest	true	"Link statement signed by both accounts"
+// @Success		201			{object}	object{result=responses.AccountLinkResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		403			{object}	object{error=string}
+// @Failure		409			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/links [post]
+func (h *Handler) CreateAccountLink() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var request CreateAccountLinkRequest
+		if err := json.Unmarshal(c.Body(), &request); err != nil {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		}
+		if request.Statement == "" || request.Signature == "" || request.LinkedSignature == "" {
+			return responses.RespondWithError(c, fiber.StatusBadRequest, fmt.Errorf("statement, signature and linkedSignature are required"))
+		}
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		link, err := h.network(c).Links.CreateLink(ctx, authenticatedClientID(c), services.LinkRequest{
+			Statement:              request.Statement,
+			PrimarySignature:       request.Signature,
+			PrimarySignatureScheme: request.SignatureScheme,
+			LinkedSignature:        request.LinkedSignature,
+			LinkedSignatureScheme:  request.LinkedSignatureScheme,
+		})
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusCreated, responses.NewAccountLinkResponse(link))
+	}
+}
+
+// @Summary		List Account Links
+// @Description	Lists the links of the client, as primary or linked account, including the revoked ones
+// @Tags			Links
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Success		200			{object}	object{result=[]responses.AccountLinkResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/links [get]
+func (h *Handler) ListAccountLinks() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		links, err := h.network(c).Links.ListLinks(ctx, authenticatedClientID(c))
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, responses.NewAccountLinksResponse(links))
+	}
+}
+
+// @Summary		Revoke Account Link
+// @Description	Revokes a link of the client, either the primary or the linked account can revoke it
+// @Tags			Links
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Param			id			path		string	true	"Account link ID"
+// @Success		200
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		404			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/links/{id} [delete]
+func (h *Handler) RevokeAccountLink() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		if err := h.network(c).Links.RevokeLink(ctx, authenticatedClientID(c), c.Params("id")); err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, nil)
+	}
+}
+
+// @Summary		List Account Link Events
+// @Description	Lists the audit trail of the creations and revocations of the links of the client
+// @Tags			Links
+// @Produce		json
+// @Param			Authorization	header		string	false	"session token from /api/v1/auth/login, as `Bearer {token}`, instead of a signed challenge"
+// @Param			X-Client-ID		header		string	false	"TFChain SS58Address"								minlength(48)	maxlength(48)
+// @Param			X-Challenge		header		string	false	"hex-encoded message `{api-domain}:{timestamp}:{nonce}`"
+// @Param			X-Signature		header		string	false	"hex-encoded sr25519|ed25519|ecdsa signature"		minlength(128)	maxlength(130)
+// @Param			X-Signature-Scheme	header		string	false	"signature scheme sr25519|ed25519|ecdsa, detected if not set"
+// @Success		200			{object}	object{result=[]responses.AccountLinkEventResponse}
+// @Failure		400			{object}	object{error=string}
+// @Failure		401			{object}	object{error=string}
+// @Failure		500			{object}	object{error=string}
+// @Router			/api/v1/links/events [get]
+func (h *Handler) ListAccountLinkEvents() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.Context(), 5*time.Second)
+		defer cancel()
+		events, err := h.network(c).Links.ListEvents(ctx, authenticatedClientID(c))
+		if err != nil {
+			return HandleError(c, err)
+		}
+		return responses.RespondWithData(c, fiber.StatusOK, responses.NewAccountLinkEventsResponse(events))
+	}
+}
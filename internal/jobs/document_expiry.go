@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ExpiringDocumentsFlagger flags the verifications whose document expires before the given time
+type ExpiringDocumentsFlagger interface {
+	FlagExpiringDocuments(ctx context.Context, until time.Time) ([]string, error)
+}
+
+// DocumentExpiryJob flags clients whose document expires within the warning period, so they can be prompted to re-verify
+type DocumentExpiryJob struct {
+	flagger     ExpiringDocumentsFlagger
+	warningDays uint
+	logger      *slog.Logger
+}
+
+func NewDocumentExpiryJob(flagger ExpiringDocumentsFlagger, warningDays uint, logger *slog.Logger) *DocumentExpiryJob {
+	return &DocumentExpiryJob{flagger: flagger, warningDays: warningDays, logger: logger}
+}
+
+func (j *DocumentExpiryJob) Name() string {
+	return "document-expiry"
+}
+
+func (j *DocumentExpiryJob) Run(ctx context.Context) error {
+	until := time.Now().UTC().AddDate(0, 0, int(j.warningDays))
+	clientIDs, err := j.flagger.FlagExpiringDocuments(ctx, until)
+	if err != nil {
+		return err
+	}
+	for _, clientID := range clientIDs {
+		j.logger.Info("Client document expires soon, re-verification required", "clientID", clientID, "until", until.Format(time.DateOnly))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ExpiredEntriesCleaner deletes the entries expired at the given time, for stores without a TTL index
+type ExpiredEntriesCleaner interface {
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ExpiryCleanupJob deletes expired entries, such as the tokens and rate limiter entries of the PostgreSQL backend
+type ExpiryCleanupJob struct {
+	name     string
+	cleaners []ExpiredEntriesCleaner
+	logger   *slog.Logger
+}
+
+func NewExpiryCleanupJob(name string, logger *slog.Logger, cleaners ...ExpiredEntriesCleaner) *ExpiryCleanupJob {
+	return &ExpiryCleanupJob{name: name, cleaners: cleaners, logger: logger}
+}
+
+func (j *ExpiryCleanupJob) Name() string {
+	return j.name
+}
+
+func (j *ExpiryCleanupJob) Run(ctx context.Context) error {
+	now := time.Now()
+	var deleted int64
+	for _, cleaner := range j.cleaners {
+		count, err := cleaner.DeleteExpired(ctx, now)
+		if err != nil {
+			return err
+		}
+		deleted += count
+	}
+	j.logger.Debug("Deleted expired entries", "job", j.name, "count", deleted)
+	return nil
+}
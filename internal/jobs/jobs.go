@@ -0,0 +1,88 @@
+/*
+Package jobs contains the background jobs for the application.
+This layer is responsible for running periodic tasks, such as flagging expiring documents, next to the HTTP server.
+Jobs must be safe to run concurrently on several service instances.
+*/
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Job is a periodic task
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type scheduledJob struct {
+	job      Job
+	interval time.Duration
+}
+
+type Scheduler struct {
+	jobs   []scheduledJob
+	logger *slog.Logger
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(logger *slog.Logger) *Scheduler {
+	return &Scheduler{logger: logger}
+}
+
+// Every schedules the job to run at the given interval, a zero interval disables the job
+func (s *Scheduler) Every(interval time.Duration, job Job) {
+	if interval <= 0 {
+		s.logger.Info("Job disabled", "job", job.Name())
+		return
+	}
+	s.jobs = append(s.jobs, scheduledJob{job: job, interval: interval})
+}
+
+// Start runs every scheduled job once, then at its interval, until Stop is called
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, scheduled := range s.jobs {
+		s.wg.Add(1)
+		go func(scheduled scheduledJob) {
+			defer s.wg.Done()
+			s.logger.Info("Starting job", "job", scheduled.job.Name(), "interval", scheduled.interval)
+			ticker := time.NewTicker(scheduled.interval)
+			defer ticker.Stop()
+			for {
+				s.run(ctx, scheduled)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(scheduled)
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, scheduled scheduledJob) {
+	start := time.Now()
+	// a run should never take longer than the interval, otherwise the next run is delayed
+	runCtx, cancel := context.WithTimeout(ctx, scheduled.interval)
+	defer cancel()
+	if err := scheduled.job.Run(runCtx); err != nil {
+		s.logger.Error("Job failed", "job", scheduled.job.Name(), "duration", time.Since(start), "error", err)
+		return
+	}
+	s.logger.Debug("Job completed", "job", scheduled.job.Name(), "duration", time.Since(start))
+}
+
+// Stop cancels the running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+}
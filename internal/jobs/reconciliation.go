@@ -0,0 +1,37 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// SessionReconciler processes the results of the verification sessions whose webhook never arrived
+type SessionReconciler interface {
+	ReconcileSessions(ctx context.Context, now time.Time) (int, error)
+}
+
+// ReconciliationJob polls iDenfy for the sessions without result after a timeout, such as when a webhook was lost
+type ReconciliationJob struct {
+	reconciler SessionReconciler
+	logger     *slog.Logger
+}
+
+func NewReconciliationJob(reconciler SessionReconciler, logger *slog.Logger) *ReconciliationJob {
+	return &ReconciliationJob{reconciler: reconciler, logger: logger}
+}
+
+func (j *ReconciliationJob) Name() string {
+	return "session-reconciliation"
+}
+
+func (j *ReconciliationJob) Run(ctx context.Context) error {
+	processed, err := j.reconciler.ReconcileSessions(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	if processed > 0 {
+		j.logger.Info("Reconciled verification sessions", "processed", processed)
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
 	"log/slog"
 	"strconv"
@@ -8,19 +10,62 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/handlers"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/responses"
-	"github.com/vedhavyas/go-subkey/v2"
-	"github.com/vedhavyas/go-subkey/v2/ed25519"
-	"github.com/vedhavyas/go-subkey/v2/sr25519"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/services"
 )
 
-// AuthMiddleware is a middleware that validates the authentication credentials
-func AuthMiddleware(config config.Challenge) fiber.Handler {
+// NonceStore accepts each server-issued challenge nonce only once
+type NonceStore interface {
+	ConsumeNonce(ctx context.Context, clientID string, nonce string) error
+}
+
+// SessionVerifier verifies a session token and returns the client ID it is bound to
+type SessionVerifier interface {
+	Verify(token string) (string, error)
+}
+
+// AuthMiddleware is a middleware that validates the authentication credentials, either a session token or a signed challenge.
+// Challenges carrying a nonce are accepted only once by the nonce store, a nil store disables this check.
+// A nil session verifier only accepts signed challenges.
+// The challenge domain and the session tokens are the ones of the request network, if the network middleware selected one.
+// The authenticated client ID is stored in the request locals under handlers.CLIENT_ID_LOCAL_KEY.
+func AuthMiddleware(defaultConfig config.Challenge, nonces NonceStore, defaultSessions SessionVerifier) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		config, sessions := defaultConfig, defaultSessions
+		if network := handlers.RequestNetwork(c); network != nil {
+			config = network.Challenge
+			if sessions != nil && network.Sessions != nil {
+				sessions = network.Sessions
+			}
+		}
 		clientID := c.Get("X-Client-ID")
+		if clientID != "" {
+			// the canonical client ID is used from here on, so the same key can't be registered under two address encodings
+			canonicalClientID, err := address.Normalize(clientID, config.AllowedSS58Prefixes)
+			if err != nil {
+				return handlers.HandleError(c, err)
+			}
+			clientID = canonicalClientID
+		}
+		if sessions != nil {
+			if token, ok := bearerToken(c.Get(fiber.HeaderAuthorization)); ok {
+				tokenClientID, err := sessions.Verify(token)
+				if err != nil {
+					return handlers.HandleError(c, err)
+				}
+				if clientID != "" && clientID != tokenClientID {
+					return responses.RespondWithError(c, fiber.StatusUnauthorized, fmt.Errorf("session token is bound to another client ID"))
+				}
+				c.Locals(handlers.CLIENT_ID_LOCAL_KEY, tokenClientID)
+				return c.Next()
+			}
+		}
+
 		signature := c.Get("X-Signature")
 		challenge := c.Get("X-Challenge")
 
@@ -29,7 +74,7 @@ func AuthMiddleware(config config.Challenge) fiber.Handler {
 		}
 
 		// Verify the clientID and signature here
-		err := ValidateChallenge(clientID, signature, challenge, config.Domain, config.Window)
+		nonce, err := ValidateChallenge(challenge, config)
 		if err != nil {
 			// cast error to service error and convert it to http status code
 			serviceError, ok := err.(*errors.ServiceError)
@@ -38,8 +83,12 @@ func AuthMiddleware(config config.Challenge) fiber.Handler {
 			}
 			return responses.RespondWithError(c, fiber.StatusBadRequest, err)
 		}
-		// Verify the signature
-		err = VerifySubstrateSignature(clientID, signature, challenge)
+		// Verify the signature, with the scheme declared by the client if any
+		schemes, err := SignatureSchemes(c.Get("X-Signature-Scheme"), config.AllowEcdsa)
+		if err != nil {
+			return handlers.HandleError(c, err)
+		}
+		err = VerifySubstrateSignature(clientID, signature, challenge, schemes...)
 		if err != nil {
 			serviceError, ok := err.(*errors.ServiceError)
 			if ok {
@@ -47,78 +96,162 @@ func AuthMiddleware(config config.Challenge) fiber.Handler {
 			}
 			return responses.RespondWithError(c, fiber.StatusUnauthorized, err)
 		}
+		// Consume the nonce only once the signature is verified, so that others can't burn it
+		if nonce != "" && nonces != nil {
+			err = nonces.ConsumeNonce(c.Context(), clientID, nonce)
+			if err != nil {
+				serviceError, ok := err.(*errors.ServiceError)
+				if ok {
+					return handlers.HandleServiceError(c, serviceError)
+				}
+				return responses.RespondWithError(c, fiber.StatusUnauthorized, err)
+			}
+		}
 
+		c.Locals(handlers.CLIENT_ID_LOCAL_KEY, clientID)
 		return c.Next()
 	}
 }
 
-func fromHex(hex string) ([]byte, bool) {
-	return subkey.DecodeHex(hex)
+// NetworkMiddleware selects the network of the request by the :network path parameter, or else by the given header.
+// Requests selecting none are served by the default network, unknown networks are rejected.
+// The network is stored in the request locals under handlers.NETWORK_LOCAL_KEY.
+func NetworkMiddleware(networks *services.Networks, header string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := c.Params("network")
+		if name == "" {
+			name = c.Get(header)
+		}
+		network := networks.Default
+		if name != "" {
+			var ok bool
+			network, ok = networks.Get(name)
+			if !ok {
+				return responses.RespondWithError(c, fiber.StatusNotFound, fmt.Errorf("unknown network %s", name))
+			}
+		}
+		c.Locals(handlers.NETWORK_LOCAL_KEY, network)
+		return c.Next()
+	}
 }
 
-func VerifySubstrateSignature(address, signature, challenge string) error {
-	challengeBytes, ok := fromHex(challenge)
-	if !ok {
-		return errors.NewValidationError("malformed challenge: failed to decode hex-encoded challenge", nil)
-	}
-	// hex to string
-	sig, ok := fromHex(signature)
-	if !ok {
-		return errors.NewValidationError("malformed signature: failed to decode hex-encoded signature", nil)
-	}
-	// Convert address to public key
-	_, pubkeyBytes, err := subkey.SS58Decode(address)
-	if err != nil {
-		return errors.NewValidationError("malformed address:failed to decode ss58 address", err)
-	}
+func bearerToken(authorization string) (string, bool) {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
 
-	// Create a new ed25519 public key
-	pubkeyEd25519, err := ed25519.Scheme{}.FromPublicKey(pubkeyBytes)
-	if err != nil {
-		return errors.NewValidationError("creating ed25519 public key", err)
-	}
+// PartnerAuthenticator authenticates a partner API key and enforces its rate limit
+type PartnerAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.PartnerAPIKey, error)
+}
 
-	if !pubkeyEd25519.Verify(challengeBytes, sig) {
-		// Create a new sr25519 public key
-		pubkeySr25519, err := sr25519.Scheme{}.FromPublicKey(pubkeyBytes)
-		if err != nil {
-			return errors.NewValidationError("creating sr25519 public key", err)
+// PartnerAPIKeyMiddleware is a middleware that authenticates partner services by their X-API-Key header.
+// Requests without a key are let through only if anonymous access is allowed.
+// The authenticated key is stored in the request locals under handlers.PARTNER_KEY_LOCAL_KEY.
+func PartnerAPIKeyMiddleware(allowAnonymous bool, partners PartnerAuthenticator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("X-API-Key")
+		if key == "" {
+			if allowAnonymous {
+				return c.Next()
+			}
+			return responses.RespondWithError(c, fiber.StatusUnauthorized, fmt.Errorf("missing API key"))
 		}
-		if !pubkeySr25519.Verify(challengeBytes, sig) {
-			return errors.NewAuthorizationError("bad signature: signature does not match", nil)
+		partnerKey, err := partners.Authenticate(c.Context(), key)
+		if err != nil {
+			return handlers.HandleError(c, err)
 		}
+		c.Locals(handlers.PARTNER_KEY_LOCAL_KEY, partnerKey)
+		return c.Next()
 	}
+}
 
-	return nil
+// AdminAuthMiddleware is a middleware that restricts access to operators holding the admin API key
+func AdminAuthMiddleware(apiKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get("X-Admin-Key")
+		if key == "" {
+			return responses.RespondWithError(c, fiber.StatusUnauthorized, fmt.Errorf("missing admin API key"))
+		}
+		if subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			return responses.RespondWithError(c, fiber.StatusUnauthorized, fmt.Errorf("invalid admin API key"))
+		}
+		return c.Next()
+	}
 }
 
-func ValidateChallenge(address, signature, challenge, expectedDomain string, challengeWindow int64) error {
+// ValidateChallenge validates a `domain:timestamp:nonce` challenge, or a legacy `domain:timestamp` one if allowed by the config.
+// The challenge may be wrapped in `<Bytes>...</Bytes>` by a Polkadot.js extension.
+// It returns the nonce of the challenge, empty for the legacy format.
+func ValidateChallenge(challenge string, config config.Challenge) (string, error) {
 	// Parse and validate the challenge
 	challengeBytes, ok := fromHex(challenge)
 	if !ok {
-		return errors.NewValidationError("malformed challenge: failed to decode hex-encoded challenge", nil)
+		return "", errors.NewValidationError("malformed challenge: failed to decode hex-encoded challenge", nil)
 	}
-	parts := strings.Split(string(challengeBytes), ":")
-	if len(parts) != 2 {
-		return errors.NewValidationError("malformed challenge: invalid challenge format", nil)
+	parts := strings.Split(string(unwrapBytes(challengeBytes)), ":")
+	var nonce string
+	switch len(parts) {
+	case 3:
+		nonce = parts[2]
+		if nonce == "" {
+			return "", errors.NewValidationError("malformed challenge: empty nonce", nil)
+		}
+	case 2:
+		if !config.AllowLegacyFormat {
+			return "", errors.NewValidationError("bad challenge: legacy challenge format is disabled, sign a `domain:timestamp:nonce` challenge with a nonce from /api/v1/challenge/nonce", nil)
+		}
+	default:
+		return "", errors.NewValidationError("malformed challenge: invalid challenge format", nil)
 	}
 
 	// Check the domain
-	if parts[0] != expectedDomain {
-		return errors.NewValidationError("bad challenge: unexpected domain", nil)
+	if parts[0] != config.Domain {
+		return "", errors.NewValidationError("bad challenge: unexpected domain", nil)
 	}
 
 	// Check the timestamp
 	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
 	if err != nil {
-		return errors.NewValidationError("bad challenge: invalid timestamp", nil)
+		return "", errors.NewValidationError("bad challenge: invalid timestamp", nil)
 	}
 
-	// Check if the timestamp is within an acceptable range (e.g., last 1 minutes)
-	if time.Now().Unix()-timestamp > challengeWindow {
-		return errors.NewValidationError("bad challenge: challenge expired", nil)
+	// Check the timestamp is within the window, allowing the configured clock skew in both directions
+	now := time.Now()
+	age := now.Unix() - timestamp
+	if age > config.Window+config.ClockSkew {
+		return "", errors.NewValidationError("bad challenge: challenge expired", nil).WithDetails(challengeTimeDetails(now, timestamp, config))
+	}
+	if -age > config.ClockSkew {
+		return "", errors.NewValidationError("bad challenge: challenge timestamp is in the future", nil).WithDetails(challengeTimeDetails(now, timestamp, config))
+	}
+	return nonce, nil
+}
+
+// challengeTimeDetails includes the server time in challenge errors, so clients with bad clocks can self-correct
+func challengeTimeDetails(now time.Time, timestamp int64, config config.Challenge) map[string]any {
+	return map[string]any{
+		"serverTime":         now.Unix(),
+		"challengeTimestamp": timestamp,
+		"window":             config.Window,
+		"clockSkew":          config.ClockSkew,
+	}
+}
+
+// REDACTED_HEADERS are the request headers holding credentials, their values are not logged
+var REDACTED_HEADERS = []string{fiber.HeaderAuthorization, "X-Admin-Key", "X-API-Key"}
+
+// redactHeaders replaces the values of the credential headers, the header names are case-insensitive
+func redactHeaders(headers map[string][]string) map[string][]string {
+	for name := range headers {
+		for _, redacted := range REDACTED_HEADERS {
+			if strings.EqualFold(name, redacted) {
+				headers[name] = []string{"[REDACTED]"}
+			}
+		}
 	}
-	return nil
+	return headers
 }
 
 func NewLoggingMiddleware(logger *slog.Logger) fiber.Handler {
@@ -129,7 +262,7 @@ func NewLoggingMiddleware(logger *slog.Logger) fiber.Handler {
 		ip := c.IP()
 
 		// Log request
-		logger.Info("Incoming request", slog.Any("method", method), slog.Any("path", path), slog.Any("queries", c.Queries()), slog.Any("ip", ip), slog.Any("user_agent", string(c.Request().Header.UserAgent())), slog.Any("headers", c.GetReqHeaders()))
+		logger.Info("Incoming request", slog.Any("method", method), slog.Any("path", path), slog.Any("queries", c.Queries()), slog.Any("ip", ip), slog.Any("user_agent", string(c.Request().Header.UserAgent())), slog.Any("headers", redactHeaders(c.GetReqHeaders())))
 
 		// Handle request
 		err := c.Next()
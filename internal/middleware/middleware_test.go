@@ -1,19 +1,29 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/handlers"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/services"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/session"
 	"github.com/vedhavyas/go-subkey/v2"
+	"github.com/vedhavyas/go-subkey/v2/ecdsa"
 	"github.com/vedhavyas/go-subkey/v2/ed25519"
 	"github.com/vedhavyas/go-subkey/v2/sr25519"
 )
@@ -22,8 +32,10 @@ func TestAuthMiddleware(t *testing.T) {
 	// Setup
 	app := fiber.New()
 	cfg := config.Challenge{
-		Window: 8,
-		Domain: "test.grid.tf",
+		Window:            8,
+		ClockSkew:         2,
+		Domain:            "test.grid.tf",
+		AllowLegacyFormat: true,
 	}
 
 	// Mock handler that should be called after middleware
@@ -32,7 +44,7 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 
 	// Apply middleware
-	app.Use(AuthMiddleware(cfg))
+	app.Use(AuthMiddleware(cfg, newFakeNonceStore(), nil))
 	app.Get("/test", successHandler)
 
 	// Generate keys
@@ -49,6 +61,7 @@ func TestAuthMiddleware(t *testing.T) {
 	invalidChallenge := createInvalidSignMessageInvalidFormat(cfg.Domain)
 	expiredChallenge := createInvalidSignMessageExpired(cfg.Domain)
 	wrongDomainChallenge := createInvalidSignMessageWrongDomain()
+	futureChallenge := createSignMessageAt(cfg.Domain, time.Now().Add(10*time.Minute))
 	validChallenge := createValidSignMessage(cfg.Domain)
 	sigSr, err := krSr25519.Sign([]byte(validChallenge))
 	if err != nil {
@@ -124,6 +137,14 @@ func TestAuthMiddleware(t *testing.T) {
 			expectedStatus: fiber.StatusBadRequest,
 			expectedError:  "challenge expired",
 		},
+		{
+			name:           "Future-dated challenge",
+			clientID:       clientIDSr,
+			signature:      sigSrHex,
+			challenge:      toHex(futureChallenge),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "challenge timestamp is in the future",
+		},
 		{
 			name:           "Invalid domain in challenge",
 			clientID:       clientIDSr,
@@ -187,6 +208,572 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestAuthMiddlewareNonce(t *testing.T) {
+	nonces := newFakeNonceStore("nonce-1", "nonce-2")
+	newApp := func(cfg config.Challenge) *fiber.App {
+		app := fiber.New()
+		app.Use(AuthMiddleware(cfg, nonces, nil))
+		app.Get("/test", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+		return app
+	}
+	cfg := config.Challenge{Window: 8, Domain: "test.grid.tf"}
+	app := newApp(cfg)
+	legacyApp := newApp(config.Challenge{Window: 8, Domain: "test.grid.tf", AllowLegacyFormat: true})
+
+	kr, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientID := kr.SS58Address(42)
+	sign := func(message string) string {
+		sig, err := kr.Sign([]byte(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(sig)
+	}
+	nonceChallenge := createValidSignMessageWithNonce(cfg.Domain, "nonce-1")
+	unknownNonceChallenge := createValidSignMessageWithNonce(cfg.Domain, "unknown")
+	emptyNonceChallenge := createValidSignMessageWithNonce(cfg.Domain, "")
+	legacyChallenge := createValidSignMessage(cfg.Domain)
+	secondNonceChallenge := createValidSignMessageWithNonce(cfg.Domain, "nonce-2")
+
+	tests := []struct {
+		name           string
+		app            *fiber.App
+		signature      string
+		challenge      string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "valid nonce challenge",
+			app:            app,
+			signature:      sign(nonceChallenge),
+			challenge:      toHex(nonceChallenge),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "replayed nonce challenge",
+			app:            app,
+			signature:      sign(nonceChallenge),
+			challenge:      toHex(nonceChallenge),
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "already used",
+		},
+		{
+			name:           "unknown nonce",
+			app:            app,
+			signature:      sign(unknownNonceChallenge),
+			challenge:      toHex(unknownNonceChallenge),
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "nonce is unknown",
+		},
+		{
+			name:           "empty nonce",
+			app:            app,
+			signature:      sign(emptyNonceChallenge),
+			challenge:      toHex(emptyNonceChallenge),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "empty nonce",
+		},
+		{
+			name:           "bad signature does not consume the nonce",
+			app:            app,
+			signature:      sign(nonceChallenge),
+			challenge:      toHex(secondNonceChallenge),
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "signature does not match",
+		},
+		{
+			name:           "nonce still valid after bad signature",
+			app:            app,
+			signature:      sign(secondNonceChallenge),
+			challenge:      toHex(secondNonceChallenge),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "legacy challenge disabled",
+			app:            app,
+			signature:      sign(legacyChallenge),
+			challenge:      toHex(legacyChallenge),
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "legacy challenge format is disabled",
+		},
+		{
+			name:           "legacy challenge allowed",
+			app:            legacyApp,
+			signature:      sign(legacyChallenge),
+			challenge:      toHex(legacyChallenge),
+			expectedStatus: fiber.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.app.Test(createTestRequest(clientID, tt.signature, tt.challenge))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedError != "" {
+				var errorResp struct {
+					Error string `json:"error"`
+				}
+				err = parseResponse(resp, &errorResp)
+				assert.NoError(t, err)
+				assert.Contains(t, errorResp.Error, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestValidateChallengeTimestamp(t *testing.T) {
+	cfg := config.Challenge{Window: 8, ClockSkew: 2, Domain: "test.grid.tf", AllowLegacyFormat: true}
+	now := time.Now()
+	tests := []struct {
+		name          string
+		timestamp     time.Time
+		expectedError string
+	}{
+		{name: "current timestamp", timestamp: now},
+		{name: "past timestamp within window", timestamp: now.Add(-7 * time.Second)},
+		{name: "past timestamp within window and skew", timestamp: now.Add(-9 * time.Second)},
+		{name: "past timestamp beyond window and skew", timestamp: now.Add(-12 * time.Second), expectedError: "challenge expired"},
+		{name: "future timestamp within skew", timestamp: now.Add(1 * time.Second)},
+		{name: "future timestamp beyond skew", timestamp: now.Add(4 * time.Second), expectedError: "in the future"},
+		{name: "far future timestamp", timestamp: now.Add(24 * time.Hour), expectedError: "in the future"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateChallenge(toHex(createSignMessageAt(cfg.Domain, tt.timestamp)), cfg)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var serviceError *errors.ServiceError
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Contains(t, serviceError.Msg, tt.expectedError)
+			assert.Equal(t, tt.timestamp.Unix(), serviceError.Details["challengeTimestamp"])
+			assert.InDelta(t, now.Unix(), serviceError.Details["serverTime"], 1)
+		})
+	}
+}
+
+func TestAuthMiddlewareFutureChallengeDetails(t *testing.T) {
+	cfg := config.Challenge{Window: 8, ClockSkew: 2, Domain: "test.grid.tf", AllowLegacyFormat: true}
+	app := fiber.New()
+	app.Use(AuthMiddleware(cfg, nil, nil))
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	kr, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := createSignMessageAt(cfg.Domain, time.Now().Add(time.Hour))
+	sig, err := kr.Sign([]byte(challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := app.Test(createTestRequest(kr.SS58Address(42), hex.EncodeToString(sig), toHex(challenge)))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusBadRequest, resp.StatusCode)
+
+	var errorResp struct {
+		Error   string         `json:"error"`
+		Details map[string]any `json:"details"`
+	}
+	assert.NoError(t, parseResponse(resp, &errorResp))
+	assert.Contains(t, errorResp.Error, "in the future")
+	assert.InDelta(t, float64(time.Now().Unix()), errorResp.Details["serverTime"], 1)
+	assert.Equal(t, float64(cfg.ClockSkew), errorResp.Details["clockSkew"])
+}
+
+func TestAuthMiddlewareSessionToken(t *testing.T) {
+	cfg := config.Challenge{Window: 8, ClockSkew: 2, Domain: "test.grid.tf", AllowLegacyFormat: true}
+	sessions := session.NewManager(config.Session{SigningKey: "test-session-signing-key-0123456789", TTL: 15}, cfg.Domain)
+	app := fiber.New()
+	app.Use(AuthMiddleware(cfg, nil, sessions))
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(handlers.CLIENT_ID_LOCAL_KEY).(string))
+	})
+
+	kr, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientID := kr.SS58Address(42)
+	token, err := sessions.Issue(clientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSessions := session.NewManager(config.Session{SigningKey: "another-session-signing-key-0123456", TTL: 15}, cfg.Domain)
+	forgedToken, err := otherSessions.Issue(clientID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := createValidSignMessage(cfg.Domain)
+	sig, err := kr.Sign([]byte(challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		authorization  string
+		clientID       string
+		signature      string
+		challenge      string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "valid session token",
+			authorization:  "Bearer " + token.Token,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "valid session token with matching client ID",
+			authorization:  "Bearer " + token.Token,
+			clientID:       clientID,
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "session token bound to another client ID",
+			authorization:  "Bearer " + token.Token,
+			clientID:       "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY",
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "bound to another client ID",
+		},
+		{
+			name:           "session token signed with another key",
+			authorization:  "Bearer " + forgedToken.Token,
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "bad session token",
+		},
+		{
+			name:           "signature still accepted",
+			clientID:       clientID,
+			signature:      hex.EncodeToString(sig),
+			challenge:      toHex(challenge),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "non bearer authorization falls back to signature",
+			authorization:  "Basic dXNlcjpwYXNz",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "missing authentication credentials",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createTestRequest(tt.clientID, tt.signature, tt.challenge)
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			if tt.expectedError != "" {
+				assert.Contains(t, string(body), tt.expectedError)
+			} else {
+				assert.Equal(t, clientID, string(body))
+			}
+		})
+	}
+}
+
+// Test vectors signed by the Alice development keys (`//Alice` derivation of the development phrase) of each scheme
+const (
+	vectorMessage        = "test.grid.tf:1700000000:0123456789abcdef"
+	vectorWrappedMessage = "<Bytes>" + vectorMessage + "</Bytes>"
+	vectorEd25519Address = "5FA9nQDVg267DEd8m1ZypXLBnvN7SFxYwV7ndqSYGiN9TTpu"
+	vectorSr25519Address = "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+	vectorEcdsaAddress   = "5C7C2Z5sWbytvHpuLTvzKunnnRwQxft1jiqrLD5rhucQ5S9X"
+	// signatures over the raw message
+	vectorEd25519Signature = "bc0f51cdba9a96d0f8c52e9d0448cf28929498e2c29f4c4ccf5fe84c9dd6f5520cc08070f6a90555b970084b8f53934a85384b62fb08aafd802294ce2280ca04"
+	vectorSr25519Signature = "b660abcfc3d90949a4468c43be62dac11f12620fa759e3a75b95e0886afad701927376921eeb026fc7e04a2710a29ad9caa458082676d7fc373279d6efe55788"
+	vectorEcdsaSignature   = "c56cdb277f51a9484edb4ad11a8768d80fa0cb2f669be1f14ceeafd89a33ef6b36f01c50af5a0f0abb1b2fc64c37fd3011bda2585e175ab30fe2950a437b99ea00"
+	// signatures over the Polkadot.js wrapped message
+	vectorWrappedEd25519Signature = "64db8f3811264697e201536ba2e12509ca7ca59071db0a359537e076dac4722e073dcd3889ae502091266facfe8663ffedb2fafc38c7d8bc56153deb082dfa07"
+	vectorWrappedSr25519Signature = "548c761c8fc0446cb3b41cccbb82aaaba6dff58db0bd1f05eb6a48de397a1969e7bd6443878c7343a99a08b2d206bfc99e8033d50e8bc152267ee2c474ba7e8f"
+	vectorWrappedEcdsaSignature   = "ac969814ff3c7e5c7042b2d84ad6269d412d09c9530e11444d6e8b3e0e6aa57e08fd31463cc6d1141a2921de2ad0b0345d2f05f3bdd35d4cbaeef1922cba176a00"
+)
+
+func TestVerifySubstrateSignatureVectors(t *testing.T) {
+	allSchemes := []SignatureScheme{SchemeEd25519, SchemeSr25519, SchemeEcdsa}
+	tests := []struct {
+		name          string
+		address       string
+		signature     string
+		challenge     string
+		schemes       []SignatureScheme
+		expectedError string
+	}{
+		{name: "ed25519 raw", address: vectorEd25519Address, signature: vectorEd25519Signature, challenge: vectorMessage},
+		{name: "sr25519 raw", address: vectorSr25519Address, signature: vectorSr25519Signature, challenge: vectorMessage},
+		{name: "ecdsa raw", address: vectorEcdsaAddress, signature: vectorEcdsaSignature, challenge: vectorMessage, schemes: allSchemes},
+		{name: "ed25519 wrapped signature of raw challenge", address: vectorEd25519Address, signature: vectorWrappedEd25519Signature, challenge: vectorMessage},
+		{name: "sr25519 wrapped signature of raw challenge", address: vectorSr25519Address, signature: vectorWrappedSr25519Signature, challenge: vectorMessage},
+		{name: "ecdsa wrapped signature of raw challenge", address: vectorEcdsaAddress, signature: vectorWrappedEcdsaSignature, challenge: vectorMessage, schemes: allSchemes},
+		{name: "sr25519 wrapped signature of wrapped challenge", address: vectorSr25519Address, signature: vectorWrappedSr25519Signature, challenge: vectorWrappedMessage},
+		{name: "sr25519 raw signature of wrapped challenge", address: vectorSr25519Address, signature: vectorSr25519Signature, challenge: vectorWrappedMessage},
+		{name: "declared sr25519 scheme", address: vectorSr25519Address, signature: vectorSr25519Signature, challenge: vectorMessage, schemes: []SignatureScheme{SchemeSr25519}},
+		{name: "declared scheme mismatch", address: vectorSr25519Address, signature: vectorSr25519Signature, challenge: vectorMessage, schemes: []SignatureScheme{SchemeEd25519}, expectedError: "signature does not match"},
+		{name: "ecdsa not enabled", address: vectorEcdsaAddress, signature: vectorEcdsaSignature, challenge: vectorMessage, expectedError: "signature does not match"},
+		{name: "ecdsa signature by another account", address: vectorSr25519Address, signature: vectorEcdsaSignature, challenge: vectorMessage, schemes: allSchemes, expectedError: "signature does not match"},
+		{name: "tampered message", address: vectorEd25519Address, signature: vectorEd25519Signature, challenge: vectorMessage + "0", schemes: allSchemes, expectedError: "signature does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySubstrateSignature(tt.address, tt.signature, toHex(tt.challenge), tt.schemes...)
+			if tt.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestSignatureSchemes(t *testing.T) {
+	schemes, err := SignatureSchemes("", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []SignatureScheme{SchemeEd25519, SchemeSr25519}, schemes)
+
+	schemes, err = SignatureSchemes("", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []SignatureScheme{SchemeEd25519, SchemeSr25519, SchemeEcdsa}, schemes)
+	assert.Equal(t, []SignatureScheme{SchemeEd25519, SchemeSr25519}, DefaultSignatureSchemes)
+
+	schemes, err = SignatureSchemes("Sr25519", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []SignatureScheme{SchemeSr25519}, schemes)
+
+	_, err = SignatureSchemes("ecdsa", false)
+	assert.ErrorContains(t, err, "unsupported signature scheme")
+}
+
+func TestAuthMiddlewareSignatureSchemes(t *testing.T) {
+	cfg := config.Challenge{Window: 8, ClockSkew: 2, Domain: "test.grid.tf", AllowLegacyFormat: true, AllowEcdsa: true}
+	app := fiber.New()
+	app.Use(AuthMiddleware(cfg, nil, nil))
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	krSr25519, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	krEcdsa, err := ecdsa.Scheme{}.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := createValidSignMessage(cfg.Domain)
+	wrapped := "<Bytes>" + challenge + "</Bytes>"
+	sign := func(kr subkey.KeyPair, message string) string {
+		sig, err := kr.Sign([]byte(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(sig)
+	}
+
+	tests := []struct {
+		name           string
+		clientID       string
+		signature      string
+		challenge      string
+		scheme         string
+		expectedStatus int
+		expectedError  string
+	}{
+		{
+			name:           "polkadot.js wrapped sr25519 signature",
+			clientID:       krSr25519.SS58Address(42),
+			signature:      sign(krSr25519, wrapped),
+			challenge:      toHex(challenge),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "wrapped challenge header",
+			clientID:       krSr25519.SS58Address(42),
+			signature:      sign(krSr25519, wrapped),
+			challenge:      toHex(wrapped),
+			scheme:         "sr25519",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "detected ecdsa signature",
+			clientID:       krEcdsa.SS58Address(42),
+			signature:      sign(krEcdsa, challenge),
+			challenge:      toHex(challenge),
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "declared ecdsa signature",
+			clientID:       krEcdsa.SS58Address(42),
+			signature:      sign(krEcdsa, wrapped),
+			challenge:      toHex(challenge),
+			scheme:         "ecdsa",
+			expectedStatus: fiber.StatusOK,
+		},
+		{
+			name:           "declared wrong scheme",
+			clientID:       krSr25519.SS58Address(42),
+			signature:      sign(krSr25519, challenge),
+			challenge:      toHex(challenge),
+			scheme:         "ed25519",
+			expectedStatus: fiber.StatusUnauthorized,
+			expectedError:  "signature does not match",
+		},
+		{
+			name:           "declared unknown scheme",
+			clientID:       krSr25519.SS58Address(42),
+			signature:      sign(krSr25519, challenge),
+			challenge:      toHex(challenge),
+			scheme:         "rsa",
+			expectedStatus: fiber.StatusBadRequest,
+			expectedError:  "unsupported signature scheme",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := createTestRequest(tt.clientID, tt.signature, tt.challenge)
+			if tt.scheme != "" {
+				req.Header.Set("X-Signature-Scheme", tt.scheme)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedError != "" {
+				var errorResp struct {
+					Error string `json:"error"`
+				}
+				assert.NoError(t, parseResponse(resp, &errorResp))
+				assert.Contains(t, errorResp.Error, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareSS58Prefix(t *testing.T) {
+	cfg := config.Challenge{Window: 8, ClockSkew: 2, Domain: "test.grid.tf", AllowLegacyFormat: true, AllowedSS58Prefixes: []uint16{42, 0}}
+	app := fiber.New()
+	app.Use(AuthMiddleware(cfg, nil, nil))
+	app.Get("/test", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals(handlers.CLIENT_ID_LOCAL_KEY).(string))
+	})
+	kr, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := createValidSignMessage(cfg.Domain)
+	sig, err := kr.Sign([]byte(challenge))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name           string
+		clientID       string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "canonical prefix", clientID: kr.SS58Address(42), expectedStatus: fiber.StatusOK, expectedBody: kr.SS58Address(42)},
+		{name: "allowed prefix is normalized", clientID: kr.SS58Address(0), expectedStatus: fiber.StatusOK, expectedBody: kr.SS58Address(42)},
+		{name: "prefix not allowed", clientID: kr.SS58Address(2), expectedStatus: fiber.StatusBadRequest, expectedBody: "ss58 prefix 2 is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(createTestRequest(tt.clientID, hex.EncodeToString(sig), toHex(challenge)))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+func TestPartnerAPIKeyMiddleware(t *testing.T) {
+	partners := fakePartnerAuthenticator{"valid-key": {Name: "partner", AllowedFields: []string{"status"}}}
+	newApp := func(allowAnonymous bool) *fiber.App {
+		app := fiber.New()
+		app.Use(PartnerAPIKeyMiddleware(allowAnonymous, partners))
+		app.Get("/test", func(c *fiber.Ctx) error {
+			if partnerKey, ok := c.Locals(handlers.PARTNER_KEY_LOCAL_KEY).(*models.PartnerAPIKey); ok {
+				return c.SendString(partnerKey.Name)
+			}
+			return c.SendString("anonymous")
+		})
+		return app
+	}
+	tests := []struct {
+		name           string
+		allowAnonymous bool
+		key            string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "anonymous allowed", allowAnonymous: true, expectedStatus: fiber.StatusOK, expectedBody: "anonymous"},
+		{name: "anonymous not allowed", allowAnonymous: false, expectedStatus: fiber.StatusUnauthorized, expectedBody: "missing API key"},
+		{name: "valid key", allowAnonymous: false, key: "valid-key", expectedStatus: fiber.StatusOK, expectedBody: "partner"},
+		{name: "invalid key with anonymous allowed", allowAnonymous: true, key: "invalid-key", expectedStatus: fiber.StatusUnauthorized, expectedBody: "invalid or revoked API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(fiber.MethodGet, "/test", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			resp, err := newApp(tt.allowAnonymous).Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Contains(t, string(body), tt.expectedBody)
+		})
+	}
+}
+
+type fakePartnerAuthenticator map[string]*models.PartnerAPIKey
+
+func (f fakePartnerAuthenticator) Authenticate(ctx context.Context, key string) (*models.PartnerAPIKey, error) {
+	if partnerKey, ok := f[key]; ok {
+		return partnerKey, nil
+	}
+	return nil, errors.NewAuthorizationError("invalid or revoked API key", nil)
+}
+
+// fakeNonceStore accepts each of its issued nonces once
+type fakeNonceStore struct {
+	mu     sync.Mutex
+	issued map[string]bool
+}
+
+func newFakeNonceStore(nonces ...string) *fakeNonceStore {
+	store := &fakeNonceStore{issued: map[string]bool{}}
+	for _, nonce := range nonces {
+		store.issued[nonce] = true
+	}
+	return store
+}
+
+func (f *fakeNonceStore) ConsumeNonce(ctx context.Context, clientID string, nonce string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.issued[nonce] {
+		return errors.NewAuthorizationError("bad challenge: nonce is unknown, expired or already used", nil)
+	}
+	delete(f.issued, nonce)
+	return nil
+}
+
 // Helper function to create test requests
 func createTestRequest(clientID, signature, challenge string) *http.Request {
 	req := httptest.NewRequest(fiber.MethodGet, "/test", nil)
@@ -221,6 +808,14 @@ func createValidSignMessage(domain string) string {
 	return message
 }
 
+func createValidSignMessageWithNonce(domain string, nonce string) string {
+	return fmt.Sprintf("%s:%d:%s", domain, time.Now().Unix(), nonce)
+}
+
+func createSignMessageAt(domain string, at time.Time) string {
+	return fmt.Sprintf("%s:%d", domain, at.Unix())
+}
+
 func createInvalidSignMessageWrongDomain() string {
 	// return a message with the domain and the current timestamp in hex
 	message := fmt.Sprintf("%s:%d", "wrong.domain", time.Now().Unix())
@@ -254,3 +849,190 @@ func generateTestEd25519Keys() (subkey.KeyPair, error) {
 	}
 	return krEd25519, nil
 }
+
+func TestNetworkMiddleware(t *testing.T) {
+	devnet := &services.Network{
+		Name:      "devnet",
+		Challenge: config.Challenge{Window: 8, ClockSkew: 2, Domain: "dev.grid.tf", AllowLegacyFormat: true},
+		KYC:       &services.KYCService{IdenfySuffix: "dev"},
+	}
+	qanet := &services.Network{
+		Name:      "qanet",
+		Challenge: config.Challenge{Window: 8, ClockSkew: 2, Domain: "qa.grid.tf", AllowLegacyFormat: true},
+		KYC:       &services.KYCService{IdenfySuffix: "qa"},
+	}
+	networks, err := services.NewNetworks(devnet, qanet)
+	assert.NoError(t, err)
+
+	app := fiber.New()
+	for _, prefix := range []string{"/api", "/networks/:network/api"} {
+		group := app.Group(prefix, NetworkMiddleware(networks, "X-TFChain-Network"), AuthMiddleware(devnet.Challenge, nil, nil))
+		group.Get("/test", func(c *fiber.Ctx) error {
+			return c.SendString(handlers.RequestNetwork(c).Name)
+		})
+	}
+
+	kr, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := func(domain string) (string, string) {
+		challenge := createValidSignMessage(domain)
+		sig, err := kr.Sign([]byte(challenge))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hex.EncodeToString(sig), toHex(challenge)
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		domain         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "default network", path: "/api/test", domain: "dev.grid.tf", expectedStatus: fiber.StatusOK, expectedBody: "devnet"},
+		{name: "network header", path: "/api/test", header: "qanet", domain: "qa.grid.tf", expectedStatus: fiber.StatusOK, expectedBody: "qanet"},
+		{name: "path prefix", path: "/networks/qanet/api/test", domain: "qa.grid.tf", expectedStatus: fiber.StatusOK, expectedBody: "qanet"},
+		{name: "challenge of another network", path: "/api/test", domain: "qa.grid.tf", expectedStatus: fiber.StatusBadRequest},
+		{name: "unknown network", path: "/networks/mainnet/api/test", domain: "dev.grid.tf", expectedStatus: fiber.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, challenge := signed(tt.domain)
+			req := createTestRequest(kr.SS58Address(42), signature, challenge)
+			req.URL.Path, req.RequestURI = tt.path, tt.path
+			if tt.header != "" {
+				req.Header.Set("X-TFChain-Network", tt.header)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedBody != "" {
+				body, err := io.ReadAll(resp.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedBody, string(body))
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareRedactsHeaders(t *testing.T) {
+	var logs bytes.Buffer
+	app := fiber.New()
+	app.Use(NewLoggingMiddleware(slog.New(slog.NewJSONHandler(&logs, nil))))
+	app.Get("/test", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) })
+
+	credentials := map[string]string{
+		"Authorization": "Bearer session-token",
+		"X-Admin-Key":   "admin-key",
+		"X-API-Key":     "partner-key",
+	}
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	for name, value := range credentials {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("X-Client-ID", "client")
+	_, err := app.Test(req)
+	assert.NoError(t, err)
+
+	for _, value := range credentials {
+		assert.NotContains(t, logs.String(), value)
+	}
+	assert.Contains(t, logs.String(), "[REDACTED]")
+	assert.Contains(t, logs.String(), "client")
+}
+
+// fakeAttemptRepo keeps the verification attempts in memory for the admin routes
+type fakeAttemptRepo struct {
+	attempts map[string]*models.VerificationAttempts
+}
+
+func (f *fakeAttemptRepo) GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	return f.attempts[clientID], nil
+}
+func (f *fakeAttemptRepo) RecordAttempt(ctx context.Context, clientID string, scanRef string) error {
+	return nil
+}
+func (f *fakeAttemptRepo) RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error {
+	return nil
+}
+func (f *fakeAttemptRepo) ResetAttempts(ctx context.Context, clientID string) error {
+	if attempts, ok := f.attempts[clientID]; ok {
+		resetAt := time.Now()
+		attempts.Count, attempts.ResetAt = 0, &resetAt
+	}
+	return nil
+}
+
+type fakeSubstrate struct{}
+
+func (f *fakeSubstrate) GetChainName() (string, error)                    { return "TFChain Devnet", nil }
+func (f *fakeSubstrate) GetAddressByTwinID(twinID uint32) (string, error) { return "", nil }
+func (f *fakeSubstrate) GetTwinIDByAddress(address string) (uint32, error) {
+	return 0, nil
+}
+func (f *fakeSubstrate) GetAccountBalance(address string) (uint64, error) { return 0, nil }
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	const adminKey = "admin-key-of-at-least-32-characters"
+	kr, err := generateTestSr25519Keys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientID := kr.SS58Address(42)
+	attempts := &fakeAttemptRepo{attempts: map[string]*models.VerificationAttempts{clientID: {ClientID: clientID, Count: 3}}}
+	cfg := &config.Config{
+		Challenge:   config.Challenge{AllowedSS58Prefixes: []uint16{42}},
+		Eligibility: config.Eligibility{Rules: []config.EligibilityRule{{Type: "max_attempts", MaxAttempts: 3}}},
+	}
+	kycService, err := services.NewKYCService(nil, nil, attempts, nil, nil, nil, nil, nil, &fakeSubstrate{}, cfg.Challenge.AllowedSS58Prefixes, cfg, slog.Default())
+	assert.NoError(t, err)
+	networks, err := services.NewNetworks(&services.Network{Name: "devnet", KYC: kycService})
+	assert.NoError(t, err)
+	handler := handlers.NewHandler(networks, nil, nil, cfg, slog.Default())
+
+	app := fiber.New()
+	admin := app.Group("/admin", AdminAuthMiddleware(adminKey))
+	admin.Get("/attempts/:client_id", handler.GetVerificationAttempts())
+	admin.Post("/attempts/:client_id/reset", handler.ResetVerificationAttempts())
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		key              string
+		expectedStatus   int
+		expectedAttempts uint
+	}{
+		{name: "missing key", method: fiber.MethodGet, path: "/admin/attempts/" + clientID, expectedStatus: fiber.StatusUnauthorized},
+		{name: "invalid key can't reset", method: fiber.MethodPost, path: "/admin/attempts/" + clientID + "/reset", key: "invalid-key", expectedStatus: fiber.StatusUnauthorized},
+		{name: "get attempts", method: fiber.MethodGet, path: "/admin/attempts/" + clientID, key: adminKey, expectedStatus: fiber.StatusOK, expectedAttempts: 3},
+		{name: "reset attempts", method: fiber.MethodPost, path: "/admin/attempts/" + clientID + "/reset", key: adminKey, expectedStatus: fiber.StatusOK, expectedAttempts: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-Admin-Key", tt.key)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.StatusCode)
+			if tt.expectedStatus != fiber.StatusOK {
+				return
+			}
+			var body struct {
+				Result struct {
+					Attempts    uint `json:"attempts"`
+					MaxAttempts uint `json:"maxAttempts"`
+				} `json:"result"`
+			}
+			assert.NoError(t, parseResponse(resp, &body))
+			assert.Equal(t, tt.expectedAttempts, body.Result.Attempts)
+			assert.Equal(t, uint(3), body.Result.MaxAttempts)
+		})
+	}
+}
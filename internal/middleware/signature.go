@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	secp256k1 "github.com/ethereum/go-ethereum/crypto"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/vedhavyas/go-subkey/v2"
+	"github.com/vedhavyas/go-subkey/v2/ed25519"
+	"github.com/vedhavyas/go-subkey/v2/sr25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+// SignatureScheme is a key scheme a client can sign the challenge with
+type SignatureScheme string
+
+const (
+	SchemeEd25519 SignatureScheme = "ed25519"
+	SchemeSr25519 SignatureScheme = "sr25519"
+	SchemeEcdsa   SignatureScheme = "ecdsa"
+)
+
+// DefaultSignatureSchemes are the schemes tried when the client doesn't declare one
+var DefaultSignatureSchemes = []SignatureScheme{SchemeEd25519, SchemeSr25519}
+
+const (
+	// Polkadot.js extensions wrap the raw payloads they sign in these tags
+	bytesWrapperPrefix = "<Bytes>"
+	bytesWrapperSuffix = "</Bytes>"
+)
+
+// SignatureSchemes returns the schemes to verify a signature with, the declared one if any, otherwise every enabled scheme
+func SignatureSchemes(declared string, allowEcdsa bool) ([]SignatureScheme, error) {
+	schemes := DefaultSignatureSchemes
+	if allowEcdsa {
+		schemes = append(schemes[:len(schemes):len(schemes)], SchemeEcdsa)
+	}
+	if declared == "" {
+		return schemes, nil
+	}
+	scheme := SignatureScheme(strings.ToLower(strings.TrimSpace(declared)))
+	for _, enabled := range schemes {
+		if scheme == enabled {
+			return []SignatureScheme{scheme}, nil
+		}
+	}
+	return nil, errors.NewValidationError(fmt.Sprintf("unsupported signature scheme %q", declared), nil)
+}
+
+func fromHex(hex string) ([]byte, bool) {
+	return subkey.DecodeHex(hex)
+}
+
+// unwrapBytes strips the Polkadot.js `<Bytes>...</Bytes>` wrapper from a message, if present
+func unwrapBytes(message []byte) []byte {
+	if bytes.HasPrefix(message, []byte(bytesWrapperPrefix)) && bytes.HasSuffix(message, []byte(bytesWrapperSuffix)) {
+		return message[len(bytesWrapperPrefix) : len(message)-len(bytesWrapperSuffix)]
+	}
+	return message
+}
+
+// signedMessages returns the messages a client may have signed for the challenge: raw or wrapped by a Polkadot.js extension
+func signedMessages(challenge []byte) [][]byte {
+	raw := unwrapBytes(challenge)
+	wrapped := append(append([]byte(bytesWrapperPrefix), raw...), bytesWrapperSuffix...)
+	return [][]byte{raw, wrapped}
+}
+
+// VerifySubstrateSignature verifies the signature of the challenge by the address, with any of the given schemes.
+// The signature is accepted over the raw challenge or its Polkadot.js `<Bytes>...</Bytes>` wrapped form.
+// Without schemes, the default ed25519 and sr25519 schemes are tried.
+func VerifySubstrateSignature(address, signature, challenge string, schemes ...SignatureScheme) error {
+	challengeBytes, ok := fromHex(challenge)
+	if !ok {
+		return errors.NewValidationError("malformed challenge: failed to decode hex-encoded challenge", nil)
+	}
+	// hex to string
+	sig, ok := fromHex(signature)
+	if !ok {
+		return errors.NewValidationError("malformed signature: failed to decode hex-encoded signature", nil)
+	}
+	// Convert address to public key
+	_, pubkeyBytes, err := subkey.SS58Decode(address)
+	if err != nil {
+		return errors.NewValidationError("malformed address:failed to decode ss58 address", err)
+	}
+	if len(schemes) == 0 {
+		schemes = DefaultSignatureSchemes
+	}
+
+	for _, scheme := range schemes {
+		verified, err := verifyWithScheme(scheme, pubkeyBytes, sig, signedMessages(challengeBytes))
+		if err != nil {
+			return err
+		}
+		if verified {
+			return nil
+		}
+	}
+	return errors.NewAuthorizationError("bad signature: signature does not match", nil)
+}
+
+func verifyWithScheme(scheme SignatureScheme, accountID []byte, sig []byte, messages [][]byte) (bool, error) {
+	switch scheme {
+	case SchemeEd25519, SchemeSr25519:
+		if len(sig) != 64 {
+			return false, nil
+		}
+		var pubkey subkey.PublicKey
+		var err error
+		if scheme == SchemeEd25519 {
+			pubkey, err = ed25519.Scheme{}.FromPublicKey(accountID)
+		} else {
+			pubkey, err = sr25519.Scheme{}.FromPublicKey(accountID)
+		}
+		if err != nil {
+			// the account ID is not a valid public key for this scheme, e.g. an ed25519 key that is not a valid ristretto point
+			return false, nil
+		}
+		for _, message := range messages {
+			if pubkey.Verify(message, sig) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case SchemeEcdsa:
+		// ecdsa account IDs are the blake2b-256 hash of the compressed public key, which is recovered from the signature
+		if len(sig) != 65 {
+			return false, nil
+		}
+		for _, message := range messages {
+			digest := blake2b.Sum256(message)
+			pubkey, err := secp256k1.SigToPub(digest[:], sig)
+			if err != nil {
+				continue
+			}
+			recoveredAccountID := blake2b.Sum256(secp256k1.CompressPubkey(pubkey))
+			if bytes.Equal(recoveredAccountID[:], accountID) && secp256k1.VerifySignature(secp256k1.CompressPubkey(pubkey), digest[:], sig[:64]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, errors.NewValidationError(fmt.Sprintf("unsupported signature scheme %q", scheme), nil)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Package migrations contains the versioned schema migrations of the application.
+Every step runs once, in version order, and is recorded in the schema_migrations collection of the database.
+Migrations run at startup, or through the migrate command of the binary, and stop at the first failing step.
+*/
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Migration is a versioned schema change, it should be safe to run again if it fails halfway
+type Migration struct {
+	Version     uint
+	Description string
+	Up          func(ctx context.Context) error
+}
+
+// Store records the applied migrations
+type Store interface {
+	AppliedVersions(ctx context.Context) ([]uint, error)
+	RecordVersion(ctx context.Context, migration Migration, appliedAt time.Time) error
+}
+
+type Migrator struct {
+	store      Store
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// New returns a migrator of the given steps, their versions must be unique and increasing
+func New(store Store, migrations []Migration, logger *slog.Logger) (*Migrator, error) {
+	for i, migration := range migrations {
+		if migration.Version == 0 {
+			return nil, fmt.Errorf("migration %q has no version", migration.Description)
+		}
+		if i > 0 && migration.Version <= migrations[i-1].Version {
+			return nil, fmt.Errorf("migration %d is not ordered after migration %d", migration.Version, migrations[i-1].Version)
+		}
+	}
+	return &Migrator{store: store, migrations: migrations, logger: logger}, nil
+}
+
+// Pending returns the migrations not applied yet
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.store.AppliedVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting applied migrations: %w", err)
+	}
+	pending := []Migration{}
+	for _, migration := range m.migrations {
+		if !slices.Contains(applied, migration.Version) {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations and returns them. In dry-run mode, the pending migrations are only returned.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Migration, error) {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		for _, migration := range pending {
+			m.logger.Info("Pending migration", "version", migration.Version, "description", migration.Description)
+		}
+		return pending, nil
+	}
+	for _, migration := range pending {
+		m.logger.Info("Applying migration", "version", migration.Version, "description", migration.Description)
+		if err := migration.Up(ctx); err != nil {
+			return nil, fmt.Errorf("applying migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		if err := m.store.RecordVersion(ctx, migration, time.Now()); err != nil {
+			return nil, fmt.Errorf("recording migration %d: %w", migration.Version, err)
+		}
+	}
+	return pending, nil
+}
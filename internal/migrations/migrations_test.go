@@ -0,0 +1,69 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+type fakeStore struct {
+	versions []uint
+}
+
+func (f *fakeStore) AppliedVersions(ctx context.Context) ([]uint, error) {
+	return f.versions, nil
+}
+
+func (f *fakeStore) RecordVersion(ctx context.Context, migration Migration, appliedAt time.Time) error {
+	f.versions = append(f.versions, migration.Version)
+	return nil
+}
+
+func TestNewRejectsUnorderedMigrations(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+	_, err := New(&fakeStore{}, []Migration{{Version: 2, Up: noop}, {Version: 1, Up: noop}}, slog.Default())
+	assert.ErrorContains(t, err, "not ordered")
+	_, err = New(&fakeStore{}, []Migration{{Version: 1, Up: noop}, {Version: 1, Up: noop}}, slog.Default())
+	assert.ErrorContains(t, err, "not ordered")
+	_, err = New(&fakeStore{}, []Migration{{Description: "unversioned", Up: noop}}, slog.Default())
+	assert.ErrorContains(t, err, "no version")
+
+	_, err = New(&fakeStore{}, MongoMigrations(nil, repository.Scope{}), slog.Default())
+	assert.NoError(t, err)
+	_, err = New(&fakeStore{}, PostgresMigrations(nil, repository.Scope{}), slog.Default())
+	assert.NoError(t, err)
+}
+
+func TestMigratorUp(t *testing.T) {
+	ran := []uint{}
+	step := func(version uint, err error) Migration {
+		return Migration{Version: version, Description: fmt.Sprintf("step %d", version), Up: func(ctx context.Context) error {
+			ran = append(ran, version)
+			return err
+		}}
+	}
+	store := &fakeStore{versions: []uint{1}}
+	migrator, err := New(store, []Migration{step(1, nil), step(2, nil), step(3, fmt.Errorf("index conflict")), step(4, nil)}, slog.Default())
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	pending, err := migrator.Up(ctx, true)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 3)
+	assert.Empty(t, ran, "dry-run should not apply migrations")
+
+	// fails fast on the failing step, later steps are not applied
+	_, err = migrator.Up(ctx, false)
+	assert.ErrorContains(t, err, "applying migration 3 (step 3): index conflict")
+	assert.Equal(t, []uint{2, 3}, ran)
+	assert.Equal(t, []uint{1, 2}, store.versions)
+
+	pending, err = migrator.Pending(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), pending[0].Version)
+}
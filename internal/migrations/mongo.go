@@ -0,0 +1,217 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MIGRATIONS_COLLECTION records the applied migrations, one document per version
+const MIGRATIONS_COLLECTION = "schema_migrations"
+
+type MongoStore struct {
+	collection *mongo.Collection
+}
+
+func NewMongoStore(db *mongo.Database) *MongoStore {
+	return &MongoStore{collection: db.Collection(MIGRATIONS_COLLECTION)}
+}
+
+func (s *MongoStore) AppliedVersions(ctx context.Context) ([]uint, error) {
+	cursor, err := s.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []struct {
+		Version uint `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	versions := make([]uint, 0, len(records))
+	for _, record := range records {
+		versions = append(versions, record.Version)
+	}
+	return versions, nil
+}
+
+func (s *MongoStore) RecordVersion(ctx context.Context, migration Migration, appliedAt time.Time) error {
+	// an upsert, so instances migrating at the same time don't fail on each other
+	_, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": migration.Version},
+		bson.M{"$setOnInsert": bson.M{"description": migration.Description, "appliedAt": appliedAt}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// NewMongo returns the migrator of the MongoDB database
+func NewMongo(db *mongo.Database, scope repository.Scope, logger *slog.Logger) (*Migrator, error) {
+	return New(NewMongoStore(db), MongoMigrations(db, scope), logger)
+}
+
+// MongoMigrations are the migrations of the MongoDB database, new steps are appended with the next version.
+// The scope is the deployment applying them, it owns the records saved before they were scoped.
+func MongoMigrations(db *mongo.Database, scope repository.Scope) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "create collection indexes",
+			Up: func(ctx context.Context) error {
+				return createIndexes(ctx, db, []index{
+					{"tokens", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)},
+					{"tokens", bson.D{{Key: "clientId", Value: 1}}, options.Index().SetUnique(true)},
+					{"tokens", bson.D{{Key: "scanRef", Value: 1}}, options.Index().SetUnique(true)},
+					{"verifications", bson.D{{Key: "clientId", Value: 1}}, options.Index().SetUnique(false)},
+					{"verifications", bson.D{{Key: "scanRef", Value: 1}}, options.Index().SetUnique(false)},
+					{"verifications", bson.D{{Key: "data.docExpiry", Value: 1}}, options.Index().SetUnique(false)},
+					{"verification_attempts", bson.D{{Key: "clientId", Value: 1}}, options.Index().SetUnique(true)},
+					{"identity_fingerprints", bson.D{{Key: "fingerprint", Value: 1}}, options.Index().SetUnique(true)},
+					{"challenge_nonces", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)},
+					{"challenge_nonces", bson.D{{Key: "nonce", Value: 1}}, options.Index().SetUnique(true)},
+					{"partner_api_keys", bson.D{{Key: "keyHash", Value: 1}}, options.Index().SetUnique(true)},
+					{"partner_api_key_usage", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)},
+					{"partner_api_key_usage", bson.D{{Key: "keyId", Value: 1}, {Key: "window", Value: 1}}, options.Index().SetUnique(true)},
+					{"consent_grants", bson.D{{Key: "clientId", Value: 1}, {Key: "partnerKeyId", Value: 1}}, nil},
+					{"consent_accesses", bson.D{{Key: "grantId", Value: 1}}, nil},
+					{"consent_accesses", bson.D{{Key: "clientId", Value: 1}}, nil},
+					{"locks", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)},
+				})
+			},
+		},
+		{
+			Version:     2,
+			Description: "scope tokens and verifications by network and namespace",
+			Up: func(ctx context.Context) error {
+				for _, collection := range []string{"tokens", "verifications"} {
+					_, err := db.Collection(collection).UpdateMany(ctx,
+						bson.M{"network": bson.M{"$exists": false}},
+						bson.M{"$set": bson.M{"network": scope.Network, "namespace": scope.Namespace}},
+					)
+					if err != nil {
+						return fmt.Errorf("scoping %s: %w", collection, err)
+					}
+				}
+				err := createIndexes(ctx, db, []index{
+					{"tokens", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "clientId", Value: 1}}, options.Index().SetUnique(true)},
+					{"verifications", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "clientId", Value: 1}, {Key: "createdAt", Value: -1}}, nil},
+				})
+				if err != nil {
+					return err
+				}
+				// the same clientId can have a token in each scope
+				return dropIndexes(ctx, db, map[string]string{"tokens": "clientId_1", "verifications": "clientId_1"})
+			},
+		},
+		{
+			Version:     3,
+			Description: "create account link indexes",
+			Up: func(ctx context.Context) error {
+				return createIndexes(ctx, db, []index{
+					// an account is linked to a single primary account at a time
+					{"account_links", bson.D{{Key: "linkedClientId", Value: 1}}, options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true})},
+					{"account_links", bson.D{{Key: "primaryClientId", Value: 1}, {Key: "active", Value: 1}}, nil},
+					{"account_link_events", bson.D{{Key: "primaryClientId", Value: 1}}, nil},
+					{"account_link_events", bson.D{{Key: "linkedClientId", Value: 1}}, nil},
+				})
+			},
+		},
+		{
+			Version:     4,
+			Description: "create verification session indexes",
+			Up: func(ctx context.Context) error {
+				return createIndexes(ctx, db, []index{
+					{"verification_sessions", bson.D{{Key: "scanRef", Value: 1}}, options.Index().SetUnique(true)},
+					{"verification_sessions", bson.D{{Key: "idenfySuffix", Value: 1}, {Key: "nextCheckAt", Value: 1}}, nil},
+					{"verification_sessions", bson.D{{Key: "expiresAt", Value: 1}}, options.Index().SetExpireAfterSeconds(0)},
+				})
+			},
+		},
+		{
+			Version:     5,
+			Description: "scope attempts, consents and account links by network and namespace",
+			Up: func(ctx context.Context) error {
+				for _, collection := range []string{"verification_attempts", "consent_grants", "account_links", "account_link_events"} {
+					_, err := db.Collection(collection).UpdateMany(ctx,
+						bson.M{"network": bson.M{"$exists": false}},
+						bson.M{"$set": bson.M{"network": scope.Network, "namespace": scope.Namespace}},
+					)
+					if err != nil {
+						return fmt.Errorf("scoping %s: %w", collection, err)
+					}
+				}
+				err := createIndexes(ctx, db, []index{
+					{"verification_attempts", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "clientId", Value: 1}}, options.Index().SetUnique(true)},
+					{"consent_grants", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "clientId", Value: 1}, {Key: "partnerKeyId", Value: 1}}, nil},
+					{"account_links", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "linkedClientId", Value: 1}}, options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"active": true})},
+					{"account_links", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "primaryClientId", Value: 1}, {Key: "active", Value: 1}}, nil},
+				})
+				if err != nil {
+					return err
+				}
+				// the same clientId can have attempts and an active link in each scope
+				return dropIndexes(ctx, db, map[string]string{"verification_attempts": "clientId_1", "account_links": "linkedClientId_1"})
+			},
+		},
+		{
+			Version:     6,
+			Description: "scope identity fingerprints by network and namespace",
+			Up: func(ctx context.Context) error {
+				_, err := db.Collection("identity_fingerprints").UpdateMany(ctx,
+					bson.M{"network": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"network": scope.Network, "namespace": scope.Namespace}},
+				)
+				if err != nil {
+					return fmt.Errorf("scoping identity_fingerprints: %w", err)
+				}
+				err = createIndexes(ctx, db, []index{
+					{"identity_fingerprints", bson.D{{Key: "network", Value: 1}, {Key: "namespace", Value: 1}, {Key: "fingerprint", Value: 1}}, options.Index().SetUnique(true)},
+				})
+				if err != nil {
+					return err
+				}
+				// the same identity can be verified once in each scope
+				return dropIndexes(ctx, db, map[string]string{"identity_fingerprints": "fingerprint_1"})
+			},
+		},
+	}
+}
+
+type index struct {
+	collection string
+	keys       bson.D
+	options    *options.IndexOptions
+}
+
+// dropIndexes drops the indexes by collection, indexes that don't exist are ignored
+func dropIndexes(ctx context.Context, db *mongo.Database, indexes map[string]string) error {
+	for collection, name := range indexes {
+		_, err := db.Collection(collection).Indexes().DropOne(ctx, name)
+		var commandErr mongo.CommandError
+		if errors.As(err, &commandErr) && commandErr.Name == "IndexNotFound" {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("dropping index %s on %s: %w", name, collection, err)
+		}
+	}
+	return nil
+}
+
+// createIndexes creates the indexes, existing indexes with the same keys and options are left as they are
+func createIndexes(ctx context.Context, db *mongo.Database, indexes []index) error {
+	for _, index := range indexes {
+		_, err := db.Collection(index.collection).Indexes().CreateOne(ctx, mongo.IndexModel{Keys: index.keys, Options: index.options})
+		if err != nil {
+			return fmt.Errorf("creating index %v on %s: %w", index.keys, index.collection, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,316 @@
+package migrations
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+type PostgresStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{pool: pool}
+}
+
+func (s *PostgresStore) AppliedVersions(ctx context.Context) ([]uint, error) {
+	_, err := s.pool.Exec(ctx, `CREATE TABLE IF NOT EXISTS `+MIGRATIONS_COLLECTION+` (
+		version BIGINT PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.pool.Query(ctx, `SELECT version FROM `+MIGRATIONS_COLLECTION)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, err
+	}
+	applied := make([]uint, 0, len(versions))
+	for _, version := range versions {
+		applied = append(applied, uint(version))
+	}
+	return applied, nil
+}
+
+func (s *PostgresStore) RecordVersion(ctx context.Context, migration Migration, appliedAt time.Time) error {
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO `+MIGRATIONS_COLLECTION+` (version, description, applied_at) VALUES ($1, $2, $3) ON CONFLICT (version) DO NOTHING`,
+		int64(migration.Version), migration.Description, appliedAt,
+	)
+	return err
+}
+
+// NewPostgres returns the migrator of the PostgreSQL database
+func NewPostgres(pool *pgxpool.Pool, scope repository.Scope, logger *slog.Logger) (*Migrator, error) {
+	return New(NewPostgresStore(pool), PostgresMigrations(pool, scope), logger)
+}
+
+// PostgresMigrations are the migrations of the PostgreSQL database, new steps are appended with the next version.
+// Documents are stored as the extended JSON of their MongoDB shape, next to the columns they are queried by.
+// The scope is the deployment applying them, it owns the records saved before they were scoped.
+func PostgresMigrations(pool *pgxpool.Pool, scope repository.Scope) []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "create tokens, verifications and rate_limits tables",
+			Up: func(ctx context.Context) error {
+				return execAll(ctx, pool,
+					`CREATE TABLE IF NOT EXISTS tokens (
+						id TEXT PRIMARY KEY,
+						client_id TEXT NOT NULL UNIQUE,
+						scan_ref TEXT NOT NULL UNIQUE,
+						document JSONB NOT NULL,
+						created_at TIMESTAMPTZ NOT NULL,
+						expires_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS tokens_expires_at_idx ON tokens (expires_at)`,
+					`CREATE TABLE IF NOT EXISTS verifications (
+						id TEXT PRIMARY KEY,
+						client_id TEXT NOT NULL,
+						scan_ref TEXT NOT NULL,
+						status_overall TEXT,
+						doc_expiry TEXT NOT NULL DEFAULT '',
+						document_expiry_flagged_at TIMESTAMPTZ,
+						document JSONB NOT NULL,
+						created_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS verifications_client_id_created_at_idx ON verifications (client_id, created_at DESC)`,
+					`CREATE INDEX IF NOT EXISTS verifications_scan_ref_idx ON verifications (scan_ref)`,
+					`CREATE INDEX IF NOT EXISTS verifications_doc_expiry_idx ON verifications (doc_expiry)`,
+					`CREATE TABLE IF NOT EXISTS rate_limits (
+						bucket TEXT NOT NULL,
+						key TEXT NOT NULL,
+						value BYTEA NOT NULL,
+						expires_at TIMESTAMPTZ,
+						PRIMARY KEY (bucket, key)
+					)`,
+					`CREATE INDEX IF NOT EXISTS rate_limits_expires_at_idx ON rate_limits (expires_at)`,
+				)
+			},
+		},
+		{
+			Version:     2,
+			Description: "scope tokens and verifications by network and namespace",
+			Up: func(ctx context.Context) error {
+				return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+					for _, table := range []string{"tokens", "verifications"} {
+						_, err := tx.Exec(ctx, `ALTER TABLE `+table+` ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS namespace TEXT NOT NULL DEFAULT ''`)
+						if err != nil {
+							return err
+						}
+						// the documents carry the scope too, as the MongoDB ones
+						_, err = tx.Exec(ctx,
+							`UPDATE `+table+` SET network = $1, namespace = $2, document = document || jsonb_build_object('network', $1::text, 'namespace', $2::text) WHERE network = ''`,
+							scope.Network, scope.Namespace,
+						)
+						if err != nil {
+							return err
+						}
+					}
+					// the same client_id can have a token in each scope
+					return execAll(ctx, tx,
+						`ALTER TABLE tokens DROP CONSTRAINT IF EXISTS tokens_client_id_key`,
+						`CREATE UNIQUE INDEX IF NOT EXISTS tokens_network_namespace_client_id_idx ON tokens (network, namespace, client_id)`,
+						`DROP INDEX IF EXISTS verifications_client_id_created_at_idx`,
+						`CREATE INDEX IF NOT EXISTS verifications_network_namespace_client_id_created_at_idx ON verifications (network, namespace, client_id, created_at DESC)`,
+					)
+				})
+			},
+		},
+		{
+			Version:     3,
+			Description: "create the tables of the attempts, fingerprints, challenges, partner keys, consents, locks, links and sessions",
+			Up: func(ctx context.Context) error {
+				// the fields updated in place are columns, they override the ones of the saved document
+				return execAll(ctx, pool,
+					`CREATE TABLE IF NOT EXISTS verification_attempts (
+						client_id TEXT PRIMARY KEY,
+						count BIGINT NOT NULL DEFAULT 0,
+						last_scan_ref TEXT NOT NULL DEFAULT '',
+						last_attempt_at TIMESTAMPTZ,
+						last_denied_at TIMESTAMPTZ,
+						reset_at TIMESTAMPTZ
+					)`,
+					`CREATE TABLE IF NOT EXISTS identity_fingerprints (
+						fingerprint TEXT PRIMARY KEY,
+						linked_client_ids TEXT[] NOT NULL DEFAULT '{}',
+						document JSONB NOT NULL
+					)`,
+					`CREATE TABLE IF NOT EXISTS challenge_nonces (
+						nonce TEXT PRIMARY KEY,
+						created_at TIMESTAMPTZ NOT NULL,
+						expires_at TIMESTAMPTZ NOT NULL,
+						used_at TIMESTAMPTZ,
+						used_by TEXT NOT NULL DEFAULT ''
+					)`,
+					`CREATE INDEX IF NOT EXISTS challenge_nonces_expires_at_idx ON challenge_nonces (expires_at)`,
+					`CREATE TABLE IF NOT EXISTS locks (
+						name TEXT PRIMARY KEY,
+						owner TEXT NOT NULL,
+						acquired_at TIMESTAMPTZ NOT NULL,
+						expires_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS locks_expires_at_idx ON locks (expires_at)`,
+					`CREATE TABLE IF NOT EXISTS partner_api_keys (
+						id TEXT PRIMARY KEY,
+						key_hash TEXT NOT NULL UNIQUE,
+						revoked_at TIMESTAMPTZ,
+						document JSONB NOT NULL,
+						created_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE TABLE IF NOT EXISTS partner_api_key_usage (
+						key_id TEXT NOT NULL,
+						window_start TIMESTAMPTZ NOT NULL,
+						count BIGINT NOT NULL,
+						expires_at TIMESTAMPTZ NOT NULL,
+						PRIMARY KEY (key_id, window_start)
+					)`,
+					`CREATE INDEX IF NOT EXISTS partner_api_key_usage_expires_at_idx ON partner_api_key_usage (expires_at)`,
+					`CREATE TABLE IF NOT EXISTS consent_grants (
+						id TEXT PRIMARY KEY,
+						client_id TEXT NOT NULL,
+						partner_key_id TEXT NOT NULL,
+						expires_at TIMESTAMPTZ NOT NULL,
+						revoked_at TIMESTAMPTZ,
+						document JSONB NOT NULL,
+						created_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS consent_grants_client_id_created_at_idx ON consent_grants (client_id, created_at DESC)`,
+					`CREATE TABLE IF NOT EXISTS consent_accesses (
+						id TEXT PRIMARY KEY,
+						grant_id TEXT NOT NULL,
+						document JSONB NOT NULL,
+						accessed_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS consent_accesses_grant_id_accessed_at_idx ON consent_accesses (grant_id, accessed_at DESC)`,
+					`CREATE TABLE IF NOT EXISTS account_links (
+						id TEXT PRIMARY KEY,
+						primary_client_id TEXT NOT NULL,
+						linked_client_id TEXT NOT NULL,
+						active BOOLEAN NOT NULL,
+						revoked_at TIMESTAMPTZ,
+						revoked_by TEXT NOT NULL DEFAULT '',
+						document JSONB NOT NULL,
+						created_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS account_links_primary_client_id_idx ON account_links (primary_client_id)`,
+					// an account has a single active link, as the partial unique index of MongoDB
+					`CREATE UNIQUE INDEX IF NOT EXISTS account_links_linked_client_id_active_idx ON account_links (linked_client_id) WHERE active`,
+					`CREATE TABLE IF NOT EXISTS account_link_events (
+						id TEXT PRIMARY KEY,
+						primary_client_id TEXT NOT NULL,
+						linked_client_id TEXT NOT NULL,
+						document JSONB NOT NULL,
+						at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS account_link_events_primary_client_id_idx ON account_link_events (primary_client_id)`,
+					`CREATE INDEX IF NOT EXISTS account_link_events_linked_client_id_idx ON account_link_events (linked_client_id)`,
+					`CREATE TABLE IF NOT EXISTS verification_sessions (
+						scan_ref TEXT PRIMARY KEY,
+						idenfy_suffix TEXT NOT NULL,
+						next_check_at TIMESTAMPTZ NOT NULL,
+						checks BIGINT NOT NULL DEFAULT 0,
+						resolved_at TIMESTAMPTZ,
+						overall TEXT NOT NULL DEFAULT '',
+						document JSONB NOT NULL,
+						expires_at TIMESTAMPTZ NOT NULL
+					)`,
+					`CREATE INDEX IF NOT EXISTS verification_sessions_idenfy_suffix_next_check_at_idx ON verification_sessions (idenfy_suffix, next_check_at) WHERE resolved_at IS NULL`,
+					`CREATE INDEX IF NOT EXISTS verification_sessions_expires_at_idx ON verification_sessions (expires_at)`,
+				)
+			},
+		},
+		{
+			Version:     4,
+			Description: "scope attempts, consents and account links by network and namespace",
+			Up: func(ctx context.Context) error {
+				return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+					for _, table := range []string{"verification_attempts", "consent_grants", "account_links", "account_link_events"} {
+						_, err := tx.Exec(ctx, `ALTER TABLE `+table+` ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS namespace TEXT NOT NULL DEFAULT ''`)
+						if err != nil {
+							return err
+						}
+					}
+					_, err := tx.Exec(ctx, `UPDATE verification_attempts SET network = $1, namespace = $2 WHERE network = ''`, scope.Network, scope.Namespace)
+					if err != nil {
+						return err
+					}
+					// the documents carry the scope too, as the MongoDB ones
+					for _, table := range []string{"consent_grants", "account_links", "account_link_events"} {
+						_, err := tx.Exec(ctx,
+							`UPDATE `+table+` SET network = $1, namespace = $2, document = document || jsonb_build_object('network', $1::text, 'namespace', $2::text) WHERE network = ''`,
+							scope.Network, scope.Namespace,
+						)
+						if err != nil {
+							return err
+						}
+					}
+					// the same client_id can have attempts and an active link in each scope
+					return execAll(ctx, tx,
+						`ALTER TABLE verification_attempts DROP CONSTRAINT IF EXISTS verification_attempts_pkey`,
+						`ALTER TABLE verification_attempts ADD PRIMARY KEY (network, namespace, client_id)`,
+						`DROP INDEX IF EXISTS consent_grants_client_id_created_at_idx`,
+						`CREATE INDEX IF NOT EXISTS consent_grants_network_namespace_client_id_created_at_idx ON consent_grants (network, namespace, client_id, created_at DESC)`,
+						`DROP INDEX IF EXISTS account_links_linked_client_id_active_idx`,
+						`CREATE UNIQUE INDEX IF NOT EXISTS account_links_network_namespace_linked_client_id_active_idx ON account_links (network, namespace, linked_client_id) WHERE active`,
+						`DROP INDEX IF EXISTS account_links_primary_client_id_idx`,
+						`CREATE INDEX IF NOT EXISTS account_links_network_namespace_primary_client_id_idx ON account_links (network, namespace, primary_client_id)`,
+						`DROP INDEX IF EXISTS account_link_events_primary_client_id_idx`,
+						`DROP INDEX IF EXISTS account_link_events_linked_client_id_idx`,
+						`CREATE INDEX IF NOT EXISTS account_link_events_network_namespace_primary_client_id_idx ON account_link_events (network, namespace, primary_client_id)`,
+						`CREATE INDEX IF NOT EXISTS account_link_events_network_namespace_linked_client_id_idx ON account_link_events (network, namespace, linked_client_id)`,
+					)
+				})
+			},
+		},
+		{
+			Version:     5,
+			Description: "scope identity fingerprints by network and namespace",
+			Up: func(ctx context.Context) error {
+				return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+					_, err := tx.Exec(ctx, `ALTER TABLE identity_fingerprints ADD COLUMN IF NOT EXISTS network TEXT NOT NULL DEFAULT '', ADD COLUMN IF NOT EXISTS namespace TEXT NOT NULL DEFAULT ''`)
+					if err != nil {
+						return err
+					}
+					_, err = tx.Exec(ctx,
+						`UPDATE identity_fingerprints SET network = $1, namespace = $2, document = document || jsonb_build_object('network', $1::text, 'namespace', $2::text) WHERE network = ''`,
+						scope.Network, scope.Namespace,
+					)
+					if err != nil {
+						return err
+					}
+					// the same identity can be verified once in each scope
+					return execAll(ctx, tx,
+						`ALTER TABLE identity_fingerprints DROP CONSTRAINT IF EXISTS identity_fingerprints_pkey`,
+						`ALTER TABLE identity_fingerprints ADD PRIMARY KEY (network, namespace, fingerprint)`,
+					)
+				})
+			},
+		},
+	}
+}
+
+// executor is a pool or a transaction
+type executor interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+func execAll(ctx context.Context, db executor, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := db.Exec(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VerificationAttempts tracks the iDenfy verification sessions started by a client over its lifetime
+type VerificationAttempts struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	ClientID string             `bson:"clientId"`
+	// Network and Namespace are the deployment the attempts are counted by, see repository.Scope
+	Network       string     `bson:"network"`
+	Namespace     string     `bson:"namespace"`
+	Count         uint       `bson:"count"`
+	LastScanRef   string     `bson:"lastScanRef"`
+	LastAttemptAt time.Time  `bson:"lastAttemptAt"`
+	LastDeniedAt  *time.Time `bson:"lastDeniedAt,omitempty"`
+	ResetAt       *time.Time `bson:"resetAt,omitempty"`
+}
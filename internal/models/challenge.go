@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChallengeNonce is a server-issued nonce that can be signed only once as part of a `domain:timestamp:nonce` challenge
+type ChallengeNonce struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Nonce     string             `bson:"nonce"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+	UsedAt    *time.Time         `bson:"usedAt,omitempty"`
+	UsedBy    string             `bson:"usedBy,omitempty"`
+}
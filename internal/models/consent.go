@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConsentGrant lets a partner service fetch a subset of the verification data of a client until it expires or is revoked
+type ConsentGrant struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	ClientID     string             `bson:"clientId"`
+	PartnerKeyID primitive.ObjectID `bson:"partnerKeyId"`
+	PartnerName  string             `bson:"partnerName"`
+	// Network and Namespace are the deployment the grant was made on, a partner only reads the data of that deployment
+	Network   string `bson:"network"`
+	Namespace string `bson:"namespace"`
+	// Fields are the verification data fields and the claims the partner is allowed to fetch
+	Fields []string `bson:"fields"`
+	// Statement is the hex-encoded `{api-domain}:{timestamp}:consent:{partnerId}:{fields}` message signed by the client
+	Statement string     `bson:"statement"`
+	Signature string     `bson:"signature"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	ExpiresAt time.Time  `bson:"expiresAt"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+}
+
+func (g *ConsentGrant) Active(now time.Time) bool {
+	return g.RevokedAt == nil && now.Before(g.ExpiresAt)
+}
+
+// ConsentAccess records a partner fetching verification data under a consent grant
+type ConsentAccess struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"`
+	GrantID      primitive.ObjectID `bson:"grantId"`
+	ClientID     string             `bson:"clientId"`
+	PartnerKeyID primitive.ObjectID `bson:"partnerKeyId"`
+	PartnerName  string             `bson:"partnerName"`
+	// Network and Namespace are the deployment the grant was made on, a partner only reads the data of that deployment
+	Network    string    `bson:"network"`
+	Namespace  string    `bson:"namespace"`
+	Fields     []string  `bson:"fields"`
+	AccessedAt time.Time `bson:"accessedAt"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IdentityFingerprint binds a keyed hash of an identity document to the first client verified with it
+type IdentityFingerprint struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	Fingerprint     string             `bson:"fingerprint"`
+	ClientID        string             `bson:"clientId"`
+	ScanRef         string             `bson:"scanRef"`
+	LinkedClientIDs []string           `bson:"linkedClientIds"`
+	// Network and Namespace are the deployment the identity was verified on, duplicates are only detected within it
+	Network   string    `bson:"network"`
+	Namespace string    `bson:"namespace"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// Shares tells whether the client owns the fingerprinted identity or is linked to it
+func (f *IdentityFingerprint) Shares(clientID string) bool {
+	return f.ClientID == clientID || slices.Contains(f.LinkedClientIDs, clientID)
+}
+
+type DuplicateIdentityOutcome string
+
+const (
+	DuplicateIdentityReject DuplicateIdentityOutcome = "REJECT"
+	DuplicateIdentityFlag   DuplicateIdentityOutcome = "FLAG"
+	DuplicateIdentityLink   DuplicateIdentityOutcome = "LINK"
+)
+
+type DuplicateIdentitySource string
+
+const (
+	DuplicateSourceFingerprint    DuplicateIdentitySource = "FINGERPRINT"
+	DuplicateSourceDuplicateFaces DuplicateIdentitySource = "DUPLICATE_FACES"
+)
+
+// DuplicateIdentity records that a verification matched an identity already used by another client
+type DuplicateIdentity struct {
+	ClientID string                   `bson:"clientId"`
+	ScanRef  string                   `bson:"scanRef"`
+	Source   DuplicateIdentitySource  `bson:"source"`
+	Outcome  DuplicateIdentityOutcome `bson:"outcome"`
+}
+
+const DenyReasonDuplicateIdentity = "DUPLICATE_IDENTITY"
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AccountLink lets a linked account inherit the verification of a primary account, both accounts signed the link statement
+type AccountLink struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	PrimaryClientID string             `bson:"primaryClientId"`
+	LinkedClientID  string             `bson:"linkedClientId"`
+	// Statement is the hex-encoded `{api-domain}:{timestamp}:link:{primaryClientId}:{linkedClientId}` message signed by both accounts
+	Statement        string `bson:"statement"`
+	PrimarySignature string `bson:"primarySignature"`
+	LinkedSignature  string `bson:"linkedSignature"`
+	// Network and Namespace are the deployment the accounts are linked on, see repository.Scope
+	Network   string `bson:"network"`
+	Namespace string `bson:"namespace"`
+	// Active is cleared on revocation, unique indexes are partial on it
+	Active    bool       `bson:"active"`
+	CreatedAt time.Time  `bson:"createdAt"`
+	RevokedAt *time.Time `bson:"revokedAt,omitempty"`
+	RevokedBy string     `bson:"revokedBy,omitempty"`
+}
+
+type AccountLinkAction string
+
+const (
+	AccountLinkCreated AccountLinkAction = "CREATED"
+	AccountLinkRevoked AccountLinkAction = "REVOKED"
+)
+
+// AccountLinkEvent audits the creation and revocation of an account link
+type AccountLinkEvent struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"`
+	LinkID          primitive.ObjectID `bson:"linkId"`
+	PrimaryClientID string             `bson:"primaryClientId"`
+	LinkedClientID  string             `bson:"linkedClientId"`
+	Network         string             `bson:"network"`
+	Namespace       string             `bson:"namespace"`
+	Action          AccountLinkAction  `bson:"action"`
+	// Actor is the client that created or revoked the link
+	Actor string    `bson:"actor"`
+	At    time.Time `bson:"at"`
+}
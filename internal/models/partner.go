@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PartnerAPIKey grants a partner service access to the verification status queries
+type PartnerAPIKey struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty"`
+	Name string             `bson:"name"`
+	// KeyHash is the SHA-256 hash of the key, the key itself is only returned once on creation
+	KeyHash string `bson:"keyHash"`
+	// Prefix is the beginning of the key, to identify it without storing it
+	Prefix string `bson:"prefix"`
+	// RateLimit is the number of requests allowed per minute, 0 means unlimited
+	RateLimit uint `bson:"rateLimit"`
+	// AllowedFields are the verification status fields returned to the partner
+	AllowedFields []string   `bson:"allowedFields"`
+	CreatedAt     time.Time  `bson:"createdAt"`
+	RevokedAt     *time.Time `bson:"revokedAt,omitempty"`
+}
+
+func (k *PartnerAPIKey) Revoked() bool {
+	return k.RevokedAt != nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VerificationSession tracks an iDenfy verification session until its result is processed,
+// so that sessions whose webhook was lost can be reconciled from the iDenfy API
+type VerificationSession struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty"`
+	ScanRef  string             `bson:"scanRef"`
+	ClientID string             `bson:"clientId"`
+	// IdenfySuffix is the suffix of the iDenfy clientId, it tells the network that created the session
+	IdenfySuffix string    `bson:"idenfySuffix"`
+	CreatedAt    time.Time `bson:"createdAt"`
+	// NextCheckAt is when the reconciler polls iDenfy for the session result, if it's still unresolved
+	NextCheckAt time.Time  `bson:"nextCheckAt"`
+	Checks      uint       `bson:"checks"`
+	ResolvedAt  *time.Time `bson:"resolvedAt,omitempty"`
+	Overall     Overall    `bson:"overall,omitempty"`
+	// ExpiresAt is when the session is no longer reconciled and is deleted
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// IdenfyClientID returns the iDenfy clientId of the session, the client ID with the network suffix
+func (s *VerificationSession) IdenfyClientID() string {
+	return s.ClientID + ":" + s.IdenfySuffix
+}
+
+// SessionStatus is the status of a verification session returned by the iDenfy status API
+type SessionStatus struct {
+	ScanRef          string            `json:"scanRef"`
+	ClientID         string            `json:"clientId"`
+	Status           Overall           `json:"status"`
+	SuspicionReasons []SuspicionReason `json:"suspicionReasons"`
+	DenyReasons      []string          `json:"denyReasons"`
+	FraudTags        []string          `json:"fraudTags"`
+	MismatchTags     []string          `json:"mismatchTags"`
+	AutoFace         string            `json:"autoFace,omitempty"`
+	ManualFace       string            `json:"manualFace,omitempty"`
+	AutoDocument     string            `json:"autoDocument,omitempty"`
+	ManualDocument   string            `json:"manualDocument,omitempty"`
+}
+
+// Final tells whether the session has a result that won't change, as sent by the verification webhook
+func (s SessionStatus) Final() bool {
+	return s.Status.Final()
+}
+
+// Final tells whether the status is the result of a finished session, rather than of a session in progress or in review
+func (o Overall) Final() bool {
+	switch o {
+	case OverallApproved, OverallDenied, OverallSuspected, OverallExpired:
+		return true
+	default:
+		return false
+	}
+}
+
+// Verification builds the verification result of the session, in the shape of the verification webhook
+func (s SessionStatus) Verification(data PersonData) Verification {
+	final := s.Final()
+	overall := s.Status
+	return Verification{
+		Final: &final,
+		Status: Status{
+			Overall:          &overall,
+			SuspicionReasons: s.SuspicionReasons,
+			DenyReasons:      s.DenyReasons,
+			FraudTags:        s.FraudTags,
+			MismatchTags:     s.MismatchTags,
+			AutoFace:         s.AutoFace,
+			ManualFace:       s.ManualFace,
+			AutoDocument:     s.AutoDocument,
+			ManualDocument:   s.ManualDocument,
+		},
+		Data:      data,
+		IdenfyRef: s.ScanRef,
+		ClientID:  s.ClientID,
+	}
+}
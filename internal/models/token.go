@@ -39,6 +39,9 @@ type Token struct {
 	UtilityBill      bool                `bson:"utilityBill"`
 	AdditionalSteps  interface{}         `bson:"additionalSteps"`
 	AdditionalData   interface{}         `bson:"additionalData"`
-	CreatedAt        time.Time           `bson:"createdAt"`
-	ExpiresAt        time.Time           `bson:"expiresAt"`
+	// Network and Namespace are the deployment the session was created by, see repository.Scope
+	Network   string    `bson:"network"`
+	Namespace string    `bson:"namespace"`
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
 }
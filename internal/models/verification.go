@@ -34,6 +34,12 @@ type Verification struct {
 	ExternalRef           string             `bson:"externalRef" json:"externalRef,omitempty"`
 	ManualAddress         string             `bson:"manualAddress" json:"manualAddress,omitempty"`
 	ManualAddressMatch    *bool              `bson:"manualAddressMatch" json:"manualAddressMatch,omitempty"`
+	Duplicate             *DuplicateIdentity `bson:"duplicate,omitempty" json:"-"`
+	// Network and Namespace are the deployment the verification belongs to, from the iDenfy clientId suffix
+	Network   string `bson:"network" json:"-"`
+	Namespace string `bson:"namespace" json:"-"`
+	// DocumentExpiryFlaggedAt is set once the client was flagged for re-verification because its document expires soon
+	DocumentExpiryFlaggedAt *time.Time `bson:"documentExpiryFlaggedAt,omitempty" json:"-"`
 }
 
 type Platform string
@@ -237,10 +243,16 @@ type ServiceStatus struct {
 }
 
 type VerificationOutcome struct {
-	Final     *bool   `bson:"final"`
-	ClientID  string  `bson:"clientId"`
-	IdenfyRef string  `bson:"idenfyRef"`
-	Outcome   Outcome `bson:"outcome"`
+	Final                  *bool      `bson:"final"`
+	ClientID               string     `bson:"clientId"`
+	IdenfyRef              string     `bson:"idenfyRef"`
+	Outcome                Outcome    `bson:"outcome"`
+	DocumentExpiresAt      *time.Time `bson:"documentExpiresAt"`
+	ReverificationRequired bool       `bson:"reverificationRequired"`
+	// TrustedNetwork is the linked network whose approved verification was accepted, empty for the network's own verifications
+	TrustedNetwork string `bson:"trustedNetwork,omitempty"`
+	// LinkedTo is the primary account whose approved verification the linked client inherits
+	LinkedTo string `bson:"linkedTo,omitempty"`
 }
 
 type Outcome string
@@ -0,0 +1,130 @@
+/*
+Package outcome contains the verification outcome policy for the application.
+This layer is responsible for deciding whether a stored iDenfy verification result grants the verified status, based on:
+- the overall status and how SUSPECTED results are treated
+- rejected suspicion reasons, fraud tags, AML, PEPs and sanctions statuses
+- the expiry date of the verified document
+*/
+package outcome
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+// DocExpiryLayout is the layout of the document dates sent by iDenfy
+const DocExpiryLayout = "2006-01-02"
+
+type Policy struct {
+	suspiciousOutcome         models.Outcome
+	expiredDocumentOutcome    models.Outcome
+	rejectedSuspicionReasons  []string
+	rejectedFraudTags         []string
+	rejectedAMLResultClasses  []string
+	rejectedPEPSStatuses      []string
+	rejectedSanctionsStatuses []string
+}
+
+// Evaluation is the result of evaluating a verification against the policy
+type Evaluation struct {
+	Outcome models.Outcome
+	// Reasons explains why the verification was rejected, empty if approved
+	Reasons []string
+	// DocumentExpiresAt is the parsed document expiry date, nil if unknown
+	DocumentExpiresAt *time.Time
+	DocumentExpired   bool
+}
+
+func (e Evaluation) Approved() bool {
+	return e.Outcome == models.OutcomeApproved
+}
+
+func New(config *config.Verification) *Policy {
+	return &Policy{
+		suspiciousOutcome:         models.Outcome(config.SuspiciousVerificationOutcome),
+		expiredDocumentOutcome:    models.Outcome(config.ExpiredDocumentOutcome),
+		rejectedSuspicionReasons:  normalize(config.RejectedSuspicionReasons),
+		rejectedFraudTags:         normalize(config.RejectedFraudTags),
+		rejectedAMLResultClasses:  normalize(config.RejectedAMLResultClasses),
+		rejectedPEPSStatuses:      normalize(config.RejectedPEPSStatuses),
+		rejectedSanctionsStatuses: normalize(config.RejectedSanctionsStatuses),
+	}
+}
+
+// Evaluate returns the outcome of the verification at the given time
+func (p *Policy) Evaluate(verification *models.Verification, now time.Time) Evaluation {
+	evaluation := Evaluation{Outcome: models.OutcomeApproved}
+	evaluation.DocumentExpiresAt = ParseDocExpiry(verification.Data.DocExpiry)
+
+	status := verification.Status
+	switch {
+	case status.Overall == nil:
+		evaluation.reject("missing overall status")
+	case *status.Overall == models.OverallApproved:
+	case *status.Overall == models.OverallSuspected:
+		if p.suspiciousOutcome != models.OutcomeApproved {
+			evaluation.reject("overall status is SUSPECTED")
+		}
+	default:
+		evaluation.reject(fmt.Sprintf("overall status is %s", *status.Overall))
+	}
+
+	for _, reason := range status.SuspicionReasons {
+		if slices.Contains(p.rejectedSuspicionReasons, strings.ToUpper(string(reason))) {
+			evaluation.reject(fmt.Sprintf("suspicion reason %s", reason))
+		}
+	}
+	for _, tag := range status.FraudTags {
+		if slices.Contains(p.rejectedFraudTags, strings.ToUpper(tag)) {
+			evaluation.reject(fmt.Sprintf("fraud tag %s", tag))
+		}
+	}
+	if status.AMLResultClass != "" && slices.Contains(p.rejectedAMLResultClasses, strings.ToUpper(status.AMLResultClass)) {
+		evaluation.reject(fmt.Sprintf("AML result class %s", status.AMLResultClass))
+	}
+	if status.PEPSStatus != "" && slices.Contains(p.rejectedPEPSStatuses, strings.ToUpper(status.PEPSStatus)) {
+		evaluation.reject(fmt.Sprintf("PEPs status %s", status.PEPSStatus))
+	}
+	if status.SanctionsStatus != "" && slices.Contains(p.rejectedSanctionsStatuses, strings.ToUpper(status.SanctionsStatus)) {
+		evaluation.reject(fmt.Sprintf("sanctions status %s", status.SanctionsStatus))
+	}
+
+	// a document is valid until the end of its expiry day
+	if evaluation.DocumentExpiresAt != nil && !now.Before(evaluation.DocumentExpiresAt.AddDate(0, 0, 1)) {
+		evaluation.DocumentExpired = true
+		if p.expiredDocumentOutcome != models.OutcomeApproved {
+			evaluation.reject("document expired")
+		}
+	}
+	return evaluation
+}
+
+func (e *Evaluation) reject(reason string) {
+	e.Outcome = models.OutcomeRejected
+	e.Reasons = append(e.Reasons, reason)
+}
+
+// ParseDocExpiry parses the document expiry date, it returns nil if the date is missing or malformed
+func ParseDocExpiry(docExpiry string) *time.Time {
+	expiresAt, err := time.Parse(DocExpiryLayout, strings.TrimSpace(docExpiry))
+	if err != nil {
+		return nil
+	}
+	return &expiresAt
+}
+
+func normalize(values []string) []string {
+	normalized := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if value != "" {
+			normalized = append(normalized, value)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,195 @@
+package outcome
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+func newVerification(overall models.Overall) *models.Verification {
+	return &models.Verification{
+		Status: models.Status{Overall: &overall},
+		Data:   models.PersonData{DocExpiry: "2030-06-15"},
+	}
+}
+
+func TestPolicyEvaluate(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	defaultConfig := config.Verification{
+		SuspiciousVerificationOutcome: "APPROVED",
+		ExpiredDocumentOutcome:        "REJECTED",
+	}
+	strictConfig := config.Verification{
+		SuspiciousVerificationOutcome: "REJECTED",
+		ExpiredDocumentOutcome:        "APPROVED",
+		RejectedSuspicionReasons:      []string{"face_suspected"},
+		RejectedFraudTags:             []string{"DOC_SUSPECTED"},
+		RejectedAMLResultClasses:      []string{"SUSPECTED"},
+		RejectedPEPSStatuses:          []string{"MATCH"},
+		RejectedSanctionsStatuses:     []string{"MATCH"},
+	}
+	tests := []struct {
+		name            string
+		config          config.Verification
+		verification    func() *models.Verification
+		expectedOutcome models.Outcome
+		expectedExpired bool
+	}{
+		{
+			name:            "approved",
+			config:          defaultConfig,
+			verification:    func() *models.Verification { return newVerification(models.OverallApproved) },
+			expectedOutcome: models.OutcomeApproved,
+		},
+		{
+			name:            "denied",
+			config:          defaultConfig,
+			verification:    func() *models.Verification { return newVerification(models.OverallDenied) },
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:            "missing overall status",
+			config:          defaultConfig,
+			verification:    func() *models.Verification { return &models.Verification{} },
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:            "suspected approved by config",
+			config:          defaultConfig,
+			verification:    func() *models.Verification { return newVerification(models.OverallSuspected) },
+			expectedOutcome: models.OutcomeApproved,
+		},
+		{
+			name:            "suspected rejected by config",
+			config:          strictConfig,
+			verification:    func() *models.Verification { return newVerification(models.OverallSuspected) },
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:   "rejected suspicion reason",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Status.SuspicionReasons = []models.SuspicionReason{models.SuspicionFaceSuspected}
+				return verification
+			},
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:   "ignored suspicion reason",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Status.SuspicionReasons = []models.SuspicionReason{models.SuspicionDocMobilePhoto}
+				return verification
+			},
+			expectedOutcome: models.OutcomeApproved,
+		},
+		{
+			name:   "rejected fraud tag",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Status.FraudTags = []string{"DOC_SUSPECTED"}
+				return verification
+			},
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:   "rejected AML result class",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Status.AMLResultClass = "SUSPECTED"
+				return verification
+			},
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:   "rejected PEPs status",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Status.PEPSStatus = "MATCH"
+				return verification
+			},
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:   "rejected sanctions status",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Status.SanctionsStatus = "MATCH"
+				return verification
+			},
+			expectedOutcome: models.OutcomeRejected,
+		},
+		{
+			name:   "expired document rejected",
+			config: defaultConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Data.DocExpiry = "2025-12-31"
+				return verification
+			},
+			expectedOutcome: models.OutcomeRejected,
+			expectedExpired: true,
+		},
+		{
+			name:   "expired document approved by config",
+			config: strictConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Data.DocExpiry = "2025-12-31"
+				return verification
+			},
+			expectedOutcome: models.OutcomeApproved,
+			expectedExpired: true,
+		},
+		{
+			name:   "document valid until the end of its expiry day",
+			config: defaultConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Data.DocExpiry = "2026-01-01"
+				return verification
+			},
+			expectedOutcome: models.OutcomeApproved,
+		},
+		{
+			name:   "malformed document expiry is ignored",
+			config: defaultConfig,
+			verification: func() *models.Verification {
+				verification := newVerification(models.OverallApproved)
+				verification.Data.DocExpiry = "YYYY-MM-DD"
+				return verification
+			},
+			expectedOutcome: models.OutcomeApproved,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := New(&tt.config)
+			evaluation := policy.Evaluate(tt.verification(), now)
+			assert.Equal(t, tt.expectedOutcome, evaluation.Outcome, "reasons: %v", evaluation.Reasons)
+			assert.Equal(t, tt.expectedExpired, evaluation.DocumentExpired)
+			if tt.expectedOutcome == models.OutcomeRejected {
+				assert.NotEmpty(t, evaluation.Reasons)
+			} else {
+				assert.Empty(t, evaluation.Reasons)
+			}
+		})
+	}
+}
+
+func TestParseDocExpiry(t *testing.T) {
+	expiresAt := ParseDocExpiry("2030-06-15")
+	assert.NotNil(t, expiresAt)
+	assert.Equal(t, time.Date(2030, 6, 15, 0, 0, 0, 0, time.UTC), *expiresAt)
+	assert.Nil(t, ParseDocExpiry(""))
+	assert.Nil(t, ParseDocExpiry("15/06/2030"))
+}
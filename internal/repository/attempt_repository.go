@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoAttemptRepository struct {
+	collection *mongo.Collection
+	scope      Scope
+	logger     *slog.Logger
+}
+
+func NewMongoAttemptRepository(db *mongo.Database, scope Scope, logger *slog.Logger) AttemptRepository {
+	repo := &MongoAttemptRepository{
+		collection: db.Collection("verification_attempts"),
+		scope:      scope,
+		logger:     logger,
+	}
+	return repo
+}
+
+func (r *MongoAttemptRepository) GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	var attempts models.VerificationAttempts
+	err := r.collection.FindOne(ctx, r.scope.filter(bson.M{"clientId": clientID})).Decode(&attempts)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+func (r *MongoAttemptRepository) RecordAttempt(ctx context.Context, clientID string, scanRef string) error {
+	update := bson.M{
+		"$inc": bson.M{"count": 1},
+		"$set": bson.M{"lastScanRef": scanRef, "lastAttemptAt": time.Now()},
+	}
+	_, err := r.collection.UpdateOne(ctx, r.scope.filter(bson.M{"clientId": clientID}), update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *MongoAttemptRepository) RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error {
+	update := bson.M{"$set": bson.M{"lastDeniedAt": deniedAt}}
+	_, err := r.collection.UpdateOne(ctx, r.scope.filter(bson.M{"clientId": clientID}), update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *MongoAttemptRepository) ResetAttempts(ctx context.Context, clientID string) error {
+	update := bson.M{
+		"$set":   bson.M{"count": 0, "resetAt": time.Now()},
+		"$unset": bson.M{"lastDeniedAt": ""},
+	}
+	_, err := r.collection.UpdateOne(ctx, r.scope.filter(bson.M{"clientId": clientID}), update)
+	return err
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the embedded database, the *_by_* buckets index the records by key prefix
+var (
+	TOKENS_BUCKET                           = []byte("tokens")
+	TOKENS_BY_SCAN_REF_BUCKET               = []byte("tokens_by_scan_ref")
+	VERIFICATIONS_BUCKET                    = []byte("verifications")
+	VERIFICATIONS_BY_CLIENT_ID_BUCKET       = []byte("verifications_by_client_id")
+	VERIFICATIONS_BY_SCAN_REF_BUCKET        = []byte("verifications_by_scan_ref")
+	RATE_LIMITS_BUCKET                      = []byte("rate_limits")
+	ATTEMPTS_BUCKET                         = []byte("verification_attempts")
+	FINGERPRINTS_BUCKET                     = []byte("identity_fingerprints")
+	CHALLENGE_NONCES_BUCKET                 = []byte("challenge_nonces")
+	LOCKS_BUCKET                            = []byte("locks")
+	PARTNER_KEYS_BUCKET                     = []byte("partner_api_keys")
+	PARTNER_KEYS_BY_HASH_BUCKET             = []byte("partner_api_keys_by_hash")
+	PARTNER_KEY_USAGE_BUCKET                = []byte("partner_api_key_usage")
+	CONSENT_GRANTS_BUCKET                   = []byte("consent_grants")
+	CONSENT_GRANTS_BY_CLIENT_ID_BUCKET      = []byte("consent_grants_by_client_id")
+	CONSENT_ACCESSES_BUCKET                 = []byte("consent_accesses")
+	ACCOUNT_LINKS_BUCKET                    = []byte("account_links")
+	ACCOUNT_LINKS_BY_CLIENT_ID_BUCKET       = []byte("account_links_by_client_id")
+	ACCOUNT_LINK_EVENTS_BUCKET              = []byte("account_link_events")
+	ACCOUNT_LINK_EVENTS_BY_CLIENT_ID_BUCKET = []byte("account_link_events_by_client_id")
+	VERIFICATION_SESSIONS_BUCKET            = []byte("verification_sessions")
+	boltBuckets                             = [][]byte{
+		TOKENS_BUCKET, TOKENS_BY_SCAN_REF_BUCKET, VERIFICATIONS_BUCKET, VERIFICATIONS_BY_CLIENT_ID_BUCKET, VERIFICATIONS_BY_SCAN_REF_BUCKET, RATE_LIMITS_BUCKET,
+		ATTEMPTS_BUCKET, FINGERPRINTS_BUCKET, CHALLENGE_NONCES_BUCKET, LOCKS_BUCKET, PARTNER_KEYS_BUCKET, PARTNER_KEYS_BY_HASH_BUCKET, PARTNER_KEY_USAGE_BUCKET,
+		CONSENT_GRANTS_BUCKET, CONSENT_GRANTS_BY_CLIENT_ID_BUCKET, CONSENT_ACCESSES_BUCKET, ACCOUNT_LINKS_BUCKET, ACCOUNT_LINKS_BY_CLIENT_ID_BUCKET,
+		ACCOUNT_LINK_EVENTS_BUCKET, ACCOUNT_LINK_EVENTS_BY_CLIENT_ID_BUCKET, VERIFICATION_SESSIONS_BUCKET,
+	}
+	boltKeySeparator = []byte{0}
+)
+
+// NewBoltDB opens the embedded database file and creates its buckets. The file is locked by a single process.
+func NewBoltDB(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("opening embedded database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating embedded database buckets: %w", err)
+	}
+	return db, nil
+}
+
+// key prefixes the key with the scope, so each scope has its own key space
+func (s Scope) key(value string) []byte {
+	return indexKey([]byte(s.Network), []byte(s.Namespace), []byte(value))
+}
+
+// contains tells whether the record of the given network and namespace belongs to the scope
+func (s Scope) contains(network string, namespace string) bool {
+	return s.Network == network && s.Namespace == namespace
+}
+
+// indexKey joins the parts of an index key, the first part being the indexed value
+func indexKey(parts ...[]byte) []byte {
+	return bytes.Join(parts, boltKeySeparator)
+}
+
+// indexPrefix is the prefix of the index keys of the value
+func indexPrefix(value string) []byte {
+	return append([]byte(value), boltKeySeparator...)
+}
+
+// timeKey encodes a time so that keys sort chronologically
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+// scanPrefix calls fn with the keys of the bucket starting with prefix, in order
+func scanPrefix(bucket *bolt.Bucket, prefix []byte, fn func(key []byte) error) error {
+	cursor := bucket.Cursor()
+	for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getDocument decodes the document of the key into model, it returns false if the key doesn't exist
+func getDocument(bucket *bolt.Bucket, key []byte, model interface{}) (bool, error) {
+	document := bucket.Get(key)
+	if document == nil {
+		return false, nil
+	}
+	return true, fromDocument(document, model)
+}
+
+func putDocument(bucket *bolt.Bucket, key []byte, model interface{}) error {
+	document, err := toDocument(model)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, document)
+}
+
+// indexedDocuments decodes the documents referenced by the index keys starting with prefix
+func indexedDocuments[T any](index *bolt.Bucket, bucket *bolt.Bucket, prefix []byte) ([]T, error) {
+	models := []T{}
+	err := scanPrefix(index, prefix, func(key []byte) error {
+		var model T
+		found, err := getDocument(bucket, index.Get(key), &model)
+		if err != nil || !found {
+			return err
+		}
+		models = append(models, model)
+		return nil
+	})
+	return models, err
+}
+
+// deleteExpired deletes the documents of the bucket for which expired returns true
+func deleteExpired[T any](bucket *bolt.Bucket, expired func(model *T) bool) (int64, error) {
+	keys := [][]byte{}
+	err := bucket.ForEach(func(key, document []byte) error {
+		var model T
+		if err := fromDocument(document, &model); err != nil {
+			return err
+		}
+		if expired(&model) {
+			// ForEach reuses the key, it is copied to be deleted after the iteration
+			keys = append(keys, append([]byte{}, key...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// keys can't be deleted while iterating
+	for _, key := range keys {
+		if err := bucket.Delete(key); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(keys)), nil
+}
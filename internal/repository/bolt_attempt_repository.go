@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltAttemptRepository stores the attempts by scope and clientId
+type BoltAttemptRepository struct {
+	db     *bolt.DB
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewBoltAttemptRepository(db *bolt.DB, scope Scope, logger *slog.Logger) AttemptRepository {
+	return &BoltAttemptRepository{
+		db:     db,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *BoltAttemptRepository) GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	var attempts models.VerificationAttempts
+	var found bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getDocument(tx.Bucket(ATTEMPTS_BUCKET), r.scope.key(clientID), &attempts)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+func (r *BoltAttemptRepository) RecordAttempt(ctx context.Context, clientID string, scanRef string) error {
+	return r.update(clientID, true, func(attempts *models.VerificationAttempts) {
+		attempts.Count++
+		attempts.LastScanRef = scanRef
+		attempts.LastAttemptAt = time.Now()
+	})
+}
+
+func (r *BoltAttemptRepository) RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error {
+	return r.update(clientID, true, func(attempts *models.VerificationAttempts) {
+		attempts.LastDeniedAt = &deniedAt
+	})
+}
+
+func (r *BoltAttemptRepository) ResetAttempts(ctx context.Context, clientID string) error {
+	return r.update(clientID, false, func(attempts *models.VerificationAttempts) {
+		now := time.Now()
+		attempts.Count = 0
+		attempts.ResetAt = &now
+		attempts.LastDeniedAt = nil
+	})
+}
+
+// update applies fn to the attempts of the client, with upsert the attempts are created if missing as the MongoDB upserts do
+func (r *BoltAttemptRepository) update(clientID string, upsert bool, fn func(attempts *models.VerificationAttempts)) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ATTEMPTS_BUCKET)
+		var attempts models.VerificationAttempts
+		found, err := getDocument(bucket, r.scope.key(clientID), &attempts)
+		if err != nil {
+			return err
+		}
+		if !found {
+			if !upsert {
+				return nil
+			}
+			attempts = models.VerificationAttempts{ID: primitive.NewObjectID(), ClientID: clientID, Network: r.scope.Network, Namespace: r.scope.Namespace}
+		}
+		fn(&attempts)
+		return putDocument(bucket, r.scope.key(clientID), &attempts)
+	})
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltChallengeRepository stores the nonces by nonce
+type BoltChallengeRepository struct {
+	db     *bolt.DB
+	logger *slog.Logger
+}
+
+func NewBoltChallengeRepository(db *bolt.DB, logger *slog.Logger) ChallengeRepository {
+	return &BoltChallengeRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *BoltChallengeRepository) SaveNonce(ctx context.Context, nonce *models.ChallengeNonce) error {
+	if nonce.ID.IsZero() {
+		nonce.ID = primitive.NewObjectID()
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(CHALLENGE_NONCES_BUCKET)
+		if bucket.Get([]byte(nonce.Nonce)) != nil {
+			return ErrDuplicateKey
+		}
+		return putDocument(bucket, []byte(nonce.Nonce), nonce)
+	})
+}
+
+// ConsumeNonce atomically marks the nonce as used, it returns false if the nonce is unknown, expired or already used
+func (r *BoltChallengeRepository) ConsumeNonce(ctx context.Context, nonce string, clientID string, usedAt time.Time) (bool, error) {
+	consumed := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(CHALLENGE_NONCES_BUCKET)
+		var challengeNonce models.ChallengeNonce
+		found, err := getDocument(bucket, []byte(nonce), &challengeNonce)
+		if err != nil || !found || !challengeNonce.ExpiresAt.After(usedAt) || challengeNonce.UsedAt != nil {
+			return err
+		}
+		challengeNonce.UsedAt = &usedAt
+		challengeNonce.UsedBy = clientID
+		consumed = true
+		return putDocument(bucket, []byte(nonce), &challengeNonce)
+	})
+	if err != nil {
+		return false, err
+	}
+	return consumed, nil
+}
+
+// DeleteExpired deletes the nonces expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *BoltChallengeRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		deleted, err = deleteExpired(tx.Bucket(CHALLENGE_NONCES_BUCKET), func(nonce *models.ChallengeNonce) bool {
+			return !nonce.ExpiresAt.After(now)
+		})
+		return err
+	})
+	return deleted, err
+}
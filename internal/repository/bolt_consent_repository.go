@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltConsentRepository stores the grants by id, with an index by scope and clientId, and the accesses by grantId
+type BoltConsentRepository struct {
+	db     *bolt.DB
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewBoltConsentRepository(db *bolt.DB, scope Scope, logger *slog.Logger) ConsentRepository {
+	return &BoltConsentRepository{
+		db:     db,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *BoltConsentRepository) SaveGrant(ctx context.Context, grant *models.ConsentGrant) error {
+	if grant.ID.IsZero() {
+		grant.ID = primitive.NewObjectID()
+	}
+	grant.Network = r.scope.Network
+	grant.Namespace = r.scope.Namespace
+	id := []byte(grant.ID.Hex())
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := putDocument(tx.Bucket(CONSENT_GRANTS_BUCKET), id, grant); err != nil {
+			return err
+		}
+		return tx.Bucket(CONSENT_GRANTS_BY_CLIENT_ID_BUCKET).Put(indexKey(r.scope.key(grant.ClientID), id), id)
+	})
+}
+
+func (r *BoltConsentRepository) GetGrant(ctx context.Context, id primitive.ObjectID) (*models.ConsentGrant, error) {
+	var grant models.ConsentGrant
+	var found bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getDocument(tx.Bucket(CONSENT_GRANTS_BUCKET), []byte(id.Hex()), &grant)
+		return err
+	})
+	if err != nil || !found || !r.scope.contains(grant.Network, grant.Namespace) {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// ListGrants returns the grants of the client, the latest first
+func (r *BoltConsentRepository) ListGrants(ctx context.Context, clientID string) ([]models.ConsentGrant, error) {
+	var grants []models.ConsentGrant
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		grants, err = indexedDocuments[models.ConsentGrant](tx.Bucket(CONSENT_GRANTS_BY_CLIENT_ID_BUCKET), tx.Bucket(CONSENT_GRANTS_BUCKET), indexPrefix(string(r.scope.key(clientID))))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(grants, func(a, b models.ConsentGrant) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return grants, nil
+}
+
+// GetActiveGrant returns the latest grant of the client to the partner that is neither expired nor revoked
+func (r *BoltConsentRepository) GetActiveGrant(ctx context.Context, clientID string, partnerKeyID primitive.ObjectID, now time.Time) (*models.ConsentGrant, error) {
+	grants, err := r.ListGrants(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, grant := range grants {
+		if grant.PartnerKeyID == partnerKeyID && grant.Active(now) {
+			return &grant, nil
+		}
+	}
+	return nil, nil
+}
+
+// RevokeGrant revokes the grant of the client, it returns false if the grant doesn't exist or is already revoked
+func (r *BoltConsentRepository) RevokeGrant(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (bool, error) {
+	revoked := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		grants := tx.Bucket(CONSENT_GRANTS_BUCKET)
+		var grant models.ConsentGrant
+		found, err := getDocument(grants, []byte(id.Hex()), &grant)
+		if err != nil || !found || !r.scope.contains(grant.Network, grant.Namespace) || grant.ClientID != clientID || grant.RevokedAt != nil {
+			return err
+		}
+		grant.RevokedAt = &revokedAt
+		revoked = true
+		return putDocument(grants, []byte(id.Hex()), &grant)
+	})
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *BoltConsentRepository) LogAccess(ctx context.Context, access *models.ConsentAccess) error {
+	if access.ID.IsZero() {
+		access.ID = primitive.NewObjectID()
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return putDocument(tx.Bucket(CONSENT_ACCESSES_BUCKET), indexKey([]byte(access.GrantID.Hex()), []byte(access.ID.Hex())), access)
+	})
+}
+
+// ListAccesses returns the accesses under the grant, the latest first
+func (r *BoltConsentRepository) ListAccesses(ctx context.Context, grantID primitive.ObjectID) ([]models.ConsentAccess, error) {
+	accesses := []models.ConsentAccess{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(CONSENT_ACCESSES_BUCKET)
+		return scanPrefix(bucket, indexPrefix(grantID.Hex()), func(key []byte) error {
+			var access models.ConsentAccess
+			if _, err := getDocument(bucket, key, &access); err != nil {
+				return err
+			}
+			accesses = append(accesses, access)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(accesses, func(a, b models.ConsentAccess) int { return b.AccessedAt.Compare(a.AccessedAt) })
+	return accesses, nil
+}
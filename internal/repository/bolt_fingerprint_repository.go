@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltFingerprintRepository stores the fingerprints by scope and fingerprint
+type BoltFingerprintRepository struct {
+	db     *bolt.DB
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewBoltFingerprintRepository(db *bolt.DB, scope Scope, logger *slog.Logger) FingerprintRepository {
+	return &BoltFingerprintRepository{
+		db:     db,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *BoltFingerprintRepository) GetFingerprint(ctx context.Context, fingerprint string) (*models.IdentityFingerprint, error) {
+	var identityFingerprint models.IdentityFingerprint
+	var found bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		found, err = getDocument(tx.Bucket(FINGERPRINTS_BUCKET), r.scope.key(fingerprint), &identityFingerprint)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &identityFingerprint, nil
+}
+
+// SaveFingerprint saves the fingerprint, it fails with ErrDuplicateKey if the fingerprint is already saved
+func (r *BoltFingerprintRepository) SaveFingerprint(ctx context.Context, fingerprint *models.IdentityFingerprint) error {
+	fingerprint.ID = primitive.NewObjectID()
+	fingerprint.Network = r.scope.Network
+	fingerprint.Namespace = r.scope.Namespace
+	fingerprint.CreatedAt = time.Now()
+	if fingerprint.LinkedClientIDs == nil {
+		fingerprint.LinkedClientIDs = []string{}
+	}
+	key := r.scope.key(fingerprint.Fingerprint)
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(FINGERPRINTS_BUCKET)
+		if bucket.Get(key) != nil {
+			return ErrDuplicateKey
+		}
+		return putDocument(bucket, key, fingerprint)
+	})
+}
+
+func (r *BoltFingerprintRepository) LinkClient(ctx context.Context, fingerprint string, clientID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(FINGERPRINTS_BUCKET)
+		var identityFingerprint models.IdentityFingerprint
+		found, err := getDocument(bucket, r.scope.key(fingerprint), &identityFingerprint)
+		if err != nil || !found || slices.Contains(identityFingerprint.LinkedClientIDs, clientID) {
+			return err
+		}
+		identityFingerprint.LinkedClientIDs = append(identityFingerprint.LinkedClientIDs, clientID)
+		return putDocument(bucket, r.scope.key(fingerprint), &identityFingerprint)
+	})
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BoltLimiterStorage is a fiber.Storage of the rate limiters, each limiter has its own key prefix in the rate_limits bucket.
+// Values are prefixed with their expiry time in Unix nanoseconds, zero when they don't expire.
+type BoltLimiterStorage struct {
+	db     *bolt.DB
+	bucket string
+}
+
+func NewBoltLimiterStorage(db *bolt.DB, bucket string) *BoltLimiterStorage {
+	return &BoltLimiterStorage{db: db, bucket: bucket}
+}
+
+func (s *BoltLimiterStorage) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		entry := tx.Bucket(RATE_LIMITS_BUCKET).Get(s.key(key))
+		if entry == nil || isExpiredEntry(entry, time.Now()) {
+			return nil
+		}
+		// the entry is only valid during the transaction
+		value = append([]byte{}, entry[8:]...)
+		return nil
+	})
+	return value, err
+}
+
+// Set stores the value of the key, a zero expiration keeps it forever
+func (s *BoltLimiterStorage) Set(key string, value []byte, exp time.Duration) error {
+	if key == "" || len(value) == 0 {
+		return nil
+	}
+	entry := make([]byte, 8, 8+len(value))
+	if exp > 0 {
+		binary.BigEndian.PutUint64(entry, uint64(time.Now().Add(exp).UnixNano()))
+	}
+	entry = append(entry, value...)
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(RATE_LIMITS_BUCKET).Put(s.key(key), entry)
+	})
+}
+
+func (s *BoltLimiterStorage) Delete(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(RATE_LIMITS_BUCKET).Delete(s.key(key))
+	})
+}
+
+func (s *BoltLimiterStorage) Reset() error {
+	_, err := s.deleteWhere(func(entry []byte) bool { return true })
+	return err
+}
+
+// Close does nothing, the database is shared with the repositories and closed by the server
+func (s *BoltLimiterStorage) Close() error {
+	return nil
+}
+
+// DeleteExpired deletes the entries of the bucket expired at the given time
+func (s *BoltLimiterStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	return s.deleteWhere(func(entry []byte) bool { return isExpiredEntry(entry, now) })
+}
+
+func (s *BoltLimiterStorage) deleteWhere(match func(entry []byte) bool) (int64, error) {
+	var deleted int64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		rateLimits := tx.Bucket(RATE_LIMITS_BUCKET)
+		keys := [][]byte{}
+		err := scanPrefix(rateLimits, indexPrefix(s.bucket), func(key []byte) error {
+			if match(rateLimits.Get(key)) {
+				keys = append(keys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// keys can't be deleted while iterating
+		for _, key := range keys {
+			if err := rateLimits.Delete(key); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(keys))
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *BoltLimiterStorage) key(key string) []byte {
+	return indexKey([]byte(s.bucket), []byte(key))
+}
+
+func isExpiredEntry(entry []byte, now time.Time) bool {
+	expiresAt := binary.BigEndian.Uint64(entry[:8])
+	return expiresAt != 0 && expiresAt <= uint64(now.UnixNano())
+}
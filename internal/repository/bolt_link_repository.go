@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltLinkRepository stores the links and their events by id, with indexes by the scope and the clientId of both accounts
+type BoltLinkRepository struct {
+	db     *bolt.DB
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewBoltLinkRepository(db *bolt.DB, scope Scope, logger *slog.Logger) LinkRepository {
+	return &BoltLinkRepository{
+		db:     db,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+// SaveLink saves an active link, it fails with ErrDuplicateKey if the linked account already has an active link
+func (r *BoltLinkRepository) SaveLink(ctx context.Context, link *models.AccountLink) error {
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	link.Network = r.scope.Network
+	link.Namespace = r.scope.Namespace
+	id := []byte(link.ID.Hex())
+	return r.db.Update(func(tx *bolt.Tx) error {
+		// same as the partial unique index of MongoDB on the active links
+		if link.Active {
+			active, err := r.activeLink(tx, link.LinkedClientID)
+			if err != nil {
+				return err
+			}
+			if active != nil {
+				return ErrDuplicateKey
+			}
+		}
+		if err := putDocument(tx.Bucket(ACCOUNT_LINKS_BUCKET), id, link); err != nil {
+			return err
+		}
+		byClientID := tx.Bucket(ACCOUNT_LINKS_BY_CLIENT_ID_BUCKET)
+		if err := byClientID.Put(indexKey(r.scope.key(link.PrimaryClientID), id), id); err != nil {
+			return err
+		}
+		return byClientID.Put(indexKey(r.scope.key(link.LinkedClientID), id), id)
+	})
+}
+
+// GetActiveLink returns the active link of the linked account
+func (r *BoltLinkRepository) GetActiveLink(ctx context.Context, linkedClientID string) (*models.AccountLink, error) {
+	var link *models.AccountLink
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		link, err = r.activeLink(tx, linkedClientID)
+		return err
+	})
+	return link, err
+}
+
+func (r *BoltLinkRepository) CountActiveLinks(ctx context.Context, primaryClientID string) (int64, error) {
+	var count int64
+	err := r.db.View(func(tx *bolt.Tx) error {
+		links, err := r.clientLinks(tx, primaryClientID)
+		if err != nil {
+			return err
+		}
+		for _, link := range links {
+			if link.PrimaryClientID == primaryClientID && link.Active {
+				count++
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// ListLinks returns the links of the client, as primary or linked account, including the revoked ones
+func (r *BoltLinkRepository) ListLinks(ctx context.Context, clientID string) ([]models.AccountLink, error) {
+	var links []models.AccountLink
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		links, err = r.clientLinks(tx, clientID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(links, func(a, b models.AccountLink) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return links, nil
+}
+
+// RevokeLink revokes the active link of either of its accounts, it returns nil if the link doesn't exist or is already revoked
+func (r *BoltLinkRepository) RevokeLink(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (*models.AccountLink, error) {
+	var revoked *models.AccountLink
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		links := tx.Bucket(ACCOUNT_LINKS_BUCKET)
+		var link models.AccountLink
+		found, err := getDocument(links, []byte(id.Hex()), &link)
+		if err != nil || !found || !r.scope.contains(link.Network, link.Namespace) || !link.Active || link.PrimaryClientID != clientID && link.LinkedClientID != clientID {
+			return err
+		}
+		link.Active = false
+		link.RevokedAt = &revokedAt
+		link.RevokedBy = clientID
+		revoked = &link
+		return putDocument(links, []byte(id.Hex()), &link)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revoked, nil
+}
+
+func (r *BoltLinkRepository) LogEvent(ctx context.Context, event *models.AccountLinkEvent) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	event.Network = r.scope.Network
+	event.Namespace = r.scope.Namespace
+	id := []byte(event.ID.Hex())
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := putDocument(tx.Bucket(ACCOUNT_LINK_EVENTS_BUCKET), id, event); err != nil {
+			return err
+		}
+		byClientID := tx.Bucket(ACCOUNT_LINK_EVENTS_BY_CLIENT_ID_BUCKET)
+		if err := byClientID.Put(indexKey(r.scope.key(event.PrimaryClientID), id), id); err != nil {
+			return err
+		}
+		return byClientID.Put(indexKey(r.scope.key(event.LinkedClientID), id), id)
+	})
+}
+
+// ListEvents returns the audit events of the links of the client, as primary or linked account
+func (r *BoltLinkRepository) ListEvents(ctx context.Context, clientID string) ([]models.AccountLinkEvent, error) {
+	var events []models.AccountLinkEvent
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		events, err = indexedDocuments[models.AccountLinkEvent](tx.Bucket(ACCOUNT_LINK_EVENTS_BY_CLIENT_ID_BUCKET), tx.Bucket(ACCOUNT_LINK_EVENTS_BUCKET), indexPrefix(string(r.scope.key(clientID))))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(events, func(a, b models.AccountLinkEvent) int { return b.At.Compare(a.At) })
+	return events, nil
+}
+
+// clientLinks returns the links of the client in the scope, as primary or linked account
+func (r *BoltLinkRepository) clientLinks(tx *bolt.Tx, clientID string) ([]models.AccountLink, error) {
+	return indexedDocuments[models.AccountLink](tx.Bucket(ACCOUNT_LINKS_BY_CLIENT_ID_BUCKET), tx.Bucket(ACCOUNT_LINKS_BUCKET), indexPrefix(string(r.scope.key(clientID))))
+}
+
+func (r *BoltLinkRepository) activeLink(tx *bolt.Tx, linkedClientID string) (*models.AccountLink, error) {
+	links, err := r.clientLinks(tx, linkedClientID)
+	if err != nil {
+		return nil, err
+	}
+	for _, link := range links {
+		if link.LinkedClientID == linkedClientID && link.Active {
+			return &link, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltLease is the lease of a lock, in the shape of the MongoDB lock documents
+type boltLease struct {
+	Owner      string    `bson:"owner"`
+	AcquiredAt time.Time `bson:"acquiredAt"`
+	ExpiresAt  time.Time `bson:"expiresAt"`
+}
+
+// BoltLockRepository stores the leases by name. The embedded database is opened by a single instance,
+// the leases only serialize its concurrent requests.
+type BoltLockRepository struct {
+	db     *bolt.DB
+	logger *slog.Logger
+}
+
+func NewBoltLockRepository(db *bolt.DB, logger *slog.Logger) LockRepository {
+	return &BoltLockRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// AcquireLock takes the lease on name for owner until now+ttl if it is free or expired.
+// It returns false if another owner holds an unexpired lease.
+func (r *BoltLockRepository) AcquireLock(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	acquired := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(LOCKS_BUCKET)
+		now := time.Now()
+		var lease boltLease
+		found, err := getDocument(bucket, []byte(name), &lease)
+		if err != nil || found && lease.ExpiresAt.After(now) {
+			return err
+		}
+		acquired = true
+		return putDocument(bucket, []byte(name), &boltLease{Owner: owner, AcquiredAt: now, ExpiresAt: now.Add(ttl)})
+	})
+	if err != nil {
+		return false, err
+	}
+	return acquired, nil
+}
+
+// ReleaseLock frees the lease on name if it is still held by owner
+func (r *BoltLockRepository) ReleaseLock(ctx context.Context, name string, owner string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(LOCKS_BUCKET)
+		var lease boltLease
+		found, err := getDocument(bucket, []byte(name), &lease)
+		if err != nil || !found || lease.Owner != owner {
+			return err
+		}
+		return bucket.Delete([]byte(name))
+	})
+}
+
+// DeleteExpired deletes the leases expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *BoltLockRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		deleted, err = deleteExpired(tx.Bucket(LOCKS_BUCKET), func(lease *boltLease) bool {
+			return !lease.ExpiresAt.After(now)
+		})
+		return err
+	})
+	return deleted, err
+}
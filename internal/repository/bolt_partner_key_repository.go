@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// boltKeyUsage counts the requests of a partner key in a window, in the shape of the MongoDB usage documents
+type boltKeyUsage struct {
+	Count     uint      `bson:"count"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// BoltPartnerKeyRepository stores the keys by id, with an index by keyHash, and their usage by id and window
+type BoltPartnerKeyRepository struct {
+	db     *bolt.DB
+	logger *slog.Logger
+}
+
+func NewBoltPartnerKeyRepository(db *bolt.DB, logger *slog.Logger) PartnerKeyRepository {
+	return &BoltPartnerKeyRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *BoltPartnerKeyRepository) SaveKey(ctx context.Context, key *models.PartnerAPIKey) error {
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		byHash := tx.Bucket(PARTNER_KEYS_BY_HASH_BUCKET)
+		if byHash.Get([]byte(key.KeyHash)) != nil {
+			return ErrDuplicateKey
+		}
+		if err := putDocument(tx.Bucket(PARTNER_KEYS_BUCKET), []byte(key.ID.Hex()), key); err != nil {
+			return err
+		}
+		return byHash.Put([]byte(key.KeyHash), []byte(key.ID.Hex()))
+	})
+}
+
+func (r *BoltPartnerKeyRepository) GetKey(ctx context.Context, id primitive.ObjectID) (*models.PartnerAPIKey, error) {
+	return r.get(func(tx *bolt.Tx) []byte { return []byte(id.Hex()) })
+}
+
+func (r *BoltPartnerKeyRepository) GetKeyByHash(ctx context.Context, keyHash string) (*models.PartnerAPIKey, error) {
+	return r.get(func(tx *bolt.Tx) []byte { return tx.Bucket(PARTNER_KEYS_BY_HASH_BUCKET).Get([]byte(keyHash)) })
+}
+
+func (r *BoltPartnerKeyRepository) ListKeys(ctx context.Context) ([]models.PartnerAPIKey, error) {
+	keys := []models.PartnerAPIKey{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(PARTNER_KEYS_BUCKET).ForEach(func(id, document []byte) error {
+			var key models.PartnerAPIKey
+			if err := fromDocument(document, &key); err != nil {
+				return err
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(keys, func(a, b models.PartnerAPIKey) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return keys, nil
+}
+
+// RevokeKey revokes the key, it returns false if the key doesn't exist or is already revoked
+func (r *BoltPartnerKeyRepository) RevokeKey(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (bool, error) {
+	revoked := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		keys := tx.Bucket(PARTNER_KEYS_BUCKET)
+		var key models.PartnerAPIKey
+		found, err := getDocument(keys, []byte(id.Hex()), &key)
+		if err != nil || !found || key.Revoked() {
+			return err
+		}
+		key.RevokedAt = &revokedAt
+		revoked = true
+		return putDocument(keys, []byte(id.Hex()), &key)
+	})
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// IncrementUsage counts a request of the key in the given window and returns the number of requests in that window so far
+func (r *BoltPartnerKeyRepository) IncrementUsage(ctx context.Context, id primitive.ObjectID, window time.Time, expiresAt time.Time) (uint, error) {
+	var usage boltKeyUsage
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(PARTNER_KEY_USAGE_BUCKET)
+		key := indexKey([]byte(id.Hex()), timeKey(window))
+		found, err := getDocument(bucket, key, &usage)
+		if err != nil {
+			return err
+		}
+		if !found {
+			usage = boltKeyUsage{ExpiresAt: expiresAt}
+		}
+		usage.Count++
+		return putDocument(bucket, key, &usage)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return usage.Count, nil
+}
+
+// DeleteExpired deletes the usage counters expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *BoltPartnerKeyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		deleted, err = deleteExpired(tx.Bucket(PARTNER_KEY_USAGE_BUCKET), func(usage *boltKeyUsage) bool {
+			return !usage.ExpiresAt.After(now)
+		})
+		return err
+	})
+	return deleted, err
+}
+
+// get returns the key of the id returned by keyID, if any
+func (r *BoltPartnerKeyRepository) get(keyID func(tx *bolt.Tx) []byte) (*models.PartnerAPIKey, error) {
+	var key models.PartnerAPIKey
+	var found bool
+	err := r.db.View(func(tx *bolt.Tx) error {
+		id := keyID(tx)
+		if id == nil {
+			return nil
+		}
+		var err error
+		found, err = getDocument(tx.Bucket(PARTNER_KEYS_BUCKET), id, &key)
+		return err
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return &key, nil
+}
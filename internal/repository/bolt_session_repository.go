@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltSessionRepository stores the sessions by scanRef
+type BoltSessionRepository struct {
+	db     *bolt.DB
+	logger *slog.Logger
+}
+
+func NewBoltSessionRepository(db *bolt.DB, logger *slog.Logger) SessionRepository {
+	return &BoltSessionRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (r *BoltSessionRepository) SaveSession(ctx context.Context, session *models.VerificationSession) error {
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(VERIFICATION_SESSIONS_BUCKET)
+		if bucket.Get([]byte(session.ScanRef)) != nil {
+			return ErrDuplicateKey
+		}
+		return putDocument(bucket, []byte(session.ScanRef), session)
+	})
+}
+
+// ListDueSessions returns the unresolved sessions of the iDenfy suffix to check at the given time, the most overdue first.
+// The sessions are deleted once expired, the scan is bounded by the sessions of the reconciliation period.
+func (r *BoltSessionRepository) ListDueSessions(ctx context.Context, idenfySuffix string, now time.Time, limit int64) ([]models.VerificationSession, error) {
+	sessions := []models.VerificationSession{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(VERIFICATION_SESSIONS_BUCKET).ForEach(func(scanRef, document []byte) error {
+			var session models.VerificationSession
+			if err := fromDocument(document, &session); err != nil {
+				return err
+			}
+			if session.IdenfySuffix == idenfySuffix && isDueSession(&session, now) && session.ExpiresAt.After(now) {
+				sessions = append(sessions, session)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(sessions, func(a, b models.VerificationSession) int { return a.NextCheckAt.Compare(b.NextCheckAt) })
+	if int64(len(sessions)) > limit {
+		sessions = sessions[:limit]
+	}
+	return sessions, nil
+}
+
+// ClaimSession postpones the next check of a due session, it returns false if another request claimed or resolved it first
+func (r *BoltSessionRepository) ClaimSession(ctx context.Context, scanRef string, now time.Time, nextCheckAt time.Time) (bool, error) {
+	claimed := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(VERIFICATION_SESSIONS_BUCKET)
+		var session models.VerificationSession
+		found, err := getDocument(bucket, []byte(scanRef), &session)
+		if err != nil || !found || !isDueSession(&session, now) {
+			return err
+		}
+		session.NextCheckAt = nextCheckAt
+		session.Checks++
+		claimed = true
+		return putDocument(bucket, []byte(scanRef), &session)
+	})
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// ResolveSession records the result of the session, unknown sessions, such as the ones created before they were tracked, are ignored
+func (r *BoltSessionRepository) ResolveSession(ctx context.Context, scanRef string, overall models.Overall, resolvedAt time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(VERIFICATION_SESSIONS_BUCKET)
+		var session models.VerificationSession
+		found, err := getDocument(bucket, []byte(scanRef), &session)
+		if err != nil || !found || session.ResolvedAt != nil {
+			return err
+		}
+		session.ResolvedAt = &resolvedAt
+		session.Overall = overall
+		return putDocument(bucket, []byte(scanRef), &session)
+	})
+}
+
+// DeleteExpired deletes the sessions expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *BoltSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		deleted, err = deleteExpired(tx.Bucket(VERIFICATION_SESSIONS_BUCKET), func(session *models.VerificationSession) bool {
+			return !session.ExpiresAt.After(now)
+		})
+		return err
+	})
+	return deleted, err
+}
+
+func isDueSession(session *models.VerificationSession, now time.Time) bool {
+	return session.ResolvedAt == nil && !session.NextCheckAt.After(now)
+}
@@ -0,0 +1,524 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testScope = Scope{Network: "dev"}
+
+func newTestBoltDB(t *testing.T) *bolt.DB {
+	db, err := NewBoltDB(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestBoltTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestBoltDB(t)
+	repo := NewBoltTokenRepository(db, testScope, slog.Default())
+
+	require.NoError(t, repo.SaveToken(ctx, &models.Token{ClientID: "client", ScanRef: "scan-1", ExpiryTime: 60}))
+	token, err := repo.GetToken(ctx, "client")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "scan-1", token.ScanRef)
+	assert.Equal(t, "dev", token.Network)
+
+	// each scope has its own tokens
+	qa := NewBoltTokenRepository(db, Scope{Network: "qa"}, slog.Default())
+	token, err = qa.GetToken(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+	require.NoError(t, qa.SaveToken(ctx, &models.Token{ClientID: "client", ScanRef: "scan-qa", ExpiryTime: 60}))
+	require.NoError(t, qa.DeleteToken(ctx, "client", "scan-qa"))
+
+	// clientId and scanRef are unique while the token is valid
+	assert.ErrorIs(t, repo.SaveToken(ctx, &models.Token{ClientID: "client", ScanRef: "scan-2", ExpiryTime: 60}), ErrDuplicateKey)
+	assert.ErrorIs(t, repo.SaveToken(ctx, &models.Token{ClientID: "other", ScanRef: "scan-1", ExpiryTime: 60}), ErrDuplicateKey)
+
+	// a different scanRef doesn't delete the token
+	require.NoError(t, repo.DeleteToken(ctx, "client", "scan-2"))
+	token, err = repo.GetToken(ctx, "client")
+	require.NoError(t, err)
+	assert.NotNil(t, token)
+	require.NoError(t, repo.DeleteToken(ctx, "client", "scan-1"))
+	token, err = repo.GetToken(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	// expired tokens are ignored, replaced and cleaned up
+	require.NoError(t, repo.SaveToken(ctx, &models.Token{ClientID: "client", ScanRef: "scan-3", ExpiryTime: 0}))
+	token, err = repo.GetToken(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, token)
+	require.NoError(t, repo.SaveToken(ctx, &models.Token{ClientID: "other", ScanRef: "scan-4", ExpiryTime: 0}))
+	require.NoError(t, repo.SaveToken(ctx, &models.Token{ClientID: "client", ScanRef: "scan-5", ExpiryTime: 60}))
+	deleted, err := repo.(*BoltTokenRepository).DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	token, err = repo.GetToken(ctx, "client")
+	require.NoError(t, err)
+	require.NotNil(t, token)
+	assert.Equal(t, "scan-5", token.ScanRef)
+}
+
+func TestBoltVerificationRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestBoltDB(t)
+	repo := NewBoltVerificationRepository(db, testScope, slog.Default())
+	qa := NewBoltVerificationRepository(db, Scope{Network: "qa", Namespace: "ns"}, slog.Default())
+	approved := models.OverallApproved
+	denied := models.OverallDenied
+
+	verification, err := repo.GetVerification(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, verification)
+
+	require.NoError(t, repo.SaveVerification(ctx, &models.Verification{ClientID: "client", IdenfyRef: "scan-1", Status: models.Status{Overall: &denied}}))
+	require.NoError(t, repo.SaveVerification(ctx, &models.Verification{ClientID: "client", IdenfyRef: "scan-2", Status: models.Status{Overall: &approved}, Data: models.PersonData{DocExpiry: "2030-01-15"}}))
+	// a client ID prefixed by another one
+	require.NoError(t, qa.SaveVerification(ctx, &models.Verification{ClientID: "client", IdenfyRef: "scan-qa", Status: models.Status{Overall: &approved}, Data: models.PersonData{DocExpiry: "2030-01-10"}}))
+	require.NoError(t, repo.SaveVerification(ctx, &models.Verification{ClientID: "client2", IdenfyRef: "scan-3", Status: models.Status{Overall: &approved}, Data: models.PersonData{DocExpiry: "2031-01-01"}}))
+	// only the latest verification of a client is flagged, the renewed document doesn't expire
+	require.NoError(t, repo.SaveVerification(ctx, &models.Verification{ClientID: "client3", IdenfyRef: "scan-4", Status: models.Status{Overall: &approved}, Data: models.PersonData{DocExpiry: "2030-01-20"}}))
+	require.NoError(t, repo.SaveVerification(ctx, &models.Verification{ClientID: "client3", IdenfyRef: "scan-5", Status: models.Status{Overall: &approved}, Data: models.PersonData{DocExpiry: "2035-01-20"}}))
+
+	verification, err = repo.GetVerification(ctx, "client")
+	require.NoError(t, err)
+	require.NotNil(t, verification)
+	assert.Equal(t, "scan-2", verification.IdenfyRef)
+
+	verification, err = qa.GetVerification(ctx, "client")
+	require.NoError(t, err)
+	require.NotNil(t, verification)
+	assert.Equal(t, "scan-qa", verification.IdenfyRef)
+	assert.Equal(t, "ns", verification.Namespace)
+
+	verifications, err := repo.GetVerificationsByScanRefs(ctx, []string{"scan-1", "scan-3", "scan-qa", "unknown"})
+	require.NoError(t, err)
+	assert.Len(t, verifications, 2)
+
+	flaggedAt := time.Now().UTC().Truncate(time.Millisecond)
+	clientIDs, err := repo.FlagExpiringDocuments(ctx, "2030-01-01", "2030-01-31", flaggedAt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"client"}, clientIDs)
+	verification, err = repo.GetVerification(ctx, "client")
+	require.NoError(t, err)
+	require.NotNil(t, verification.DocumentExpiryFlaggedAt)
+	assert.True(t, flaggedAt.Equal(*verification.DocumentExpiryFlaggedAt))
+
+	// flagged verifications are not flagged again
+	clientIDs, err = repo.FlagExpiringDocuments(ctx, "2030-01-01", "2030-01-31", flaggedAt)
+	require.NoError(t, err)
+	assert.Empty(t, clientIDs)
+}
+
+func TestBoltLimiterStorage(t *testing.T) {
+	db := newTestBoltDB(t)
+	storage := NewBoltLimiterStorage(db, "ip_limit")
+	other := NewBoltLimiterStorage(db, "id_limit")
+
+	require.NoError(t, storage.Set("key", []byte("value"), time.Minute))
+	require.NoError(t, storage.Set("forever", []byte("value"), 0))
+	require.NoError(t, storage.Set("expired", []byte("value"), time.Nanosecond))
+	require.NoError(t, other.Set("key", []byte("other"), 0))
+
+	value, err := storage.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+	time.Sleep(time.Millisecond)
+	value, err = storage.Get("expired")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	deleted, err := storage.DeleteExpired(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// each limiter only resets its own entries
+	require.NoError(t, storage.Reset())
+	value, err = storage.Get("forever")
+	require.NoError(t, err)
+	assert.Nil(t, value)
+	value, err = other.Get("key")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("other"), value)
+}
+
+func TestBoltAttemptRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltAttemptRepository(newTestBoltDB(t), testScope, slog.Default())
+
+	attempts, err := repo.GetAttempts(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, attempts)
+
+	// resetting unknown attempts doesn't create them, unlike the other updates
+	require.NoError(t, repo.ResetAttempts(ctx, "client"))
+	attempts, err = repo.GetAttempts(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, attempts)
+
+	deniedAt := time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, repo.RecordDenial(ctx, "client", deniedAt))
+	require.NoError(t, repo.RecordAttempt(ctx, "client", "scan-1"))
+	require.NoError(t, repo.RecordAttempt(ctx, "client", "scan-2"))
+	attempts, err = repo.GetAttempts(ctx, "client")
+	require.NoError(t, err)
+	require.NotNil(t, attempts)
+	assert.Equal(t, uint(2), attempts.Count)
+	assert.Equal(t, "scan-2", attempts.LastScanRef)
+	require.NotNil(t, attempts.LastDeniedAt)
+	assert.True(t, deniedAt.Equal(*attempts.LastDeniedAt))
+
+	require.NoError(t, repo.ResetAttempts(ctx, "client"))
+	attempts, err = repo.GetAttempts(ctx, "client")
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), attempts.Count)
+	assert.Nil(t, attempts.LastDeniedAt)
+	assert.NotNil(t, attempts.ResetAt)
+}
+
+func TestBoltFingerprintRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltFingerprintRepository(newTestBoltDB(t), testScope, slog.Default())
+
+	require.NoError(t, repo.SaveFingerprint(ctx, &models.IdentityFingerprint{Fingerprint: "fp", ClientID: "client", ScanRef: "scan"}))
+	assert.ErrorIs(t, repo.SaveFingerprint(ctx, &models.IdentityFingerprint{Fingerprint: "fp", ClientID: "other"}), ErrDuplicateKey)
+	require.NoError(t, repo.LinkClient(ctx, "fp", "other"))
+	require.NoError(t, repo.LinkClient(ctx, "fp", "other"))
+	require.NoError(t, repo.LinkClient(ctx, "unknown", "other"))
+
+	fingerprint, err := repo.GetFingerprint(ctx, "fp")
+	require.NoError(t, err)
+	require.NotNil(t, fingerprint)
+	assert.Equal(t, "client", fingerprint.ClientID)
+	assert.Equal(t, []string{"other"}, fingerprint.LinkedClientIDs)
+	fingerprint, err = repo.GetFingerprint(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, fingerprint)
+}
+
+func TestBoltChallengeRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltChallengeRepository(newTestBoltDB(t), slog.Default())
+	now := time.Now()
+
+	require.NoError(t, repo.SaveNonce(ctx, &models.ChallengeNonce{Nonce: "nonce", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}))
+	require.NoError(t, repo.SaveNonce(ctx, &models.ChallengeNonce{Nonce: "expired", CreatedAt: now, ExpiresAt: now}))
+	assert.ErrorIs(t, repo.SaveNonce(ctx, &models.ChallengeNonce{Nonce: "nonce", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}), ErrDuplicateKey)
+
+	// a nonce is consumed once, before it expires
+	consumed, err := repo.ConsumeNonce(ctx, "nonce", "client", now)
+	require.NoError(t, err)
+	assert.True(t, consumed)
+	consumed, err = repo.ConsumeNonce(ctx, "nonce", "client", now)
+	require.NoError(t, err)
+	assert.False(t, consumed)
+	consumed, err = repo.ConsumeNonce(ctx, "expired", "client", now)
+	require.NoError(t, err)
+	assert.False(t, consumed)
+	consumed, err = repo.ConsumeNonce(ctx, "unknown", "client", now)
+	require.NoError(t, err)
+	assert.False(t, consumed)
+
+	deleted, err := repo.(*BoltChallengeRepository).DeleteExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestBoltLockRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltLockRepository(newTestBoltDB(t), slog.Default())
+
+	acquired, err := repo.AcquireLock(ctx, "lock", "owner", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = repo.AcquireLock(ctx, "lock", "other", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// only the owner releases the lease
+	require.NoError(t, repo.ReleaseLock(ctx, "lock", "other"))
+	acquired, err = repo.AcquireLock(ctx, "lock", "other", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	require.NoError(t, repo.ReleaseLock(ctx, "lock", "owner"))
+	acquired, err = repo.AcquireLock(ctx, "lock", "other", 0)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// an expired lease is taken over and cleaned up
+	acquired, err = repo.AcquireLock(ctx, "lock", "owner", 0)
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	deleted, err := repo.(*BoltLockRepository).DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestBoltPartnerKeyRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltPartnerKeyRepository(newTestBoltDB(t), slog.Default())
+	now := time.Now()
+
+	first := &models.PartnerAPIKey{Name: "first", KeyHash: "hash-1", CreatedAt: now}
+	require.NoError(t, repo.SaveKey(ctx, first))
+	require.NoError(t, repo.SaveKey(ctx, &models.PartnerAPIKey{Name: "second", KeyHash: "hash-2", CreatedAt: now.Add(time.Second)}))
+	assert.ErrorIs(t, repo.SaveKey(ctx, &models.PartnerAPIKey{Name: "third", KeyHash: "hash-1", CreatedAt: now}), ErrDuplicateKey)
+
+	key, err := repo.GetKeyByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	require.NotNil(t, key)
+	assert.Equal(t, first.ID, key.ID)
+	key, err = repo.GetKeyByHash(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, key)
+	keys, err := repo.ListKeys(ctx)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "first", keys[0].Name)
+
+	revoked, err := repo.RevokeKey(ctx, first.ID, now)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = repo.RevokeKey(ctx, first.ID, now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	key, err = repo.GetKey(ctx, first.ID)
+	require.NoError(t, err)
+	assert.True(t, key.Revoked())
+
+	// the usage is counted per window
+	window := now.Truncate(time.Minute)
+	for expected := uint(1); expected <= 2; expected++ {
+		count, err := repo.IncrementUsage(ctx, first.ID, window, now)
+		require.NoError(t, err)
+		assert.Equal(t, expected, count)
+	}
+	count, err := repo.IncrementUsage(ctx, first.ID, window.Add(time.Minute), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), count)
+	deleted, err := repo.(*BoltPartnerKeyRepository).DeleteExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestBoltConsentRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltConsentRepository(newTestBoltDB(t), testScope, slog.Default())
+	now := time.Now()
+	partner := primitive.NewObjectID()
+
+	older := &models.ConsentGrant{ClientID: "client", PartnerKeyID: partner, CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	latest := &models.ConsentGrant{ClientID: "client", PartnerKeyID: partner, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	expired := &models.ConsentGrant{ClientID: "client", PartnerKeyID: primitive.NewObjectID(), CreatedAt: now, ExpiresAt: now}
+	for _, grant := range []*models.ConsentGrant{older, latest, expired} {
+		require.NoError(t, repo.SaveGrant(ctx, grant))
+	}
+	require.NoError(t, repo.SaveGrant(ctx, &models.ConsentGrant{ClientID: "client2", PartnerKeyID: partner, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	grants, err := repo.ListGrants(ctx, "client")
+	require.NoError(t, err)
+	require.Len(t, grants, 3)
+	assert.Equal(t, older.ID, grants[2].ID)
+
+	grant, err := repo.GetActiveGrant(ctx, "client", partner, now)
+	require.NoError(t, err)
+	require.NotNil(t, grant)
+	assert.Equal(t, latest.ID, grant.ID)
+	grant, err = repo.GetActiveGrant(ctx, "client", expired.PartnerKeyID, now)
+	require.NoError(t, err)
+	assert.Nil(t, grant)
+
+	// only the client revokes its grants, once
+	revoked, err := repo.RevokeGrant(ctx, latest.ID, "client2", now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = repo.RevokeGrant(ctx, latest.ID, "client", now)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = repo.RevokeGrant(ctx, latest.ID, "client", now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	grant, err = repo.GetActiveGrant(ctx, "client", partner, now)
+	require.NoError(t, err)
+	require.NotNil(t, grant)
+	assert.Equal(t, older.ID, grant.ID)
+
+	require.NoError(t, repo.LogAccess(ctx, &models.ConsentAccess{GrantID: older.ID, AccessedAt: now.Add(-time.Minute)}))
+	require.NoError(t, repo.LogAccess(ctx, &models.ConsentAccess{GrantID: older.ID, AccessedAt: now}))
+	require.NoError(t, repo.LogAccess(ctx, &models.ConsentAccess{GrantID: latest.ID, AccessedAt: now}))
+	accesses, err := repo.ListAccesses(ctx, older.ID)
+	require.NoError(t, err)
+	require.Len(t, accesses, 2)
+	assert.WithinDuration(t, now, accesses[0].AccessedAt, time.Millisecond)
+}
+
+func TestBoltLinkRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltLinkRepository(newTestBoltDB(t), testScope, slog.Default())
+	now := time.Now()
+
+	link := &models.AccountLink{PrimaryClientID: "primary", LinkedClientID: "linked", Active: true, CreatedAt: now}
+	require.NoError(t, repo.SaveLink(ctx, link))
+	require.NoError(t, repo.SaveLink(ctx, &models.AccountLink{PrimaryClientID: "primary", LinkedClientID: "linked2", Active: true, CreatedAt: now.Add(time.Second)}))
+	// an account has a single active link
+	assert.ErrorIs(t, repo.SaveLink(ctx, &models.AccountLink{PrimaryClientID: "other", LinkedClientID: "linked", Active: true, CreatedAt: now}), ErrDuplicateKey)
+
+	active, err := repo.GetActiveLink(ctx, "linked")
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, link.ID, active.ID)
+	active, err = repo.GetActiveLink(ctx, "primary")
+	require.NoError(t, err)
+	assert.Nil(t, active)
+	count, err := repo.CountActiveLinks(ctx, "primary")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	revoked, err := repo.RevokeLink(ctx, link.ID, "other", now)
+	require.NoError(t, err)
+	assert.Nil(t, revoked)
+	revoked, err = repo.RevokeLink(ctx, link.ID, "linked", now)
+	require.NoError(t, err)
+	require.NotNil(t, revoked)
+	assert.False(t, revoked.Active)
+	assert.Equal(t, "linked", revoked.RevokedBy)
+	revoked, err = repo.RevokeLink(ctx, link.ID, "linked", now)
+	require.NoError(t, err)
+	assert.Nil(t, revoked)
+	count, err = repo.CountActiveLinks(ctx, "primary")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// the revoked link frees the account
+	require.NoError(t, repo.SaveLink(ctx, &models.AccountLink{PrimaryClientID: "other", LinkedClientID: "linked", Active: true, CreatedAt: now.Add(2 * time.Second)}))
+	links, err := repo.ListLinks(ctx, "linked")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, "other", links[0].PrimaryClientID)
+
+	require.NoError(t, repo.LogEvent(ctx, &models.AccountLinkEvent{LinkID: link.ID, PrimaryClientID: "primary", LinkedClientID: "linked", Action: models.AccountLinkCreated, At: now}))
+	require.NoError(t, repo.LogEvent(ctx, &models.AccountLinkEvent{LinkID: link.ID, PrimaryClientID: "primary", LinkedClientID: "linked", Action: models.AccountLinkRevoked, At: now.Add(time.Second)}))
+	events, err := repo.ListEvents(ctx, "primary")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.AccountLinkRevoked, events[0].Action)
+	events, err = repo.ListEvents(ctx, "other")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestBoltSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := NewBoltSessionRepository(newTestBoltDB(t), slog.Default())
+	now := time.Now()
+
+	sessions := []*models.VerificationSession{
+		{ScanRef: "later", IdenfySuffix: "devnet", NextCheckAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)},
+		{ScanRef: "overdue", IdenfySuffix: "devnet", NextCheckAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ScanRef: "not-due", IdenfySuffix: "devnet", NextCheckAt: now.Add(time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ScanRef: "other-network", IdenfySuffix: "qanet", NextCheckAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{ScanRef: "expired", IdenfySuffix: "devnet", NextCheckAt: now.Add(-time.Hour), ExpiresAt: now},
+	}
+	for _, session := range sessions {
+		require.NoError(t, repo.SaveSession(ctx, session))
+	}
+	assert.ErrorIs(t, repo.SaveSession(ctx, &models.VerificationSession{ScanRef: "later"}), ErrDuplicateKey)
+
+	due, err := repo.ListDueSessions(ctx, "devnet", now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "overdue", due[0].ScanRef)
+	due, err = repo.ListDueSessions(ctx, "devnet", now, 1)
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	// a session is claimed once until its next check, and not after it is resolved
+	claimed, err := repo.ClaimSession(ctx, "overdue", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = repo.ClaimSession(ctx, "overdue", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	require.NoError(t, repo.ResolveSession(ctx, "later", models.OverallApproved, now))
+	claimed, err = repo.ClaimSession(ctx, "later", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	require.NoError(t, repo.ResolveSession(ctx, "unknown", models.OverallApproved, now))
+	due, err = repo.ListDueSessions(ctx, "devnet", now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+
+	deleted, err := repo.(*BoltSessionRepository).DeleteExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestBoltClientRecordsScope(t *testing.T) {
+	ctx := context.Background()
+	db := newTestBoltDB(t)
+	otherScope := Scope{Network: "qa"}
+	now := time.Now()
+
+	attempts := NewBoltAttemptRepository(db, testScope, slog.Default())
+	require.NoError(t, attempts.RecordAttempt(ctx, "client", "scan"))
+	counted, err := NewBoltAttemptRepository(db, otherScope, slog.Default()).GetAttempts(ctx, "client")
+	require.NoError(t, err)
+	assert.Nil(t, counted)
+
+	// a grant only gives access to the data of its network
+	consent := NewBoltConsentRepository(db, testScope, slog.Default())
+	otherConsent := NewBoltConsentRepository(db, otherScope, slog.Default())
+	grant := &models.ConsentGrant{ClientID: "client", PartnerKeyID: primitive.NewObjectID(), CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	require.NoError(t, consent.SaveGrant(ctx, grant))
+	active, err := otherConsent.GetActiveGrant(ctx, "client", grant.PartnerKeyID, now)
+	require.NoError(t, err)
+	assert.Nil(t, active)
+	found, err := otherConsent.GetGrant(ctx, grant.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	revoked, err := otherConsent.RevokeGrant(ctx, grant.ID, "client", now)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// an account can be linked on each network
+	links := NewBoltLinkRepository(db, testScope, slog.Default())
+	otherLinks := NewBoltLinkRepository(db, otherScope, slog.Default())
+	require.NoError(t, links.SaveLink(ctx, &models.AccountLink{PrimaryClientID: "primary", LinkedClientID: "linked", Active: true, CreatedAt: now}))
+	require.NoError(t, otherLinks.SaveLink(ctx, &models.AccountLink{PrimaryClientID: "other", LinkedClientID: "linked", Active: true, CreatedAt: now}))
+	link, err := otherLinks.GetActiveLink(ctx, "linked")
+	require.NoError(t, err)
+	require.NotNil(t, link)
+	assert.Equal(t, "other", link.PrimaryClientID)
+	count, err := otherLinks.CountActiveLinks(ctx, "primary")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// an identity can be verified once on each network
+	fingerprints := NewBoltFingerprintRepository(db, testScope, slog.Default())
+	otherFingerprints := NewBoltFingerprintRepository(db, otherScope, slog.Default())
+	require.NoError(t, fingerprints.SaveFingerprint(ctx, &models.IdentityFingerprint{Fingerprint: "fp", ClientID: "client"}))
+	fingerprint, err := otherFingerprints.GetFingerprint(ctx, "fp")
+	require.NoError(t, err)
+	assert.Nil(t, fingerprint)
+	require.NoError(t, otherFingerprints.SaveFingerprint(ctx, &models.IdentityFingerprint{Fingerprint: "fp", ClientID: "other"}))
+	require.NoError(t, otherFingerprints.LinkClient(ctx, "fp", "linked"))
+	fingerprint, err = fingerprints.GetFingerprint(ctx, "fp")
+	require.NoError(t, err)
+	require.NotNil(t, fingerprint)
+	assert.Equal(t, "client", fingerprint.ClientID)
+	assert.Empty(t, fingerprint.LinkedClientIDs)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltTokenRepository stores the tokens by scope and clientId, with an index by scanRef
+type BoltTokenRepository struct {
+	db     *bolt.DB
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewBoltTokenRepository(db *bolt.DB, scope Scope, logger *slog.Logger) TokenRepository {
+	return &BoltTokenRepository{
+		db:     db,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *BoltTokenRepository) SaveToken(ctx context.Context, token *models.Token) error {
+	token.ID = primitive.NewObjectID()
+	token.Network = r.scope.Network
+	token.Namespace = r.scope.Namespace
+	token.CreatedAt = time.Now()
+	token.ExpiresAt = token.CreatedAt.Add(time.Duration(token.ExpiryTime) * time.Second)
+	document, err := toDocument(token)
+	if err != nil {
+		return err
+	}
+	return r.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(TOKENS_BUCKET)
+		byScanRef := tx.Bucket(TOKENS_BY_SCAN_REF_BUCKET)
+		key := r.scope.key(token.ClientID)
+		// same as the unique clientId and scanRef indexes, expired tokens not cleaned up yet are replaced
+		// the index value is copied, it may not outlive the deletion of the token it references
+		scanRefKey := append([]byte{}, byScanRef.Get([]byte(token.ScanRef))...)
+		for _, existingKey := range [][]byte{key, scanRefKey} {
+			existing, err := getToken(tokens, existingKey)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				continue
+			}
+			if existing.ExpiresAt.After(token.CreatedAt) {
+				return ErrDuplicateKey
+			}
+			if err := deleteToken(tokens, byScanRef, existing); err != nil {
+				return err
+			}
+		}
+		if err := tokens.Put(key, document); err != nil {
+			return err
+		}
+		return byScanRef.Put([]byte(token.ScanRef), key)
+	})
+}
+
+func (r *BoltTokenRepository) GetToken(ctx context.Context, clientID string) (*models.Token, error) {
+	var token *models.Token
+	err := r.db.View(func(tx *bolt.Tx) error {
+		var err error
+		token, err = getToken(tx.Bucket(TOKENS_BUCKET), r.scope.key(clientID))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// expired tokens are deleted by the cleanup job, they are ignored until then
+	if token == nil || !token.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return token, nil
+}
+
+func (r *BoltTokenRepository) DeleteToken(ctx context.Context, clientID string, scanRef string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(TOKENS_BUCKET)
+		token, err := getToken(tokens, r.scope.key(clientID))
+		if err != nil || token == nil || token.ScanRef != scanRef {
+			return err
+		}
+		return deleteToken(tokens, tx.Bucket(TOKENS_BY_SCAN_REF_BUCKET), token)
+	})
+}
+
+// DeleteExpired deletes the tokens of all scopes expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *BoltTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		tokens := tx.Bucket(TOKENS_BUCKET)
+		expired := []*models.Token{}
+		err := tokens.ForEach(func(key, document []byte) error {
+			var token models.Token
+			if err := fromDocument(document, &token); err != nil {
+				return err
+			}
+			if !token.ExpiresAt.After(now) {
+				expired = append(expired, &token)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// keys can't be deleted while iterating
+		for _, token := range expired {
+			if err := deleteToken(tokens, tx.Bucket(TOKENS_BY_SCAN_REF_BUCKET), token); err != nil {
+				return err
+			}
+		}
+		deleted = int64(len(expired))
+		return nil
+	})
+	return deleted, err
+}
+
+func getToken(tokens *bolt.Bucket, key []byte) (*models.Token, error) {
+	if len(key) == 0 {
+		return nil, nil
+	}
+	document := tokens.Get(key)
+	if document == nil {
+		return nil, nil
+	}
+	var token models.Token
+	if err := fromDocument(document, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func deleteToken(tokens *bolt.Bucket, byScanRef *bolt.Bucket, token *models.Token) error {
+	key := Scope{Network: token.Network, Namespace: token.Namespace}.key(token.ClientID)
+	if err := tokens.Delete(key); err != nil {
+		return err
+	}
+	return byScanRef.Delete([]byte(token.ScanRef))
+}
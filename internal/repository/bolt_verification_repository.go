@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	bolt "go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BoltVerificationRepository stores the verifications by ID, indexed by scope, clientId and creation time and by scanRef
+type BoltVerificationRepository struct {
+	db     *bolt.DB
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewBoltVerificationRepository(db *bolt.DB, scope Scope, logger *slog.Logger) VerificationRepository {
+	return &BoltVerificationRepository{
+		db:     db,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *BoltVerificationRepository) SaveVerification(ctx context.Context, verification *models.Verification) error {
+	verification.ID = primitive.NewObjectID()
+	verification.Network = r.scope.Network
+	verification.Namespace = r.scope.Namespace
+	verification.CreatedAt = time.Now()
+	document, err := toDocument(verification)
+	if err != nil {
+		return err
+	}
+	id := []byte(verification.ID.Hex())
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(VERIFICATIONS_BUCKET).Put(id, document); err != nil {
+			return err
+		}
+		byClientID := indexKey(r.scope.key(verification.ClientID), timeKey(verification.CreatedAt), id)
+		if err := tx.Bucket(VERIFICATIONS_BY_CLIENT_ID_BUCKET).Put(byClientID, id); err != nil {
+			return err
+		}
+		return tx.Bucket(VERIFICATIONS_BY_SCAN_REF_BUCKET).Put(indexKey([]byte(verification.IdenfyRef), id), id)
+	})
+}
+
+func (r *BoltVerificationRepository) GetVerification(ctx context.Context, clientID string) (*models.Verification, error) {
+	var verification *models.Verification
+	err := r.db.View(func(tx *bolt.Tx) error {
+		// return the latest verification, the index keys of a client sort by creation time
+		var latest []byte
+		err := scanPrefix(tx.Bucket(VERIFICATIONS_BY_CLIENT_ID_BUCKET), indexPrefix(string(r.scope.key(clientID))), func(key []byte) error {
+			latest = key
+			return nil
+		})
+		if err != nil || latest == nil {
+			return err
+		}
+		verification, err = getVerification(tx, tx.Bucket(VERIFICATIONS_BY_CLIENT_ID_BUCKET).Get(latest))
+		return err
+	})
+	return verification, err
+}
+
+func (r *BoltVerificationRepository) GetVerificationsByScanRefs(ctx context.Context, scanRefs []string) ([]models.Verification, error) {
+	verifications := []models.Verification{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		byScanRef := tx.Bucket(VERIFICATIONS_BY_SCAN_REF_BUCKET)
+		for _, scanRef := range scanRefs {
+			err := scanPrefix(byScanRef, indexPrefix(scanRef), func(key []byte) error {
+				verification, err := getVerification(tx, byScanRef.Get(key))
+				// scanRefs are unique across scopes, the index isn't scoped
+				if err != nil || verification == nil || !r.scope.contains(verification.Network, verification.Namespace) {
+					return err
+				}
+				verifications = append(verifications, *verification)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verifications, nil
+}
+
+// FlagExpiringDocuments flags the latest verification of each client when it is approved, its document expires between
+// from and until (YYYY-MM-DD, inclusive) and it was not flagged yet. It returns the client IDs of the flagged verifications.
+// The document expiry isn't indexed, the job runs daily and scans all verifications.
+func (r *BoltVerificationRepository) FlagExpiringDocuments(ctx context.Context, from string, until string, flaggedAt time.Time) ([]string, error) {
+	clientIDs := []string{}
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		verifications := tx.Bucket(VERIFICATIONS_BUCKET)
+		type latestVerification struct {
+			id           []byte
+			verification models.Verification
+		}
+		latest := map[string]*latestVerification{}
+		err := verifications.ForEach(func(id, document []byte) error {
+			var verification models.Verification
+			if err := fromDocument(document, &verification); err != nil {
+				return err
+			}
+			if !r.scope.contains(verification.Network, verification.Namespace) {
+				return nil
+			}
+			key := verification.Network + "/" + verification.Namespace + "/" + verification.ClientID
+			current, ok := latest[key]
+			// the object IDs order the verifications created at the same time
+			if ok && (verification.CreatedAt.Before(current.verification.CreatedAt) ||
+				verification.CreatedAt.Equal(current.verification.CreatedAt) && verification.ID.Hex() < current.verification.ID.Hex()) {
+				return nil
+			}
+			// ForEach reuses the key, it is copied to be written after the iteration
+			latest[key] = &latestVerification{id: append([]byte{}, id...), verification: verification}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		// keys can't be updated while iterating
+		for _, current := range latest {
+			if !isExpiringDocument(&current.verification, from, until) {
+				continue
+			}
+			current.verification.DocumentExpiryFlaggedAt = &flaggedAt
+			document, err := toDocument(&current.verification)
+			if err != nil {
+				return err
+			}
+			if err := verifications.Put(current.id, document); err != nil {
+				return err
+			}
+			clientIDs = append(clientIDs, current.verification.ClientID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clientIDs, nil
+}
+
+func isExpiringDocument(verification *models.Verification, from string, until string) bool {
+	if verification.DocumentExpiryFlaggedAt != nil || verification.Status.Overall == nil {
+		return false
+	}
+	if *verification.Status.Overall != models.OverallApproved && *verification.Status.Overall != models.OverallSuspected {
+		return false
+	}
+	return verification.Data.DocExpiry >= from && verification.Data.DocExpiry <= until
+}
+
+func getVerification(tx *bolt.Tx, id []byte) (*models.Verification, error) {
+	document := tx.Bucket(VERIFICATIONS_BUCKET).Get(id)
+	if document == nil {
+		return nil, nil
+	}
+	var verification models.Verification
+	if err := fromDocument(document, &verification); err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
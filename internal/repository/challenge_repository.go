@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoChallengeRepository struct {
+	collection *mongo.Collection
+	logger     *slog.Logger
+}
+
+func NewMongoChallengeRepository(db *mongo.Database, logger *slog.Logger) ChallengeRepository {
+	repo := &MongoChallengeRepository{
+		collection: db.Collection("challenge_nonces"),
+		logger:     logger,
+	}
+	return repo
+}
+
+func (r *MongoChallengeRepository) SaveNonce(ctx context.Context, nonce *models.ChallengeNonce) error {
+	_, err := r.collection.InsertOne(ctx, nonce)
+	return err
+}
+
+// ConsumeNonce atomically marks the nonce as used, it returns false if the nonce is unknown, expired or already used
+func (r *MongoChallengeRepository) ConsumeNonce(ctx context.Context, nonce string, clientID string, usedAt time.Time) (bool, error) {
+	filter := bson.M{
+		"nonce":     nonce,
+		"expiresAt": bson.M{"$gt": usedAt},
+		"usedAt":    bson.M{"$exists": false},
+	}
+	update := bson.M{"$set": bson.M{"usedAt": usedAt, "usedBy": clientID}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoConsentRepository struct {
+	grants   *mongo.Collection
+	accesses *mongo.Collection
+	scope    Scope
+	logger   *slog.Logger
+}
+
+// NewMongoConsentRepository returns the grants of the scope, the accesses are reached through their grant
+func NewMongoConsentRepository(db *mongo.Database, scope Scope, logger *slog.Logger) ConsentRepository {
+	repo := &MongoConsentRepository{
+		grants:   db.Collection("consent_grants"),
+		accesses: db.Collection("consent_accesses"),
+		scope:    scope,
+		logger:   logger,
+	}
+	return repo
+}
+
+func (r *MongoConsentRepository) SaveGrant(ctx context.Context, grant *models.ConsentGrant) error {
+	grant.Network = r.scope.Network
+	grant.Namespace = r.scope.Namespace
+	result, err := r.grants.InsertOne(ctx, grant)
+	if err != nil {
+		return err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		grant.ID = id
+	}
+	return nil
+}
+
+func (r *MongoConsentRepository) GetGrant(ctx context.Context, id primitive.ObjectID) (*models.ConsentGrant, error) {
+	var grant models.ConsentGrant
+	err := r.grants.FindOne(ctx, r.scope.filter(bson.M{"_id": id})).Decode(&grant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+func (r *MongoConsentRepository) ListGrants(ctx context.Context, clientID string) ([]models.ConsentGrant, error) {
+	cursor, err := r.grants.Find(ctx, r.scope.filter(bson.M{"clientId": clientID}), options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	grants := []models.ConsentGrant{}
+	if err := cursor.All(ctx, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// GetActiveGrant returns the latest grant of the client to the partner that is neither expired nor revoked
+func (r *MongoConsentRepository) GetActiveGrant(ctx context.Context, clientID string, partnerKeyID primitive.ObjectID, now time.Time) (*models.ConsentGrant, error) {
+	filter := r.scope.filter(bson.M{
+		"clientId":     clientID,
+		"partnerKeyId": partnerKeyID,
+		"expiresAt":    bson.M{"$gt": now},
+		"revokedAt":    bson.M{"$exists": false},
+	})
+	var grant models.ConsentGrant
+	err := r.grants.FindOne(ctx, filter, options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}})).Decode(&grant)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// RevokeGrant revokes the grant of the client, it returns false if the grant doesn't exist or is already revoked
+func (r *MongoConsentRepository) RevokeGrant(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (bool, error) {
+	filter := r.scope.filter(bson.M{"_id": id, "clientId": clientID, "revokedAt": bson.M{"$exists": false}})
+	result, err := r.grants.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": revokedAt}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+func (r *MongoConsentRepository) LogAccess(ctx context.Context, access *models.ConsentAccess) error {
+	_, err := r.accesses.InsertOne(ctx, access)
+	return err
+}
+
+func (r *MongoConsentRepository) ListAccesses(ctx context.Context, grantID primitive.ObjectID) ([]models.ConsentAccess, error) {
+	cursor, err := r.accesses.Find(ctx, bson.M{"grantId": grantID}, options.Find().SetSort(bson.D{{Key: "accessedAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	accesses := []models.ConsentAccess{}
+	if err := cursor.All(ctx, &accesses); err != nil {
+		return nil, err
+	}
+	return accesses, nil
+}
@@ -0,0 +1,12 @@
+package repository
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// toDocument encodes a model as the relaxed extended JSON of its MongoDB document, so every backend stores the same shape
+func toDocument(model interface{}) ([]byte, error) {
+	return bson.MarshalExtJSON(model, false, false)
+}
+
+func fromDocument(document []byte, model interface{}) error {
+	return bson.UnmarshalExtJSON(document, false, model)
+}
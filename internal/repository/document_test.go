@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	approved := models.OverallApproved
+	passport := models.PASSPORT
+	final := true
+	flaggedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	verification := models.Verification{
+		ID:        primitive.NewObjectID(),
+		CreatedAt: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Final:     &final,
+		Status:    models.Status{Overall: &approved, FraudTags: []string{"TAG"}},
+		Data:      models.PersonData{DocNumber: "AB123", DocType: &passport, DocExpiry: "2030-01-01"},
+		IdenfyRef: "scan-ref",
+		ClientID:  "client",
+		Duplicate: &models.DuplicateIdentity{ClientID: "other", Source: models.DuplicateSourceFingerprint},
+		// fields hidden from the JSON API are kept too
+		DocumentExpiryFlaggedAt: &flaggedAt,
+	}
+	document, err := toDocument(&verification)
+	assert.NoError(t, err)
+	// the same field names as the MongoDB documents
+	assert.Contains(t, string(document), `"scanRef":"scan-ref"`)
+
+	var decoded models.Verification
+	assert.NoError(t, fromDocument(document, &decoded))
+	assert.Equal(t, verification, decoded)
+}
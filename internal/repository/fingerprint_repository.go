@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type MongoFingerprintRepository struct {
+	collection *mongo.Collection
+	scope      Scope
+	logger     *slog.Logger
+}
+
+// NewMongoFingerprintRepository returns the fingerprints of the scope
+func NewMongoFingerprintRepository(db *mongo.Database, scope Scope, logger *slog.Logger) FingerprintRepository {
+	repo := &MongoFingerprintRepository{
+		collection: db.Collection("identity_fingerprints"),
+		scope:      scope,
+		logger:     logger,
+	}
+	return repo
+}
+
+func (r *MongoFingerprintRepository) GetFingerprint(ctx context.Context, fingerprint string) (*models.IdentityFingerprint, error) {
+	var identityFingerprint models.IdentityFingerprint
+	err := r.collection.FindOne(ctx, r.scope.filter(bson.M{"fingerprint": fingerprint})).Decode(&identityFingerprint)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identityFingerprint, nil
+}
+
+// SaveFingerprint saves the fingerprint, it fails with ErrDuplicateKey if the fingerprint is already saved
+func (r *MongoFingerprintRepository) SaveFingerprint(ctx context.Context, fingerprint *models.IdentityFingerprint) error {
+	fingerprint.Network = r.scope.Network
+	fingerprint.Namespace = r.scope.Namespace
+	fingerprint.CreatedAt = time.Now()
+	if fingerprint.LinkedClientIDs == nil {
+		fingerprint.LinkedClientIDs = []string{}
+	}
+	_, err := r.collection.InsertOne(ctx, fingerprint)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", ErrDuplicateKey, err)
+	}
+	return err
+}
+
+func (r *MongoFingerprintRepository) LinkClient(ctx context.Context, fingerprint string, clientID string) error {
+	update := bson.M{"$addToSet": bson.M{"linkedClientIds": clientID}}
+	_, err := r.collection.UpdateOne(ctx, r.scope.filter(bson.M{"fingerprint": fingerprint}), update)
+	return err
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoLinkRepository struct {
+	links  *mongo.Collection
+	events *mongo.Collection
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewMongoLinkRepository(db *mongo.Database, scope Scope, logger *slog.Logger) LinkRepository {
+	return &MongoLinkRepository{
+		links:  db.Collection("account_links"),
+		events: db.Collection("account_link_events"),
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+// SaveLink saves an active link, it fails with ErrDuplicateKey if the linked account already has an active link
+func (r *MongoLinkRepository) SaveLink(ctx context.Context, link *models.AccountLink) error {
+	link.Network = r.scope.Network
+	link.Namespace = r.scope.Namespace
+	result, err := r.links.InsertOne(ctx, link)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", ErrDuplicateKey, err)
+	}
+	if err != nil {
+		return err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		link.ID = id
+	}
+	return nil
+}
+
+// GetActiveLink returns the active link of the linked account
+func (r *MongoLinkRepository) GetActiveLink(ctx context.Context, linkedClientID string) (*models.AccountLink, error) {
+	var link models.AccountLink
+	err := r.links.FindOne(ctx, r.scope.filter(bson.M{"linkedClientId": linkedClientID, "active": true})).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *MongoLinkRepository) CountActiveLinks(ctx context.Context, primaryClientID string) (int64, error) {
+	return r.links.CountDocuments(ctx, r.scope.filter(bson.M{"primaryClientId": primaryClientID, "active": true}))
+}
+
+// ListLinks returns the links of the client, as primary or linked account, including the revoked ones
+func (r *MongoLinkRepository) ListLinks(ctx context.Context, clientID string) ([]models.AccountLink, error) {
+	filter := r.scope.filter(bson.M{"$or": bson.A{bson.M{"primaryClientId": clientID}, bson.M{"linkedClientId": clientID}}})
+	cursor, err := r.links.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	links := []models.AccountLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+// RevokeLink revokes the active link of either of its accounts, it returns nil if the link doesn't exist or is already revoked
+func (r *MongoLinkRepository) RevokeLink(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (*models.AccountLink, error) {
+	filter := r.scope.filter(bson.M{
+		"_id":    id,
+		"active": true,
+		"$or":    bson.A{bson.M{"primaryClientId": clientID}, bson.M{"linkedClientId": clientID}},
+	})
+	update := bson.M{"$set": bson.M{"active": false, "revokedAt": revokedAt, "revokedBy": clientID}}
+	var link models.AccountLink
+	err := r.links.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (r *MongoLinkRepository) LogEvent(ctx context.Context, event *models.AccountLinkEvent) error {
+	event.Network = r.scope.Network
+	event.Namespace = r.scope.Namespace
+	_, err := r.events.InsertOne(ctx, event)
+	return err
+}
+
+// ListEvents returns the audit events of the links of the client, as primary or linked account
+func (r *MongoLinkRepository) ListEvents(ctx context.Context, clientID string) ([]models.AccountLinkEvent, error) {
+	filter := r.scope.filter(bson.M{"$or": bson.A{bson.M{"primaryClientId": clientID}, bson.M{"linkedClientId": clientID}}})
+	cursor, err := r.events.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	events := []models.AccountLinkEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoLockRepository struct {
+	collection *mongo.Collection
+	logger     *slog.Logger
+}
+
+func NewMongoLockRepository(db *mongo.Database, logger *slog.Logger) LockRepository {
+	repo := &MongoLockRepository{
+		collection: db.Collection("locks"),
+		logger:     logger,
+	}
+	return repo
+}
+
+// AcquireLock takes the lease on name for owner until now+ttl if it is free or expired.
+// It returns false if another owner holds an unexpired lease.
+func (r *MongoLockRepository) AcquireLock(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{"_id": name, "expiresAt": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"owner": owner, "acquiredAt": now, "expiresAt": now.Add(ttl)}}
+	// the upsert inserts a document with _id name when no expired lease matches, which fails with a duplicate key
+	// error if an unexpired lease exists
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseLock frees the lease on name if it is still held by owner
+func (r *MongoLockRepository) ReleaseLock(ctx context.Context, name string, owner string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": name, "owner": owner})
+	return err
+}
@@ -2,22 +2,108 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Scope is the deployment, TFChain network and iDenfy namespace, owning the tokens and verifications of a repository.
+// Repositories stamp the records they save with their scope and only read the records of their scope,
+// so deployments sharing a database don't see each other's records.
+type Scope struct {
+	Network   string
+	Namespace string
+}
+
+// filter restricts the filter to the records of the scope
+func (s Scope) filter(filter bson.M) bson.M {
+	filter["network"] = s.Network
+	filter["namespace"] = s.Namespace
+	return filter
+}
+
+// ErrDuplicateKey is returned when a record violates a unique index, the MongoDB repositories return the driver error as is
+// unless documented otherwise
+var ErrDuplicateKey = errors.New("duplicate key")
+
 type TokenRepository interface {
 	SaveToken(ctx context.Context, token *models.Token) error
 	GetToken(ctx context.Context, clientID string) (*models.Token, error)
 	DeleteToken(ctx context.Context, clientID string, scanRef string) error
 }
 
+// LockRepository provides leases serializing work across instances, a lease expires after its TTL if never released
+type LockRepository interface {
+	AcquireLock(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name string, owner string) error
+}
+
 type VerificationRepository interface {
 	SaveVerification(ctx context.Context, verification *models.Verification) error
 	GetVerification(ctx context.Context, clientID string) (*models.Verification, error)
+	GetVerificationsByScanRefs(ctx context.Context, scanRefs []string) ([]models.Verification, error)
+	FlagExpiringDocuments(ctx context.Context, from string, until string, flaggedAt time.Time) ([]string, error)
+}
+
+type AttemptRepository interface {
+	GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error)
+	RecordAttempt(ctx context.Context, clientID string, scanRef string) error
+	RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error
+	ResetAttempts(ctx context.Context, clientID string) error
+}
+
+type FingerprintRepository interface {
+	GetFingerprint(ctx context.Context, fingerprint string) (*models.IdentityFingerprint, error)
+	SaveFingerprint(ctx context.Context, fingerprint *models.IdentityFingerprint) error
+	LinkClient(ctx context.Context, fingerprint string, clientID string) error
+}
+
+type ChallengeRepository interface {
+	SaveNonce(ctx context.Context, nonce *models.ChallengeNonce) error
+	ConsumeNonce(ctx context.Context, nonce string, clientID string, usedAt time.Time) (bool, error)
+}
+
+type PartnerKeyRepository interface {
+	SaveKey(ctx context.Context, key *models.PartnerAPIKey) error
+	GetKey(ctx context.Context, id primitive.ObjectID) (*models.PartnerAPIKey, error)
+	GetKeyByHash(ctx context.Context, keyHash string) (*models.PartnerAPIKey, error)
+	ListKeys(ctx context.Context) ([]models.PartnerAPIKey, error)
+	RevokeKey(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (bool, error)
+	IncrementUsage(ctx context.Context, id primitive.ObjectID, window time.Time, expiresAt time.Time) (uint, error)
+}
+
+type ConsentRepository interface {
+	SaveGrant(ctx context.Context, grant *models.ConsentGrant) error
+	GetGrant(ctx context.Context, id primitive.ObjectID) (*models.ConsentGrant, error)
+	ListGrants(ctx context.Context, clientID string) ([]models.ConsentGrant, error)
+	GetActiveGrant(ctx context.Context, clientID string, partnerKeyID primitive.ObjectID, now time.Time) (*models.ConsentGrant, error)
+	RevokeGrant(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (bool, error)
+	LogAccess(ctx context.Context, access *models.ConsentAccess) error
+	ListAccesses(ctx context.Context, grantID primitive.ObjectID) ([]models.ConsentAccess, error)
+}
+
+// SessionRepository tracks the iDenfy verification sessions until their result is processed
+type SessionRepository interface {
+	SaveSession(ctx context.Context, session *models.VerificationSession) error
+	ListDueSessions(ctx context.Context, idenfySuffix string, now time.Time, limit int64) ([]models.VerificationSession, error)
+	ClaimSession(ctx context.Context, scanRef string, now time.Time, nextCheckAt time.Time) (bool, error)
+	ResolveSession(ctx context.Context, scanRef string, overall models.Overall, resolvedAt time.Time) error
+}
+
+type LinkRepository interface {
+	SaveLink(ctx context.Context, link *models.AccountLink) error
+	GetActiveLink(ctx context.Context, linkedClientID string) (*models.AccountLink, error)
+	CountActiveLinks(ctx context.Context, primaryClientID string) (int64, error)
+	ListLinks(ctx context.Context, clientID string) ([]models.AccountLink, error)
+	RevokeLink(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (*models.AccountLink, error)
+	LogEvent(ctx context.Context, event *models.AccountLinkEvent) error
+	ListEvents(ctx context.Context, clientID string) ([]models.AccountLinkEvent, error)
 }
 
 func NewMongoClient(ctx context.Context, mongoURI string) (*mongo.Client, error) {
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoPartnerKeyRepository struct {
+	collection *mongo.Collection
+	usage      *mongo.Collection
+	logger     *slog.Logger
+}
+
+func NewMongoPartnerKeyRepository(db *mongo.Database, logger *slog.Logger) PartnerKeyRepository {
+	repo := &MongoPartnerKeyRepository{
+		collection: db.Collection("partner_api_keys"),
+		usage:      db.Collection("partner_api_key_usage"),
+		logger:     logger,
+	}
+	return repo
+}
+
+func (r *MongoPartnerKeyRepository) SaveKey(ctx context.Context, key *models.PartnerAPIKey) error {
+	result, err := r.collection.InsertOne(ctx, key)
+	if err != nil {
+		return err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		key.ID = id
+	}
+	return nil
+}
+
+func (r *MongoPartnerKeyRepository) GetKey(ctx context.Context, id primitive.ObjectID) (*models.PartnerAPIKey, error) {
+	var key models.PartnerAPIKey
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *MongoPartnerKeyRepository) GetKeyByHash(ctx context.Context, keyHash string) (*models.PartnerAPIKey, error) {
+	var key models.PartnerAPIKey
+	err := r.collection.FindOne(ctx, bson.M{"keyHash": keyHash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *MongoPartnerKeyRepository) ListKeys(ctx context.Context) ([]models.PartnerAPIKey, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	keys := []models.PartnerAPIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeKey revokes the key, it returns false if the key doesn't exist or is already revoked
+func (r *MongoPartnerKeyRepository) RevokeKey(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (bool, error) {
+	filter := bson.M{"_id": id, "revokedAt": bson.M{"$exists": false}}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": revokedAt}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// IncrementUsage counts a request of the key in the given window and returns the number of requests in that window so far
+func (r *MongoPartnerKeyRepository) IncrementUsage(ctx context.Context, id primitive.ObjectID, window time.Time, expiresAt time.Time) (uint, error) {
+	filter := bson.M{"keyId": id, "window": window}
+	update := bson.M{
+		"$inc":         bson.M{"count": 1},
+		"$setOnInsert": bson.M{"expiresAt": expiresAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var usage struct {
+		Count uint `bson:"count"`
+	}
+	if err := r.usage.FindOneAndUpdate(ctx, filter, update, opts).Decode(&usage); err != nil {
+		return 0, err
+	}
+	return usage.Count, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// POSTGRES_UNIQUE_VIOLATION is the SQLSTATE of the errors violating a unique index
+const POSTGRES_UNIQUE_VIOLATION = "23505"
+
+func NewPostgresPool(ctx context.Context, url string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to PostgreSQL: %w", err)
+	}
+
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("pinging PostgreSQL: %w", err)
+	}
+
+	return pool, nil
+}
+
+// duplicateKeyError wraps the unique violations in ErrDuplicateKey
+func duplicateKeyError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == POSTGRES_UNIQUE_VIOLATION {
+		return fmt.Errorf("%w: %w", ErrDuplicateKey, err)
+	}
+	return err
+}
+
+// scanDocuments decodes the rows of a single document column, for the records never updated in place
+func scanDocuments[T any](rows pgx.Rows) ([]T, error) {
+	defer rows.Close()
+	models := []T{}
+	for rows.Next() {
+		var document []byte
+		if err := rows.Scan(&document); err != nil {
+			return nil, err
+		}
+		var model T
+		if err := fromDocument(document, &model); err != nil {
+			return nil, err
+		}
+		models = append(models, model)
+	}
+	return models, rows.Err()
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+type PostgresAttemptRepository struct {
+	pool   *pgxpool.Pool
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewPostgresAttemptRepository(pool *pgxpool.Pool, scope Scope, logger *slog.Logger) AttemptRepository {
+	return &PostgresAttemptRepository{
+		pool:   pool,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *PostgresAttemptRepository) GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	attempts := models.VerificationAttempts{ClientID: clientID, Network: r.scope.Network, Namespace: r.scope.Namespace}
+	var count int64
+	var lastAttemptAt *time.Time
+	err := r.pool.QueryRow(ctx,
+		`SELECT count, last_scan_ref, last_attempt_at, last_denied_at, reset_at FROM verification_attempts WHERE network = $1 AND namespace = $2 AND client_id = $3`,
+		r.scope.Network, r.scope.Namespace, clientID,
+	).Scan(&count, &attempts.LastScanRef, &lastAttemptAt, &attempts.LastDeniedAt, &attempts.ResetAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	attempts.Count = uint(count)
+	// a denial can be recorded before any attempt, as the MongoDB upsert does
+	if lastAttemptAt != nil {
+		attempts.LastAttemptAt = *lastAttemptAt
+	}
+	return &attempts, nil
+}
+
+func (r *PostgresAttemptRepository) RecordAttempt(ctx context.Context, clientID string, scanRef string) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO verification_attempts (network, namespace, client_id, count, last_scan_ref, last_attempt_at) VALUES ($1, $2, $3, 1, $4, $5)
+		ON CONFLICT (network, namespace, client_id) DO UPDATE SET count = verification_attempts.count + 1, last_scan_ref = EXCLUDED.last_scan_ref, last_attempt_at = EXCLUDED.last_attempt_at`,
+		r.scope.Network, r.scope.Namespace, clientID, scanRef, time.Now(),
+	)
+	return err
+}
+
+func (r *PostgresAttemptRepository) RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO verification_attempts (network, namespace, client_id, last_denied_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (network, namespace, client_id) DO UPDATE SET last_denied_at = EXCLUDED.last_denied_at`,
+		r.scope.Network, r.scope.Namespace, clientID, deniedAt,
+	)
+	return err
+}
+
+func (r *PostgresAttemptRepository) ResetAttempts(ctx context.Context, clientID string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE verification_attempts SET count = 0, reset_at = $4, last_denied_at = NULL WHERE network = $1 AND namespace = $2 AND client_id = $3`,
+		r.scope.Network, r.scope.Namespace, clientID, time.Now(),
+	)
+	return err
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+type PostgresChallengeRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewPostgresChallengeRepository(pool *pgxpool.Pool, logger *slog.Logger) ChallengeRepository {
+	return &PostgresChallengeRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PostgresChallengeRepository) SaveNonce(ctx context.Context, nonce *models.ChallengeNonce) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO challenge_nonces (nonce, created_at, expires_at) VALUES ($1, $2, $3)`,
+		nonce.Nonce, nonce.CreatedAt, nonce.ExpiresAt,
+	)
+	return duplicateKeyError(err)
+}
+
+// ConsumeNonce atomically marks the nonce as used, it returns false if the nonce is unknown, expired or already used
+func (r *PostgresChallengeRepository) ConsumeNonce(ctx context.Context, nonce string, clientID string, usedAt time.Time) (bool, error) {
+	result, err := r.pool.Exec(ctx,
+		`UPDATE challenge_nonces SET used_at = $3, used_by = $2 WHERE nonce = $1 AND expires_at > $3 AND used_at IS NULL`,
+		nonce, clientID, usedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// DeleteExpired deletes the nonces expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *PostgresChallengeRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM challenge_nonces WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresConsentRepository struct {
+	pool   *pgxpool.Pool
+	scope  Scope
+	logger *slog.Logger
+}
+
+// NewPostgresConsentRepository returns the grants of the scope, the accesses are reached through their grant
+func NewPostgresConsentRepository(pool *pgxpool.Pool, scope Scope, logger *slog.Logger) ConsentRepository {
+	return &PostgresConsentRepository{
+		pool:   pool,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *PostgresConsentRepository) SaveGrant(ctx context.Context, grant *models.ConsentGrant) error {
+	if grant.ID.IsZero() {
+		grant.ID = primitive.NewObjectID()
+	}
+	grant.Network = r.scope.Network
+	grant.Namespace = r.scope.Namespace
+	document, err := toDocument(grant)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO consent_grants (id, network, namespace, client_id, partner_key_id, expires_at, revoked_at, document, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		grant.ID.Hex(), r.scope.Network, r.scope.Namespace, grant.ClientID, grant.PartnerKeyID.Hex(), grant.ExpiresAt, grant.RevokedAt, document, grant.CreatedAt,
+	)
+	return err
+}
+
+func (r *PostgresConsentRepository) GetGrant(ctx context.Context, id primitive.ObjectID) (*models.ConsentGrant, error) {
+	rows, err := r.pool.Query(ctx, `SELECT document, revoked_at FROM consent_grants WHERE network = $1 AND namespace = $2 AND id = $3`,
+		r.scope.Network, r.scope.Namespace, id.Hex(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return firstGrant(rows)
+}
+
+func (r *PostgresConsentRepository) ListGrants(ctx context.Context, clientID string) ([]models.ConsentGrant, error) {
+	rows, err := r.pool.Query(ctx, `SELECT document, revoked_at FROM consent_grants WHERE network = $1 AND namespace = $2 AND client_id = $3 ORDER BY created_at DESC`,
+		r.scope.Network, r.scope.Namespace, clientID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanGrants(rows)
+}
+
+// GetActiveGrant returns the latest grant of the client to the partner that is neither expired nor revoked
+func (r *PostgresConsentRepository) GetActiveGrant(ctx context.Context, clientID string, partnerKeyID primitive.ObjectID, now time.Time) (*models.ConsentGrant, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT document, revoked_at FROM consent_grants
+		WHERE network = $1 AND namespace = $2 AND client_id = $3 AND partner_key_id = $4 AND expires_at > $5 AND revoked_at IS NULL
+		ORDER BY created_at DESC LIMIT 1`,
+		r.scope.Network, r.scope.Namespace, clientID, partnerKeyID.Hex(), now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return firstGrant(rows)
+}
+
+// RevokeGrant revokes the grant of the client, it returns false if the grant doesn't exist or is already revoked
+func (r *PostgresConsentRepository) RevokeGrant(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (bool, error) {
+	result, err := r.pool.Exec(ctx,
+		`UPDATE consent_grants SET revoked_at = $5 WHERE network = $1 AND namespace = $2 AND id = $3 AND client_id = $4 AND revoked_at IS NULL`,
+		r.scope.Network, r.scope.Namespace, id.Hex(), clientID, revokedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *PostgresConsentRepository) LogAccess(ctx context.Context, access *models.ConsentAccess) error {
+	if access.ID.IsZero() {
+		access.ID = primitive.NewObjectID()
+	}
+	document, err := toDocument(access)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO consent_accesses (id, grant_id, document, accessed_at) VALUES ($1, $2, $3, $4)`,
+		access.ID.Hex(), access.GrantID.Hex(), document, access.AccessedAt,
+	)
+	return err
+}
+
+func (r *PostgresConsentRepository) ListAccesses(ctx context.Context, grantID primitive.ObjectID) ([]models.ConsentAccess, error) {
+	rows, err := r.pool.Query(ctx, `SELECT document FROM consent_accesses WHERE grant_id = $1 ORDER BY accessed_at DESC`, grantID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return scanDocuments[models.ConsentAccess](rows)
+}
+
+func firstGrant(rows pgx.Rows) (*models.ConsentGrant, error) {
+	grants, err := scanGrants(rows)
+	if err != nil || len(grants) == 0 {
+		return nil, err
+	}
+	return &grants[0], nil
+}
+
+// scanGrants decodes the grant documents, the revocation is kept in its own column
+func scanGrants(rows pgx.Rows) ([]models.ConsentGrant, error) {
+	defer rows.Close()
+	grants := []models.ConsentGrant{}
+	for rows.Next() {
+		var document []byte
+		var revokedAt *time.Time
+		if err := rows.Scan(&document, &revokedAt); err != nil {
+			return nil, err
+		}
+		var grant models.ConsentGrant
+		if err := fromDocument(document, &grant); err != nil {
+			return nil, err
+		}
+		grant.RevokedAt = revokedAt
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
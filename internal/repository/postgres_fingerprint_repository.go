@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresFingerprintRepository struct {
+	pool   *pgxpool.Pool
+	scope  Scope
+	logger *slog.Logger
+}
+
+// NewPostgresFingerprintRepository returns the fingerprints of the scope
+func NewPostgresFingerprintRepository(pool *pgxpool.Pool, scope Scope, logger *slog.Logger) FingerprintRepository {
+	return &PostgresFingerprintRepository{
+		pool:   pool,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *PostgresFingerprintRepository) GetFingerprint(ctx context.Context, fingerprint string) (*models.IdentityFingerprint, error) {
+	var document []byte
+	var linkedClientIDs []string
+	err := r.pool.QueryRow(ctx, `SELECT document, linked_client_ids FROM identity_fingerprints WHERE network = $1 AND namespace = $2 AND fingerprint = $3`,
+		r.scope.Network, r.scope.Namespace, fingerprint,
+	).Scan(&document, &linkedClientIDs)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var identityFingerprint models.IdentityFingerprint
+	if err := fromDocument(document, &identityFingerprint); err != nil {
+		return nil, err
+	}
+	identityFingerprint.LinkedClientIDs = linkedClientIDs
+	return &identityFingerprint, nil
+}
+
+// SaveFingerprint saves the fingerprint, it fails with ErrDuplicateKey if the fingerprint is already saved
+func (r *PostgresFingerprintRepository) SaveFingerprint(ctx context.Context, fingerprint *models.IdentityFingerprint) error {
+	fingerprint.ID = primitive.NewObjectID()
+	fingerprint.Network = r.scope.Network
+	fingerprint.Namespace = r.scope.Namespace
+	fingerprint.CreatedAt = time.Now()
+	if fingerprint.LinkedClientIDs == nil {
+		fingerprint.LinkedClientIDs = []string{}
+	}
+	document, err := toDocument(fingerprint)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO identity_fingerprints (network, namespace, fingerprint, linked_client_ids, document) VALUES ($1, $2, $3, $4, $5)`,
+		r.scope.Network, r.scope.Namespace, fingerprint.Fingerprint, fingerprint.LinkedClientIDs, document,
+	)
+	return duplicateKeyError(err)
+}
+
+func (r *PostgresFingerprintRepository) LinkClient(ctx context.Context, fingerprint string, clientID string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE identity_fingerprints SET linked_client_ids = array_append(linked_client_ids, $4) WHERE network = $1 AND namespace = $2 AND fingerprint = $3 AND NOT ($4 = ANY(linked_client_ids))`,
+		r.scope.Network, r.scope.Namespace, fingerprint, clientID,
+	)
+	return err
+}
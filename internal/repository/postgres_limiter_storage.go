@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// POSTGRES_STORAGE_TIMEOUT bounds the queries of the rate limiter storage, whose interface has no context
+const POSTGRES_STORAGE_TIMEOUT = 5 * time.Second
+
+// PostgresLimiterStorage is a fiber.Storage of the rate limiters, each limiter has its own bucket in the rate_limits table
+type PostgresLimiterStorage struct {
+	pool   *pgxpool.Pool
+	bucket string
+}
+
+func NewPostgresLimiterStorage(pool *pgxpool.Pool, bucket string) *PostgresLimiterStorage {
+	return &PostgresLimiterStorage{pool: pool, bucket: bucket}
+}
+
+func (s *PostgresLimiterStorage) Get(key string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), POSTGRES_STORAGE_TIMEOUT)
+	defer cancel()
+	var value []byte
+	err := s.pool.QueryRow(ctx,
+		`SELECT value FROM rate_limits WHERE bucket = $1 AND key = $2 AND (expires_at IS NULL OR expires_at > $3)`,
+		s.bucket, key, time.Now(),
+	).Scan(&value)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Set stores the value of the key, a zero expiration keeps it forever
+func (s *PostgresLimiterStorage) Set(key string, value []byte, exp time.Duration) error {
+	if key == "" || len(value) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), POSTGRES_STORAGE_TIMEOUT)
+	defer cancel()
+	var expiresAt *time.Time
+	if exp > 0 {
+		at := time.Now().Add(exp)
+		expiresAt = &at
+	}
+	_, err := s.pool.Exec(ctx,
+		`INSERT INTO rate_limits (bucket, key, value, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (bucket, key) DO UPDATE SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at`,
+		s.bucket, key, value, expiresAt,
+	)
+	return err
+}
+
+func (s *PostgresLimiterStorage) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), POSTGRES_STORAGE_TIMEOUT)
+	defer cancel()
+	_, err := s.pool.Exec(ctx, `DELETE FROM rate_limits WHERE bucket = $1 AND key = $2`, s.bucket, key)
+	return err
+}
+
+func (s *PostgresLimiterStorage) Reset() error {
+	ctx, cancel := context.WithTimeout(context.Background(), POSTGRES_STORAGE_TIMEOUT)
+	defer cancel()
+	_, err := s.pool.Exec(ctx, `DELETE FROM rate_limits WHERE bucket = $1`, s.bucket)
+	return err
+}
+
+// Close does nothing, the pool is shared with the repositories and closed by the server
+func (s *PostgresLimiterStorage) Close() error {
+	return nil
+}
+
+// DeleteExpired deletes the entries of the bucket expired at the given time
+func (s *PostgresLimiterStorage) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.pool.Exec(ctx, `DELETE FROM rate_limits WHERE bucket = $1 AND expires_at <= $2`, s.bucket, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresLinkRepository struct {
+	pool   *pgxpool.Pool
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewPostgresLinkRepository(pool *pgxpool.Pool, scope Scope, logger *slog.Logger) LinkRepository {
+	return &PostgresLinkRepository{
+		pool:   pool,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+// SaveLink saves an active link, it fails with ErrDuplicateKey if the linked account already has an active link
+func (r *PostgresLinkRepository) SaveLink(ctx context.Context, link *models.AccountLink) error {
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	link.Network = r.scope.Network
+	link.Namespace = r.scope.Namespace
+	document, err := toDocument(link)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO account_links (id, network, namespace, primary_client_id, linked_client_id, active, revoked_at, revoked_by, document, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		link.ID.Hex(), r.scope.Network, r.scope.Namespace, link.PrimaryClientID, link.LinkedClientID, link.Active, link.RevokedAt, link.RevokedBy, document, link.CreatedAt,
+	)
+	return duplicateKeyError(err)
+}
+
+// GetActiveLink returns the active link of the linked account
+func (r *PostgresLinkRepository) GetActiveLink(ctx context.Context, linkedClientID string) (*models.AccountLink, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT document, active, revoked_at, revoked_by FROM account_links WHERE network = $1 AND namespace = $2 AND linked_client_id = $3 AND active`,
+		r.scope.Network, r.scope.Namespace, linkedClientID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return firstLink(rows)
+}
+
+func (r *PostgresLinkRepository) CountActiveLinks(ctx context.Context, primaryClientID string) (int64, error) {
+	var count int64
+	err := r.pool.QueryRow(ctx, `SELECT count(*) FROM account_links WHERE network = $1 AND namespace = $2 AND primary_client_id = $3 AND active`,
+		r.scope.Network, r.scope.Namespace, primaryClientID,
+	).Scan(&count)
+	return count, err
+}
+
+// ListLinks returns the links of the client, as primary or linked account, including the revoked ones
+func (r *PostgresLinkRepository) ListLinks(ctx context.Context, clientID string) ([]models.AccountLink, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT document, active, revoked_at, revoked_by FROM account_links
+		WHERE network = $1 AND namespace = $2 AND (primary_client_id = $3 OR linked_client_id = $3) ORDER BY created_at DESC`,
+		r.scope.Network, r.scope.Namespace, clientID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanLinks(rows)
+}
+
+// RevokeLink revokes the active link of either of its accounts, it returns nil if the link doesn't exist or is already revoked
+func (r *PostgresLinkRepository) RevokeLink(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (*models.AccountLink, error) {
+	rows, err := r.pool.Query(ctx,
+		`UPDATE account_links SET active = FALSE, revoked_at = $5, revoked_by = $4
+		WHERE network = $1 AND namespace = $2 AND id = $3 AND active AND (primary_client_id = $4 OR linked_client_id = $4)
+		RETURNING document, active, revoked_at, revoked_by`,
+		r.scope.Network, r.scope.Namespace, id.Hex(), clientID, revokedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return firstLink(rows)
+}
+
+func (r *PostgresLinkRepository) LogEvent(ctx context.Context, event *models.AccountLinkEvent) error {
+	if event.ID.IsZero() {
+		event.ID = primitive.NewObjectID()
+	}
+	event.Network = r.scope.Network
+	event.Namespace = r.scope.Namespace
+	document, err := toDocument(event)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO account_link_events (id, network, namespace, primary_client_id, linked_client_id, document, at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		event.ID.Hex(), r.scope.Network, r.scope.Namespace, event.PrimaryClientID, event.LinkedClientID, document, event.At,
+	)
+	return err
+}
+
+// ListEvents returns the audit events of the links of the client, as primary or linked account
+func (r *PostgresLinkRepository) ListEvents(ctx context.Context, clientID string) ([]models.AccountLinkEvent, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT document FROM account_link_events
+		WHERE network = $1 AND namespace = $2 AND (primary_client_id = $3 OR linked_client_id = $3) ORDER BY at DESC`,
+		r.scope.Network, r.scope.Namespace, clientID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanDocuments[models.AccountLinkEvent](rows)
+}
+
+func firstLink(rows pgx.Rows) (*models.AccountLink, error) {
+	links, err := scanLinks(rows)
+	if err != nil || len(links) == 0 {
+		return nil, err
+	}
+	return &links[0], nil
+}
+
+// scanLinks decodes the link documents, the revocation is kept in its own columns
+func scanLinks(rows pgx.Rows) ([]models.AccountLink, error) {
+	defer rows.Close()
+	links := []models.AccountLink{}
+	for rows.Next() {
+		var document []byte
+		var link models.AccountLink
+		var active bool
+		var revokedAt *time.Time
+		var revokedBy string
+		if err := rows.Scan(&document, &active, &revokedAt, &revokedBy); err != nil {
+			return nil, err
+		}
+		if err := fromDocument(document, &link); err != nil {
+			return nil, err
+		}
+		link.Active = active
+		link.RevokedAt = revokedAt
+		link.RevokedBy = revokedBy
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresLockRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewPostgresLockRepository(pool *pgxpool.Pool, logger *slog.Logger) LockRepository {
+	return &PostgresLockRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// AcquireLock takes the lease on name for owner until now+ttl if it is free or expired.
+// It returns false if another owner holds an unexpired lease.
+func (r *PostgresLockRepository) AcquireLock(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// the conflicting row is only taken over if its lease expired, otherwise no row is affected
+	result, err := r.pool.Exec(ctx,
+		`INSERT INTO locks (name, owner, acquired_at, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, acquired_at = EXCLUDED.acquired_at, expires_at = EXCLUDED.expires_at
+		WHERE locks.expires_at <= EXCLUDED.acquired_at`,
+		name, owner, now, now.Add(ttl),
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// ReleaseLock frees the lease on name if it is still held by owner
+func (r *PostgresLockRepository) ReleaseLock(ctx context.Context, name string, owner string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM locks WHERE name = $1 AND owner = $2`, name, owner)
+	return err
+}
+
+// DeleteExpired deletes the leases expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *PostgresLockRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM locks WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
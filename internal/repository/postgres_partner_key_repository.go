@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresPartnerKeyRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewPostgresPartnerKeyRepository(pool *pgxpool.Pool, logger *slog.Logger) PartnerKeyRepository {
+	return &PostgresPartnerKeyRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PostgresPartnerKeyRepository) SaveKey(ctx context.Context, key *models.PartnerAPIKey) error {
+	if key.ID.IsZero() {
+		key.ID = primitive.NewObjectID()
+	}
+	document, err := toDocument(key)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO partner_api_keys (id, key_hash, revoked_at, document, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		key.ID.Hex(), key.KeyHash, key.RevokedAt, document, key.CreatedAt,
+	)
+	return duplicateKeyError(err)
+}
+
+func (r *PostgresPartnerKeyRepository) GetKey(ctx context.Context, id primitive.ObjectID) (*models.PartnerAPIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT document, revoked_at FROM partner_api_keys WHERE id = $1`, id.Hex())
+	if err != nil {
+		return nil, err
+	}
+	return firstPartnerKey(rows)
+}
+
+func (r *PostgresPartnerKeyRepository) GetKeyByHash(ctx context.Context, keyHash string) (*models.PartnerAPIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT document, revoked_at FROM partner_api_keys WHERE key_hash = $1`, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	return firstPartnerKey(rows)
+}
+
+func (r *PostgresPartnerKeyRepository) ListKeys(ctx context.Context) ([]models.PartnerAPIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT document, revoked_at FROM partner_api_keys ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	return scanPartnerKeys(rows)
+}
+
+// RevokeKey revokes the key, it returns false if the key doesn't exist or is already revoked
+func (r *PostgresPartnerKeyRepository) RevokeKey(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (bool, error) {
+	result, err := r.pool.Exec(ctx, `UPDATE partner_api_keys SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL`, id.Hex(), revokedAt)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// IncrementUsage counts a request of the key in the given window and returns the number of requests in that window so far
+func (r *PostgresPartnerKeyRepository) IncrementUsage(ctx context.Context, id primitive.ObjectID, window time.Time, expiresAt time.Time) (uint, error) {
+	var count int64
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO partner_api_key_usage (key_id, window_start, count, expires_at) VALUES ($1, $2, 1, $3)
+		ON CONFLICT (key_id, window_start) DO UPDATE SET count = partner_api_key_usage.count + 1
+		RETURNING count`,
+		id.Hex(), window, expiresAt,
+	).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+	return uint(count), nil
+}
+
+// DeleteExpired deletes the usage counters expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *PostgresPartnerKeyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM partner_api_key_usage WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+func firstPartnerKey(rows pgx.Rows) (*models.PartnerAPIKey, error) {
+	keys, err := scanPartnerKeys(rows)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return &keys[0], nil
+}
+
+// scanPartnerKeys decodes the key documents, the revocation is kept in its own column
+func scanPartnerKeys(rows pgx.Rows) ([]models.PartnerAPIKey, error) {
+	defer rows.Close()
+	keys := []models.PartnerAPIKey{}
+	for rows.Next() {
+		var document []byte
+		var revokedAt *time.Time
+		if err := rows.Scan(&document, &revokedAt); err != nil {
+			return nil, err
+		}
+		var key models.PartnerAPIKey
+		if err := fromDocument(document, &key); err != nil {
+			return nil, err
+		}
+		key.RevokedAt = revokedAt
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresSessionRepository struct {
+	pool   *pgxpool.Pool
+	logger *slog.Logger
+}
+
+func NewPostgresSessionRepository(pool *pgxpool.Pool, logger *slog.Logger) SessionRepository {
+	return &PostgresSessionRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PostgresSessionRepository) SaveSession(ctx context.Context, session *models.VerificationSession) error {
+	if session.ID.IsZero() {
+		session.ID = primitive.NewObjectID()
+	}
+	document, err := toDocument(session)
+	if err != nil {
+		return err
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO verification_sessions (scan_ref, idenfy_suffix, next_check_at, checks, resolved_at, overall, document, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		session.ScanRef, session.IdenfySuffix, session.NextCheckAt, int64(session.Checks), session.ResolvedAt, string(session.Overall), document, session.ExpiresAt,
+	)
+	return duplicateKeyError(err)
+}
+
+// ListDueSessions returns the unresolved sessions of the iDenfy suffix to check at the given time, the most overdue first
+func (r *PostgresSessionRepository) ListDueSessions(ctx context.Context, idenfySuffix string, now time.Time, limit int64) ([]models.VerificationSession, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT document, next_check_at, checks, resolved_at, overall FROM verification_sessions
+		WHERE idenfy_suffix = $1 AND resolved_at IS NULL AND next_check_at <= $2 AND expires_at > $2
+		ORDER BY next_check_at LIMIT $3`,
+		idenfySuffix, now, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanSessions(rows)
+}
+
+// ClaimSession postpones the next check of a due session, it returns false if another instance claimed or resolved it first
+func (r *PostgresSessionRepository) ClaimSession(ctx context.Context, scanRef string, now time.Time, nextCheckAt time.Time) (bool, error) {
+	result, err := r.pool.Exec(ctx,
+		`UPDATE verification_sessions SET next_check_at = $3, checks = checks + 1 WHERE scan_ref = $1 AND resolved_at IS NULL AND next_check_at <= $2`,
+		scanRef, now, nextCheckAt,
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// ResolveSession records the result of the session, unknown sessions, such as the ones created before they were tracked, are ignored
+func (r *PostgresSessionRepository) ResolveSession(ctx context.Context, scanRef string, overall models.Overall, resolvedAt time.Time) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE verification_sessions SET resolved_at = $3, overall = $2 WHERE scan_ref = $1 AND resolved_at IS NULL`,
+		scanRef, string(overall), resolvedAt,
+	)
+	return err
+}
+
+// DeleteExpired deletes the sessions expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *PostgresSessionRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM verification_sessions WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// scanSessions decodes the session documents, the reconciliation progress is kept in its own columns
+func scanSessions(rows pgx.Rows) ([]models.VerificationSession, error) {
+	defer rows.Close()
+	sessions := []models.VerificationSession{}
+	for rows.Next() {
+		var document []byte
+		var session models.VerificationSession
+		var nextCheckAt time.Time
+		var checks int64
+		var resolvedAt *time.Time
+		var overall string
+		if err := rows.Scan(&document, &nextCheckAt, &checks, &resolvedAt, &overall); err != nil {
+			return nil, err
+		}
+		if err := fromDocument(document, &session); err != nil {
+			return nil, err
+		}
+		session.NextCheckAt = nextCheckAt
+		session.Checks = uint(checks)
+		session.ResolvedAt = resolvedAt
+		session.Overall = models.Overall(overall)
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
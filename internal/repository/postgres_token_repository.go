@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresTokenRepository struct {
+	pool   *pgxpool.Pool
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewPostgresTokenRepository(pool *pgxpool.Pool, scope Scope, logger *slog.Logger) TokenRepository {
+	return &PostgresTokenRepository{
+		pool:   pool,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *PostgresTokenRepository) SaveToken(ctx context.Context, token *models.Token) error {
+	token.ID = primitive.NewObjectID()
+	token.Network = r.scope.Network
+	token.Namespace = r.scope.Namespace
+	token.CreatedAt = time.Now()
+	token.ExpiresAt = token.CreatedAt.Add(time.Duration(token.ExpiryTime) * time.Second)
+	document, err := toDocument(token)
+	if err != nil {
+		return err
+	}
+	err = pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		// an expired token not cleaned up yet must not conflict with the new one
+		_, err := tx.Exec(ctx, `DELETE FROM tokens WHERE network = $1 AND namespace = $2 AND client_id = $3 AND expires_at <= $4`,
+			r.scope.Network, r.scope.Namespace, token.ClientID, token.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO tokens (id, network, namespace, client_id, scan_ref, document, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			token.ID.Hex(), r.scope.Network, r.scope.Namespace, token.ClientID, token.ScanRef, document, token.CreatedAt, token.ExpiresAt,
+		)
+		return err
+	})
+	return duplicateKeyError(err)
+}
+
+func (r *PostgresTokenRepository) GetToken(ctx context.Context, clientID string) (*models.Token, error) {
+	var document []byte
+	err := r.pool.QueryRow(ctx, `SELECT document FROM tokens WHERE network = $1 AND namespace = $2 AND client_id = $3 AND expires_at > $4`,
+		r.scope.Network, r.scope.Namespace, clientID, time.Now(),
+	).Scan(&document)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var token models.Token
+	if err := fromDocument(document, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *PostgresTokenRepository) DeleteToken(ctx context.Context, clientID string, scanRef string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM tokens WHERE network = $1 AND namespace = $2 AND client_id = $3 AND scan_ref = $4`,
+		r.scope.Network, r.scope.Namespace, clientID, scanRef,
+	)
+	return err
+}
+
+// DeleteExpired deletes the tokens expired at the given time, the MongoDB backend relies on a TTL index instead
+func (r *PostgresTokenRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM tokens WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PostgresVerificationRepository struct {
+	pool   *pgxpool.Pool
+	scope  Scope
+	logger *slog.Logger
+}
+
+func NewPostgresVerificationRepository(pool *pgxpool.Pool, scope Scope, logger *slog.Logger) VerificationRepository {
+	return &PostgresVerificationRepository{
+		pool:   pool,
+		scope:  scope,
+		logger: logger,
+	}
+}
+
+func (r *PostgresVerificationRepository) SaveVerification(ctx context.Context, verification *models.Verification) error {
+	verification.ID = primitive.NewObjectID()
+	verification.Network = r.scope.Network
+	verification.Namespace = r.scope.Namespace
+	verification.CreatedAt = time.Now()
+	document, err := toDocument(verification)
+	if err != nil {
+		return err
+	}
+	var overall *string
+	if verification.Status.Overall != nil {
+		status := string(*verification.Status.Overall)
+		overall = &status
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO verifications (id, network, namespace, client_id, scan_ref, status_overall, doc_expiry, document, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		verification.ID.Hex(), r.scope.Network, r.scope.Namespace, verification.ClientID, verification.IdenfyRef, overall, verification.Data.DocExpiry, document, verification.CreatedAt,
+	)
+	return err
+}
+
+func (r *PostgresVerificationRepository) GetVerification(ctx context.Context, clientID string) (*models.Verification, error) {
+	// return the latest verification
+	rows, err := r.pool.Query(ctx,
+		`SELECT document, document_expiry_flagged_at FROM verifications WHERE network = $1 AND namespace = $2 AND client_id = $3 ORDER BY created_at DESC LIMIT 1`,
+		r.scope.Network, r.scope.Namespace, clientID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	verifications, err := scanVerifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(verifications) == 0 {
+		return nil, nil
+	}
+	return &verifications[0], nil
+}
+
+func (r *PostgresVerificationRepository) GetVerificationsByScanRefs(ctx context.Context, scanRefs []string) ([]models.Verification, error) {
+	if len(scanRefs) == 0 {
+		return []models.Verification{}, nil
+	}
+	rows, err := r.pool.Query(ctx,
+		`SELECT document, document_expiry_flagged_at FROM verifications WHERE network = $1 AND namespace = $2 AND scan_ref = ANY($3)`,
+		r.scope.Network, r.scope.Namespace, scanRefs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanVerifications(rows)
+}
+
+// FlagExpiringDocuments flags the latest verification of each client when it is approved, its document expires between
+// from and until (YYYY-MM-DD, inclusive) and it was not flagged yet. It returns the client IDs of the flagged verifications.
+func (r *PostgresVerificationRepository) FlagExpiringDocuments(ctx context.Context, from string, until string, flaggedAt time.Time) ([]string, error) {
+	rows, err := r.pool.Query(ctx,
+		`UPDATE verifications SET document_expiry_flagged_at = $1
+		WHERE id IN (
+			SELECT DISTINCT ON (client_id) id FROM verifications
+			WHERE network = $2 AND namespace = $3
+			ORDER BY client_id, created_at DESC, id DESC
+		) AND doc_expiry BETWEEN $4 AND $5 AND status_overall = ANY($6) AND document_expiry_flagged_at IS NULL
+		RETURNING client_id`,
+		flaggedAt, r.scope.Network, r.scope.Namespace, from, until, []string{string(models.OverallApproved), string(models.OverallSuspected)},
+	)
+	if err != nil {
+		return nil, err
+	}
+	clientIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, err
+	}
+	return clientIDs, nil
+}
+
+// scanVerifications decodes the verification documents, the expiry flag is kept in its own column
+func scanVerifications(rows pgx.Rows) ([]models.Verification, error) {
+	defer rows.Close()
+	verifications := []models.Verification{}
+	for rows.Next() {
+		var document []byte
+		var flaggedAt *time.Time
+		if err := rows.Scan(&document, &flaggedAt); err != nil {
+			return nil, err
+		}
+		var verification models.Verification
+		if err := fromDocument(document, &verification); err != nil {
+			return nil, err
+		}
+		verification.DocumentExpiryFlaggedAt = flaggedAt
+		verifications = append(verifications, verification)
+	}
+	return verifications, rows.Err()
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoSessionRepository struct {
+	collection *mongo.Collection
+	logger     *slog.Logger
+}
+
+func NewMongoSessionRepository(db *mongo.Database, logger *slog.Logger) SessionRepository {
+	return &MongoSessionRepository{
+		collection: db.Collection("verification_sessions"),
+		logger:     logger,
+	}
+}
+
+func (r *MongoSessionRepository) SaveSession(ctx context.Context, session *models.VerificationSession) error {
+	_, err := r.collection.InsertOne(ctx, session)
+	return err
+}
+
+// ListDueSessions returns the unresolved sessions of the iDenfy suffix to check at the given time, the most overdue first
+func (r *MongoSessionRepository) ListDueSessions(ctx context.Context, idenfySuffix string, now time.Time, limit int64) ([]models.VerificationSession, error) {
+	filter := bson.M{
+		"idenfySuffix": idenfySuffix,
+		"resolvedAt":   bson.M{"$exists": false},
+		"nextCheckAt":  bson.M{"$lte": now},
+		"expiresAt":    bson.M{"$gt": now},
+	}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "nextCheckAt", Value: 1}}).SetLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	sessions := []models.VerificationSession{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// ClaimSession postpones the next check of a due session, it returns false if another instance claimed or resolved it first
+func (r *MongoSessionRepository) ClaimSession(ctx context.Context, scanRef string, now time.Time, nextCheckAt time.Time) (bool, error) {
+	filter := bson.M{"scanRef": scanRef, "resolvedAt": bson.M{"$exists": false}, "nextCheckAt": bson.M{"$lte": now}}
+	update := bson.M{"$set": bson.M{"nextCheckAt": nextCheckAt}, "$inc": bson.M{"checks": 1}}
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// ResolveSession records the result of the session, unknown sessions, such as the ones created before they were tracked, are ignored
+func (r *MongoSessionRepository) ResolveSession(ctx context.Context, scanRef string, overall models.Overall, resolvedAt time.Time) error {
+	filter := bson.M{"scanRef": scanRef, "resolvedAt": bson.M{"$exists": false}}
+	_, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"resolvedAt": resolvedAt, "overall": overall}})
+	return err
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"log/slog"
@@ -14,58 +15,40 @@ import (
 
 type MongoTokenRepository struct {
 	collection *mongo.Collection
+	scope      Scope
 	logger     *slog.Logger
 }
 
-func NewMongoTokenRepository(ctx context.Context, db *mongo.Database, logger *slog.Logger) TokenRepository {
+func NewMongoTokenRepository(db *mongo.Database, scope Scope, logger *slog.Logger) TokenRepository {
 	repo := &MongoTokenRepository{
 		collection: db.Collection("tokens"),
+		scope:      scope,
 		logger:     logger,
 	}
-	repo.createTTLIndex(ctx)
-	repo.createCollectionIndexes(ctx)
 	return repo
 }
 
-func (r *MongoTokenRepository) createTTLIndex(ctx context.Context) {
-	_, err := r.collection.Indexes().CreateOne(
-		ctx,
-		mongo.IndexModel{
-			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
-			Options: options.Index().SetExpireAfterSeconds(0),
-		},
-	)
-	if err != nil {
-		r.logger.Error("Error creating TTL index", "error", err)
-	}
-}
-
-func (r *MongoTokenRepository) createCollectionIndexes(ctx context.Context) {
-	keys := []bson.D{
-		{{Key: "clientId", Value: 1}},
-		{{Key: "scanRef", Value: 1}},
-	}
-	for _, key := range keys {
-		_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
-			Keys:    key,
-			Options: options.Index().SetUnique(true),
-		})
-		if err != nil {
-			r.logger.Error("Error creating index", "key", key, "error", err)
-		}
-	}
-}
-
 func (r *MongoTokenRepository) SaveToken(ctx context.Context, token *models.Token) error {
+	token.Network = r.scope.Network
+	token.Namespace = r.scope.Namespace
 	token.CreatedAt = time.Now()
 	token.ExpiresAt = token.CreatedAt.Add(time.Duration(token.ExpiryTime) * time.Second)
-	_, err := r.collection.InsertOne(ctx, token)
+	// an expired token the TTL monitor hasn't deleted yet is replaced, a live one conflicts on the unique clientId index
+	_, err := r.collection.ReplaceOne(ctx,
+		r.scope.filter(bson.M{"clientId": token.ClientID, "expiresAt": bson.M{"$lte": token.CreatedAt}}),
+		token,
+		options.Replace().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %w", ErrDuplicateKey, err)
+	}
 	return err
 }
 
+// GetToken returns the token of the client, expired tokens are ignored until the TTL monitor deletes them
 func (r *MongoTokenRepository) GetToken(ctx context.Context, clientID string) (*models.Token, error) {
 	var token models.Token
-	err := r.collection.FindOne(ctx, bson.M{"clientId": clientID}).Decode(&token)
+	err := r.collection.FindOne(ctx, r.scope.filter(bson.M{"clientId": clientID, "expiresAt": bson.M{"$gt": time.Now()}})).Decode(&token)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -77,6 +60,6 @@ func (r *MongoTokenRepository) GetToken(ctx context.Context, clientID string) (*
 }
 
 func (r *MongoTokenRepository) DeleteToken(ctx context.Context, clientID string, scanRef string) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"clientId": clientID, "scanRef": scanRef})
+	_, err := r.collection.DeleteOne(ctx, r.scope.filter(bson.M{"clientId": clientID, "scanRef": scanRef}))
 	return err
 }
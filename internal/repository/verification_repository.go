@@ -7,37 +7,29 @@ import (
 
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type MongoVerificationRepository struct {
 	collection *mongo.Collection
+	scope      Scope
 	logger     *slog.Logger
 }
 
-func NewMongoVerificationRepository(ctx context.Context, db *mongo.Database, logger *slog.Logger) VerificationRepository {
-	// create index for clientId
+func NewMongoVerificationRepository(db *mongo.Database, scope Scope, logger *slog.Logger) VerificationRepository {
 	repo := &MongoVerificationRepository{
 		collection: db.Collection("verifications"),
+		scope:      scope,
 		logger:     logger,
 	}
-	repo.createCollectionIndexes(ctx)
 	return repo
 }
 
-func (r *MongoVerificationRepository) createCollectionIndexes(ctx context.Context) {
-	key := bson.D{{Key: "clientId", Value: 1}}
-	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    key,
-		Options: options.Index().SetUnique(false),
-	})
-	if err != nil {
-		r.logger.Error("Error creating index", "key", key, "error", err)
-	}
-}
-
 func (r *MongoVerificationRepository) SaveVerification(ctx context.Context, verification *models.Verification) error {
+	verification.Network = r.scope.Network
+	verification.Namespace = r.scope.Namespace
 	verification.CreatedAt = time.Now()
 	_, err := r.collection.InsertOne(ctx, verification)
 	return err
@@ -47,7 +39,7 @@ func (r *MongoVerificationRepository) GetVerification(ctx context.Context, clien
 	var verification models.Verification
 	// return the latest verification
 	opts := options.FindOne().SetSort(bson.D{{Key: "createdAt", Value: -1}})
-	err := r.collection.FindOne(ctx, bson.M{"clientId": clientID}, opts).Decode(&verification)
+	err := r.collection.FindOne(ctx, r.scope.filter(bson.M{"clientId": clientID}), opts).Decode(&verification)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -56,3 +48,67 @@ func (r *MongoVerificationRepository) GetVerification(ctx context.Context, clien
 	}
 	return &verification, nil
 }
+
+func (r *MongoVerificationRepository) GetVerificationsByScanRefs(ctx context.Context, scanRefs []string) ([]models.Verification, error) {
+	verifications := []models.Verification{}
+	if len(scanRefs) == 0 {
+		return verifications, nil
+	}
+	cursor, err := r.collection.Find(ctx, r.scope.filter(bson.M{"scanRef": bson.M{"$in": scanRefs}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &verifications); err != nil {
+		return nil, err
+	}
+	return verifications, nil
+}
+
+// FlagExpiringDocuments flags the latest verification of each client when it is approved, its document expires between
+// from and until (YYYY-MM-DD, inclusive) and it was not flagged yet. It returns the client IDs of the flagged verifications.
+// The verifications are flagged one by one only if still unflagged, so concurrent jobs never return the same client twice.
+func (r *MongoVerificationRepository) FlagExpiringDocuments(ctx context.Context, from string, until string, flaggedAt time.Time) ([]string, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: r.scope.filter(bson.M{})}},
+		{{Key: "$sort", Value: bson.D{{Key: "clientId", Value: 1}, {Key: "createdAt", Value: -1}, {Key: "_id", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":                     "$clientId",
+			"verificationId":          bson.M{"$first": "$_id"},
+			"docExpiry":               bson.M{"$first": "$data.docExpiry"},
+			"overall":                 bson.M{"$first": "$status.overall"},
+			"documentExpiryFlaggedAt": bson.M{"$first": "$documentExpiryFlaggedAt"},
+		}}},
+		{{Key: "$match", Value: bson.M{
+			"docExpiry":               bson.M{"$gte": from, "$lte": until},
+			"overall":                 bson.M{"$in": bson.A{models.OverallApproved, models.OverallSuspected}},
+			"documentExpiryFlaggedAt": nil,
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var expiring []struct {
+		ClientID       string             `bson:"_id"`
+		VerificationID primitive.ObjectID `bson:"verificationId"`
+	}
+	if err := cursor.All(ctx, &expiring); err != nil {
+		return nil, err
+	}
+	clientIDs := []string{}
+	for _, verification := range expiring {
+		result, err := r.collection.UpdateOne(ctx,
+			bson.M{"_id": verification.VerificationID, "documentExpiryFlaggedAt": bson.M{"$exists": false}},
+			bson.M{"$set": bson.M{"documentExpiryFlaggedAt": flaggedAt}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		// another instance flagged it in the meantime
+		if result.ModifiedCount == 0 {
+			continue
+		}
+		clientIDs = append(clientIDs, verification.ClientID)
+	}
+	return clientIDs, nil
+}
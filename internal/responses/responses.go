@@ -1,14 +1,22 @@
 package responses
 
 import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/session"
 )
 
 type APIResponse struct {
-	Result any    `json:"result,omitempty"`
-	Error  string `json:"error,omitempty"`
+	Result  any            `json:"result,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
 }
 
 func Success(data any) *APIResponse {
@@ -27,6 +35,12 @@ func RespondWithError(c *fiber.Ctx, status int, err error) error {
 	return c.Status(status).JSON(Error(err.Error()))
 }
 
+func RespondWithErrorDetails(c *fiber.Ctx, status int, err error, details map[string]any) error {
+	response := Error(err.Error())
+	response.Details = details
+	return c.Status(status).JSON(response)
+}
+
 func RespondWithData(c *fiber.Ctx, status int, data any) error {
 	return c.Status(status).JSON(Success(data))
 }
@@ -42,6 +56,12 @@ type HealthResponse struct {
 	Status    HealthStatus `json:"status"`
 	Timestamp string       `json:"timestamp"`
 	Errors    []string     `json:"errors"`
+	Idenfy    IdenfyHealth `json:"idenfy"`
+}
+
+type IdenfyHealth struct {
+	// BreakerState of the iDenfy circuit breaker, one of closed, open and half-open
+	BreakerState string `json:"breakerState" enums:"closed,open,half-open"`
 }
 
 type TokenResponse struct {
@@ -63,10 +83,16 @@ const (
 )
 
 type VerificationStatusResponse struct {
-	Final     bool    `json:"final"`
-	IdenfyRef string  `json:"idenfyRef"`
-	ClientID  string  `json:"clientId"`
-	Status    Outcome `json:"status"`
+	Final                  bool       `json:"final"`
+	IdenfyRef              string     `json:"idenfyRef"`
+	ClientID               string     `json:"clientId"`
+	Status                 Outcome    `json:"status"`
+	DocumentExpiresAt      *time.Time `json:"documentExpiresAt,omitempty"`
+	ReverificationRequired bool       `json:"reverificationRequired"`
+	// TrustedNetwork is set when the status was accepted from the verification of the same key on a linked network
+	TrustedNetwork string `json:"trustedNetwork,omitempty"`
+	// LinkedTo is set when the status is inherited from the primary account the client is linked to
+	LinkedTo string `json:"linkedTo,omitempty"`
 }
 
 type VerificationDataResponse struct {
@@ -110,6 +136,88 @@ type VerificationDataResponse struct {
 	ClientID               string      `json:"clientId"`
 }
 
+type ChallengeNonceResponse struct {
+	Nonce     string    `json:"nonce"`
+	Domain    string    `json:"domain"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type SessionTokenResponse struct {
+	Token     string    `json:"token"`
+	TokenType string    `json:"tokenType"`
+	ClientID  string    `json:"clientId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// VerificationStatusFields are the fields of the verification status a partner API key can be scoped to
+var VerificationStatusFields = []string{"final", "idenfyRef", "clientId", "status", "documentExpiresAt", "reverificationRequired", "trustedNetwork", "linkedTo"}
+
+type PartnerAPIKeyResponse struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	RateLimit     uint       `json:"rateLimit"`
+	AllowedFields []string   `json:"allowedFields"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	RevokedAt     *time.Time `json:"revokedAt,omitempty"`
+}
+
+type CreatedPartnerAPIKeyResponse struct {
+	PartnerAPIKeyResponse
+	// Key is only returned once, on creation
+	Key string `json:"key"`
+}
+
+// VerificationDataFields are the fields of the verification data a client can grant a partner access to
+var VerificationDataFields = jsonFields(VerificationDataResponse{})
+
+type ConsentGrantResponse struct {
+	ID          string     `json:"id"`
+	ClientID    string     `json:"clientId"`
+	PartnerID   string     `json:"partnerId"`
+	PartnerName string     `json:"partnerName"`
+	Fields      []string   `json:"fields"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   time.Time  `json:"expiresAt"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+}
+
+type ConsentAccessResponse struct {
+	PartnerID   string    `json:"partnerId"`
+	PartnerName string    `json:"partnerName"`
+	Fields      []string  `json:"fields"`
+	AccessedAt  time.Time `json:"accessedAt"`
+}
+
+type AccountLinkResponse struct {
+	ID              string     `json:"id"`
+	PrimaryClientID string     `json:"primaryClientId"`
+	LinkedClientID  string     `json:"linkedClientId"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	RevokedAt       *time.Time `json:"revokedAt,omitempty"`
+	RevokedBy       string     `json:"revokedBy,omitempty"`
+}
+
+type AccountLinkEventResponse struct {
+	LinkID          string `json:"linkId"`
+	PrimaryClientID string `json:"primaryClientId"`
+	LinkedClientID  string `json:"linkedClientId"`
+	Action          string `json:"action"`
+	// Actor is the client that created or revoked the link
+	Actor string    `json:"actor"`
+	At    time.Time `json:"at"`
+}
+
+type VerificationAttemptsResponse struct {
+	ClientID      string     `json:"clientId"`
+	Attempts      uint       `json:"attempts"`
+	MaxAttempts   uint       `json:"maxAttempts"`
+	LastScanRef   string     `json:"lastScanRef"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt"`
+	LastDeniedAt  *time.Time `json:"lastDeniedAt"`
+	ResetAt       *time.Time `json:"resetAt"`
+}
+
 func NewTokenResponseWithStatus(token *models.Token, isNewToken bool) *TokenResponse {
 	message := "Existing valid token retrieved."
 	if isNewToken {
@@ -133,10 +241,14 @@ func NewVerificationStatusResponse(verificationOutcome *models.VerificationOutco
 		outcome = OutcomeRejected
 	}
 	return &VerificationStatusResponse{
-		Final:     *verificationOutcome.Final,
-		IdenfyRef: verificationOutcome.IdenfyRef,
-		ClientID:  verificationOutcome.ClientID,
-		Status:    outcome,
+		Final:                  *verificationOutcome.Final,
+		IdenfyRef:              verificationOutcome.IdenfyRef,
+		ClientID:               verificationOutcome.ClientID,
+		Status:                 outcome,
+		DocumentExpiresAt:      verificationOutcome.DocumentExpiresAt,
+		ReverificationRequired: verificationOutcome.ReverificationRequired,
+		TrustedNetwork:         verificationOutcome.TrustedNetwork,
+		LinkedTo:               verificationOutcome.LinkedTo,
 	}
 }
 
@@ -198,6 +310,23 @@ func NewVerificationDataResponse(verification *models.Verification) *Verificatio
 	}
 }
 
+func NewVerificationAttemptsResponse(clientID string, attempts *models.VerificationAttempts, maxAttempts uint) *VerificationAttemptsResponse {
+	response := &VerificationAttemptsResponse{
+		ClientID:    clientID,
+		MaxAttempts: maxAttempts,
+	}
+	if attempts != nil {
+		response.Attempts = attempts.Count
+		response.LastScanRef = attempts.LastScanRef
+		if !attempts.LastAttemptAt.IsZero() {
+			response.LastAttemptAt = &attempts.LastAttemptAt
+		}
+		response.LastDeniedAt = attempts.LastDeniedAt
+		response.ResetAt = attempts.ResetAt
+	}
+	return response
+}
+
 // appConfigsResponse
 type AppConfigsResponse = config.Config
 
@@ -205,3 +334,139 @@ type AppConfigsResponse = config.Config
 type AppVersionResponse struct {
 	Version string `json:"version"`
 }
+
+func NewChallengeNonceResponse(nonce *models.ChallengeNonce, domain string) *ChallengeNonceResponse {
+	return &ChallengeNonceResponse{
+		Nonce:     nonce.Nonce,
+		Domain:    domain,
+		ExpiresAt: nonce.ExpiresAt,
+	}
+}
+
+func NewSessionTokenResponse(token *session.Token) *SessionTokenResponse {
+	return &SessionTokenResponse{
+		Token:     token.Token,
+		TokenType: "Bearer",
+		ClientID:  token.ClientID,
+		ExpiresAt: token.ExpiresAt,
+	}
+}
+
+func NewPartnerAPIKeyResponse(key *models.PartnerAPIKey) *PartnerAPIKeyResponse {
+	return &PartnerAPIKeyResponse{
+		ID:            key.ID.Hex(),
+		Name:          key.Name,
+		Prefix:        key.Prefix,
+		RateLimit:     key.RateLimit,
+		AllowedFields: key.AllowedFields,
+		CreatedAt:     key.CreatedAt,
+		RevokedAt:     key.RevokedAt,
+	}
+}
+
+func NewPartnerAPIKeysResponse(keys []models.PartnerAPIKey) []*PartnerAPIKeyResponse {
+	response := make([]*PartnerAPIKeyResponse, 0, len(keys))
+	for i := range keys {
+		response = append(response, NewPartnerAPIKeyResponse(&keys[i]))
+	}
+	return response
+}
+
+// FilterFields keeps only the allowed JSON fields of the response
+func FilterFields(response any, allowedFields []string) (map[string]any, error) {
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+	for field := range fields {
+		if !slices.Contains(allowedFields, field) {
+			delete(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+func NewConsentGrantResponse(grant *models.ConsentGrant) *ConsentGrantResponse {
+	return &ConsentGrantResponse{
+		ID:          grant.ID.Hex(),
+		ClientID:    grant.ClientID,
+		PartnerID:   grant.PartnerKeyID.Hex(),
+		PartnerName: grant.PartnerName,
+		Fields:      grant.Fields,
+		CreatedAt:   grant.CreatedAt,
+		ExpiresAt:   grant.ExpiresAt,
+		RevokedAt:   grant.RevokedAt,
+	}
+}
+
+func NewConsentGrantsResponse(grants []models.ConsentGrant) []*ConsentGrantResponse {
+	response := make([]*ConsentGrantResponse, 0, len(grants))
+	for i := range grants {
+		response = append(response, NewConsentGrantResponse(&grants[i]))
+	}
+	return response
+}
+
+func NewConsentAccessesResponse(accesses []models.ConsentAccess) []*ConsentAccessResponse {
+	response := make([]*ConsentAccessResponse, 0, len(accesses))
+	for _, access := range accesses {
+		response = append(response, &ConsentAccessResponse{
+			PartnerID:   access.PartnerKeyID.Hex(),
+			PartnerName: access.PartnerName,
+			Fields:      access.Fields,
+			AccessedAt:  access.AccessedAt,
+		})
+	}
+	return response
+}
+
+func NewAccountLinkResponse(link *models.AccountLink) *AccountLinkResponse {
+	return &AccountLinkResponse{
+		ID:              link.ID.Hex(),
+		PrimaryClientID: link.PrimaryClientID,
+		LinkedClientID:  link.LinkedClientID,
+		CreatedAt:       link.CreatedAt,
+		RevokedAt:       link.RevokedAt,
+		RevokedBy:       link.RevokedBy,
+	}
+}
+
+func NewAccountLinksResponse(links []models.AccountLink) []*AccountLinkResponse {
+	response := make([]*AccountLinkResponse, 0, len(links))
+	for i := range links {
+		response = append(response, NewAccountLinkResponse(&links[i]))
+	}
+	return response
+}
+
+func NewAccountLinkEventsResponse(events []models.AccountLinkEvent) []*AccountLinkEventResponse {
+	response := make([]*AccountLinkEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, &AccountLinkEventResponse{
+			LinkID:          event.LinkID.Hex(),
+			PrimaryClientID: event.PrimaryClientID,
+			LinkedClientID:  event.LinkedClientID,
+			Action:          string(event.Action),
+			Actor:           event.Actor,
+			At:              event.At,
+		})
+	}
+	return response
+}
+
+// jsonFields returns the JSON field names of a struct
+func jsonFields(v any) []string {
+	valueType := reflect.TypeOf(v)
+	fields := make([]string, 0, valueType.NumField())
+	for i := 0; i < valueType.NumField(); i++ {
+		name, _, _ := strings.Cut(valueType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
@@ -28,15 +28,23 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/storage/mongodb"
 	"github.com/gofiber/swagger"
+	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/threefoldtech/tf-kyc-verifier/api/docs"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/idenfy"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/substrate"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/handlers"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/jobs"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/middleware"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/migrations"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/responses"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/services"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/session"
+	bolt "go.etcd.io/bbolt"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 const (
@@ -50,9 +58,18 @@ const (
 
 // Server represents the HTTP server and its dependencies
 type Server struct {
-	app    *fiber.App
-	config *config.Config
-	logger *slog.Logger
+	app       *fiber.App
+	config    *config.Config
+	logger    *slog.Logger
+	scheduler *jobs.Scheduler
+	// mongo is only connected with the mongodb storage backend
+	mongo *mongo.Database
+	// postgres is only connected with the postgres storage backend
+	postgres *pgxpool.Pool
+	// embedded is only opened with the embedded storage backend
+	embedded *bolt.DB
+	// expiryCleaners delete the expired entries of the stores without TTL indexes
+	expiryCleaners []jobs.ExpiredEntriesCleaner
 }
 
 // New creates a new server instance with the given configuration and options
@@ -85,31 +102,53 @@ func New(config *config.Config, srvLogger *slog.Logger) (*Server, error) {
 
 // initializeCore sets up the core components of the server
 func (s *Server) initializeCore(ctx context.Context) error {
-	// Setup middleware
-	if err := s.setupMiddleware(); err != nil {
-		return fmt.Errorf("setting up middleware: %w", err)
+	// Connect to TFChain, its network scopes the tokens and verifications
+	substrateClient, scope, err := s.setupSubstrate(&s.config.TFChain)
+	if err != nil {
+		return fmt.Errorf("setting up substrate client: %w", err)
 	}
 
+	return s.initializeStorage(ctx, substrateClient, scope)
+}
+
+// initializeStorage sets up the database of the storage backend and the components using it
+func (s *Server) initializeStorage(ctx context.Context, substrateClient substrate.SubstrateClient, scope repository.Scope) error {
 	// Setup database
-	dbClient, db, err := s.setupDatabase(ctx)
-	if err != nil {
+	if err := s.setupDatabase(ctx); err != nil {
 		return fmt.Errorf("setting up database: %w", err)
 	}
 
+	// Apply database migrations
+	if err := s.migrateDatabase(ctx, scope); err != nil {
+		return fmt.Errorf("migrating database: %w", err)
+	}
+
+	// Setup middleware, the rate limiters are stored in the database
+	if err := s.setupMiddleware(); err != nil {
+		return fmt.Errorf("setting up middleware: %w", err)
+	}
+
 	// Setup repositories
-	repos, err := s.setupRepositories(ctx, db)
+	repos, err := s.setupRepositories(scope)
 	if err != nil {
 		return fmt.Errorf("setting up repositories: %w", err)
 	}
 
-	// Setup services
-	service, err := s.setupServices(repos)
+	// Setup services, one set per network sharing the iDenfy client
+	idenfyClient := idenfy.New(&s.config.Idenfy, s.logger)
+	networks, err := s.setupNetworks(repos, idenfyClient, substrateClient, scope)
 	if err != nil {
 		return fmt.Errorf("setting up services: %w", err)
 	}
 
+	challengeService := services.NewChallengeService(repos.challenge, &s.config.Challenge, s.logger)
+	partnerService := services.NewPartnerService(repos.partnerKey, s.logger)
+
+	// Setup background jobs
+	s.setupJobs(networks)
+
 	// Setup routes
-	if err := s.setupRoutes(service, dbClient); err != nil {
+	if err := s.setupRoutes(networks, challengeService, partnerService, idenfyClient); err != nil {
 		return fmt.Errorf("setting up routes: %w", err)
 	}
 
@@ -120,19 +159,8 @@ func (s *Server) setupMiddleware() error {
 	s.logger.Debug("Setting up middleware")
 
 	// Setup rate limiter stores
-	ipLimiterStore := mongodb.New(mongodb.Config{
-		ConnectionURI: s.config.MongoDB.URI,
-		Database:      s.config.MongoDB.DatabaseName,
-		Collection:    "ip_limit",
-		Reset:         false,
-	})
-
-	idLimiterStore := mongodb.New(mongodb.Config{
-		ConnectionURI: s.config.MongoDB.URI,
-		Database:      s.config.MongoDB.DatabaseName,
-		Collection:    "id_limit",
-		Reset:         false,
-	})
+	ipLimiterStore := s.newLimiterStorage("ip_limit")
+	idLimiterStore := s.newLimiterStorage("id_limit")
 
 	// Configure rate limiters
 	ipLimiterConfig := limiter.Config{
@@ -153,7 +181,16 @@ func (s *Server) setupMiddleware() error {
 		Expiration: time.Duration(s.config.IDLimiter.TokenExpiration) * time.Minute,
 		Storage:    idLimiterStore,
 		KeyGenerator: func(c *fiber.Ctx) string {
-			return c.Get("X-Client-ID")
+			if clientID := c.Get("X-Client-ID"); clientID != "" {
+				// other encodings of the same key share the same quota
+				if canonicalClientID, err := address.Normalize(clientID, s.config.Challenge.AllowedSS58Prefixes); err == nil {
+					return canonicalClientID
+				}
+				return clientID
+			}
+			// failed requests are skipped, so a forged session token can't consume another client's quota
+			token, _ := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+			return session.UnverifiedClientID(token)
 		},
 		SkipFailedRequests: true,
 	}
@@ -166,89 +203,449 @@ func (s *Server) setupMiddleware() error {
 	}))
 	s.app.Use(helmet.New())
 
+	// the limiters are shared by the networks, a client has the same quota on all of them
+	tokenRoutes := []string{"/api/v1/token", "/networks/:network/api/v1/token"}
 	if s.config.IPLimiter.MaxTokenRequests > 0 {
-		s.app.Use("/api/v1/token", limiter.New(ipLimiterConfig))
+		ipLimiter := limiter.New(ipLimiterConfig)
+		for _, route := range tokenRoutes {
+			s.app.Use(route, ipLimiter)
+		}
 	}
 	if s.config.IDLimiter.MaxTokenRequests > 0 {
-		s.app.Use("/api/v1/token", limiter.New(idLimiterConfig))
+		idLimiter := limiter.New(idLimiterConfig)
+		for _, route := range tokenRoutes {
+			s.app.Use(route, idLimiter)
+		}
+	}
+	// each nonce is stored until it expires, so an IP can only request a few of them
+	if s.config.Challenge.NonceRateLimit > 0 {
+		nonceLimiter := limiter.New(limiter.Config{
+			Max:          int(s.config.Challenge.NonceRateLimit),
+			Expiration:   time.Minute,
+			Storage:      s.newLimiterStorage("nonce_limit"),
+			KeyGenerator: ipLimiterConfig.KeyGenerator,
+			Next:         ipLimiterConfig.Next,
+		})
+		for _, route := range []string{"/api/v1/challenge/nonce", "/networks/:network/api/v1/challenge/nonce"} {
+			s.app.Use(route, nonceLimiter)
+		}
 	}
 
 	return nil
 }
 
-func (s *Server) setupDatabase(ctx context.Context) (*mongo.Client, *mongo.Database, error) {
-	s.logger.Debug("Connecting to database")
+// newLimiterStorage returns the rate limiter storage of the configured backend, each limiter has its own collection or bucket
+func (s *Server) newLimiterStorage(name string) fiber.Storage {
+	if s.postgres != nil {
+		storage := repository.NewPostgresLimiterStorage(s.postgres, name)
+		s.expiryCleaners = append(s.expiryCleaners, storage)
+		return storage
+	}
+	if s.embedded != nil {
+		storage := repository.NewBoltLimiterStorage(s.embedded, name)
+		s.expiryCleaners = append(s.expiryCleaners, storage)
+		return storage
+	}
+	return mongodb.New(mongodb.Config{
+		ConnectionURI: s.config.MongoDB.URI,
+		Database:      s.config.MongoDB.DatabaseName,
+		Collection:    name,
+		Reset:         false,
+	})
+}
+
+// setupDatabase connects to the database of the configured storage backend
+func (s *Server) setupDatabase(ctx context.Context) error {
+	s.logger.Debug("Connecting to database", "backend", s.config.Storage.Backend)
+
+	var err error
+	switch s.config.Storage.Backend {
+	case "postgres":
+		s.postgres, err = repository.NewPostgresPool(ctx, s.config.Postgres.URL)
+		if err != nil {
+			return fmt.Errorf("setting up database: %w", err)
+		}
+	case "embedded":
+		// the buckets are created on open, the embedded database has no migrations
+		s.embedded, err = repository.NewBoltDB(s.config.Embedded.Path)
+		if err != nil {
+			return fmt.Errorf("setting up database: %w", err)
+		}
+	default:
+		s.mongo, err = s.connectMongo(ctx)
+		if err != nil {
+			return fmt.Errorf("setting up database: %w", err)
+		}
+	}
+	return nil
+}
 
+func (s *Server) connectMongo(ctx context.Context) (*mongo.Database, error) {
 	client, err := repository.NewMongoClient(ctx, s.config.MongoDB.URI)
 	if err != nil {
-		return nil, nil, fmt.Errorf("setting up database: %w", err)
+		return nil, err
+	}
+	return client.Database(s.config.MongoDB.DatabaseName), nil
+}
+
+// migrateDatabase applies the pending migrations, or refuses to start with pending migrations if they are run through the migrate command
+func (s *Server) migrateDatabase(ctx context.Context, scope repository.Scope) error {
+	migrators, err := databaseMigrators(s.mongo, s.postgres, scope, s.logger)
+	if err != nil {
+		return err
+	}
+	for _, migrator := range migrators {
+		if !s.config.Migrations.OnStartup {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d pending migrations, run the migrate command first", len(pending))
+			}
+			continue
+		}
+		if _, err := migrator.Up(ctx, false); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	return client, client.Database(s.config.MongoDB.DatabaseName), nil
+// databaseMigrators returns the migrators of the connected MongoDB and PostgreSQL databases
+func databaseMigrators(db *mongo.Database, pool *pgxpool.Pool, scope repository.Scope, logger *slog.Logger) ([]*migrations.Migrator, error) {
+	migrators := []*migrations.Migrator{}
+	if db != nil {
+		migrator, err := migrations.NewMongo(db, scope, logger)
+		if err != nil {
+			return nil, err
+		}
+		migrators = append(migrators, migrator)
+	}
+	if pool != nil {
+		migrator, err := migrations.NewPostgres(pool, scope, logger)
+		if err != nil {
+			return nil, err
+		}
+		migrators = append(migrators, migrator)
+	}
+	return migrators, nil
+}
+
+// Migrate connects to the databases and applies their pending migrations, or only lists them in dry-run mode
+func Migrate(ctx context.Context, config *config.Config, logger *slog.Logger, dryRun bool) error {
+	var db *mongo.Database
+	var pool *pgxpool.Pool
+	switch config.Storage.Backend {
+	case "postgres":
+		var err error
+		pool, err = repository.NewPostgresPool(ctx, config.Postgres.URL)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+	case "embedded":
+		logger.Info("The embedded storage backend has no migrations")
+		return nil
+	default:
+		client, err := repository.NewMongoClient(ctx, config.MongoDB.URI)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(context.Background())
+		db = client.Database(config.MongoDB.DatabaseName)
+	}
+
+	substrateClient, err := substrate.New(&config.TFChain, logger)
+	if err != nil {
+		return fmt.Errorf("initializing substrate client: %w", err)
+	}
+	scope, err := services.GetScope(substrateClient, config)
+	if err != nil {
+		return err
+	}
+	migrators, err := databaseMigrators(db, pool, scope, logger)
+	if err != nil {
+		return err
+	}
+	for _, migrator := range migrators {
+		migrated, err := migrator.Up(ctx, dryRun)
+		if err != nil {
+			return err
+		}
+		logger.Info("Migrations done", "dryRun", dryRun, "migrations", len(migrated))
+	}
+	return nil
 }
 
 type repositories struct {
 	token        repository.TokenRepository
 	verification repository.VerificationRepository
+	attempt      repository.AttemptRepository
+	fingerprint  repository.FingerprintRepository
+	challenge    repository.ChallengeRepository
+	partnerKey   repository.PartnerKeyRepository
+	consent      repository.ConsentRepository
+	lock         repository.LockRepository
+	link         repository.LinkRepository
+	session      repository.SessionRepository
 }
 
-func (s *Server) setupRepositories(ctx context.Context, db *mongo.Database) (*repositories, error) {
+// setupRepositories sets up the repositories of the configured storage backend
+func (s *Server) setupRepositories(scope repository.Scope) (*repositories, error) {
 	s.logger.Debug("Setting up repositories")
 
-	return &repositories{
-		token:        repository.NewMongoTokenRepository(ctx, db, s.logger),
-		verification: repository.NewMongoVerificationRepository(ctx, db, s.logger),
-	}, nil
+	var repos *repositories
+	switch {
+	case s.postgres != nil:
+		repos = &repositories{
+			challenge:  repository.NewPostgresChallengeRepository(s.postgres, s.logger),
+			partnerKey: repository.NewPostgresPartnerKeyRepository(s.postgres, s.logger),
+			lock:       repository.NewPostgresLockRepository(s.postgres, s.logger),
+			session:    repository.NewPostgresSessionRepository(s.postgres, s.logger),
+		}
+	case s.embedded != nil:
+		repos = &repositories{
+			challenge:  repository.NewBoltChallengeRepository(s.embedded, s.logger),
+			partnerKey: repository.NewBoltPartnerKeyRepository(s.embedded, s.logger),
+			lock:       repository.NewBoltLockRepository(s.embedded, s.logger),
+			session:    repository.NewBoltSessionRepository(s.embedded, s.logger),
+		}
+	default:
+		repos = &repositories{
+			challenge:  repository.NewMongoChallengeRepository(s.mongo, s.logger),
+			partnerKey: repository.NewMongoPartnerKeyRepository(s.mongo, s.logger),
+			lock:       repository.NewMongoLockRepository(s.mongo, s.logger),
+			session:    repository.NewMongoSessionRepository(s.mongo, s.logger),
+		}
+	}
+	s.scopeRepositories(repos, scope)
+	// the expired entries of all scopes are deleted at once, the MongoDB repositories rely on TTL indexes instead
+	for _, repo := range []any{repos.token, repos.challenge, repos.partnerKey, repos.lock, repos.session} {
+		if cleaner, ok := repo.(jobs.ExpiredEntriesCleaner); ok {
+			s.expiryCleaners = append(s.expiryCleaners, cleaner)
+		}
+	}
+	return repos, nil
+}
+
+// scopeRepositories sets the repositories of the client records of the scope, in the configured storage backend.
+// A network only sees its own tokens, verifications, attempts, consents, links and identity fingerprints.
+func (s *Server) scopeRepositories(repos *repositories, scope repository.Scope) {
+	switch {
+	case s.postgres != nil:
+		repos.token = repository.NewPostgresTokenRepository(s.postgres, scope, s.logger)
+		repos.verification = repository.NewPostgresVerificationRepository(s.postgres, scope, s.logger)
+		repos.attempt = repository.NewPostgresAttemptRepository(s.postgres, scope, s.logger)
+		repos.consent = repository.NewPostgresConsentRepository(s.postgres, scope, s.logger)
+		repos.link = repository.NewPostgresLinkRepository(s.postgres, scope, s.logger)
+		repos.fingerprint = repository.NewPostgresFingerprintRepository(s.postgres, scope, s.logger)
+	case s.embedded != nil:
+		repos.token = repository.NewBoltTokenRepository(s.embedded, scope, s.logger)
+		repos.verification = repository.NewBoltVerificationRepository(s.embedded, scope, s.logger)
+		repos.attempt = repository.NewBoltAttemptRepository(s.embedded, scope, s.logger)
+		repos.consent = repository.NewBoltConsentRepository(s.embedded, scope, s.logger)
+		repos.link = repository.NewBoltLinkRepository(s.embedded, scope, s.logger)
+		repos.fingerprint = repository.NewBoltFingerprintRepository(s.embedded, scope, s.logger)
+	default:
+		repos.token = repository.NewMongoTokenRepository(s.mongo, scope, s.logger)
+		repos.verification = repository.NewMongoVerificationRepository(s.mongo, scope, s.logger)
+		repos.attempt = repository.NewMongoAttemptRepository(s.mongo, scope, s.logger)
+		repos.consent = repository.NewMongoConsentRepository(s.mongo, scope, s.logger)
+		repos.link = repository.NewMongoLinkRepository(s.mongo, scope, s.logger)
+		repos.fingerprint = repository.NewMongoFingerprintRepository(s.mongo, scope, s.logger)
+	}
+}
+
+// setupSubstrate connects to the TFChain network and returns its scope
+func (s *Server) setupSubstrate(network substrate.WsProviderURLGetter) (substrate.SubstrateClient, repository.Scope, error) {
+	s.logger.Debug("Connecting to TFChain", "url", network.GetWsProviderURL())
+
+	substrateClient, err := substrate.New(network, s.logger)
+	if err != nil {
+		return nil, repository.Scope{}, fmt.Errorf("initializing substrate client: %w", err)
+	}
+	scope, err := services.GetScope(substrateClient, s.config)
+	if err != nil {
+		return nil, repository.Scope{}, err
+	}
+	return substrateClient, scope, nil
 }
 
-func (s *Server) setupServices(repos *repositories) (*services.KYCService, error) {
+// setupNetworks sets up the services of the default network and of the networks of the networks file.
+// The networks share the iDenfy client, the partner keys, the locks and the sessions, the client records are scoped by network.
+func (s *Server) setupNetworks(repos *repositories, idenfyClient idenfy.IdenfyClient, substrateClient substrate.SubstrateClient, scope repository.Scope) (*services.Networks, error) {
 	s.logger.Debug("Setting up services")
 
-	idenfyClient := idenfy.New(&s.config.Idenfy, s.logger)
+	if s.config.Verification.FingerprintKey == "" {
+		s.logger.Warn("Verification FingerprintKey is not set. Duplicate identities across accounts will only be detected using iDenfy duplicate faces.", "duplicateIdentityOutcome", s.config.Verification.DuplicateIdentityOutcome)
+	}
 
-	substrateClient, err := substrate.New(&s.config.TFChain, s.logger)
+	defaultNetwork, err := s.newNetwork(scope.Network, s.config.Challenge, repos, idenfyClient, substrateClient)
 	if err != nil {
-		return nil, fmt.Errorf("initializing substrate client: %w", err)
+		return nil, err
 	}
+	others := []*services.Network{}
+	for _, networkConfig := range s.config.Networks.List {
+		substrateClient, scope, err := s.setupSubstrate(&networkConfig)
+		if err != nil {
+			return nil, fmt.Errorf("setting up network %s: %w", networkConfig.Name, err)
+		}
+		networkRepos := *repos
+		s.scopeRepositories(&networkRepos, scope)
+		challenge := s.config.Challenge
+		challenge.Domain = networkConfig.ChallengeDomain
+		if len(networkConfig.SS58Prefixes) > 0 {
+			challenge.AllowedSS58Prefixes = networkConfig.SS58Prefixes
+		}
+		network, err := s.newNetwork(networkConfig.Name, challenge, &networkRepos, idenfyClient, substrateClient)
+		if err != nil {
+			return nil, fmt.Errorf("setting up network %s: %w", networkConfig.Name, err)
+		}
+		others = append(others, network)
+	}
+	networks, err := services.NewNetworks(defaultNetwork, others...)
+	if err != nil {
+		return nil, err
+	}
+	if err := networks.Trust(s.config.Networks.Trust); err != nil {
+		return nil, fmt.Errorf("setting up networks trust: %w", err)
+	}
+	return networks, nil
+}
+
+func (s *Server) newNetwork(name string, challenge config.Challenge, repos *repositories, idenfyClient idenfy.IdenfyClient, substrateClient substrate.SubstrateClient) (*services.Network, error) {
+	logger := s.logger.With("network", name)
 	kycService, err := services.NewKYCService(
 		repos.verification,
 		repos.token,
+		repos.attempt,
+		repos.fingerprint,
+		repos.lock,
+		repos.link,
+		repos.session,
 		idenfyClient,
 		substrateClient,
+		challenge.AllowedSS58Prefixes,
 		s.config,
-		s.logger,
+		logger,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return kycService, nil
+	// link and consent statements are signed with the same schemes as the challenges of the network
+	verifySignature := func(clientID string, signature string, message string, scheme string) error {
+		schemes, err := middleware.SignatureSchemes(scheme, challenge.AllowEcdsa)
+		if err != nil {
+			return err
+		}
+		return middleware.VerifySubstrateSignature(clientID, signature, message, schemes...)
+	}
+	network := &services.Network{
+		Name:      name,
+		Challenge: challenge,
+		KYC:       kycService,
+		Consent:   services.NewConsentService(repos.consent, repos.partnerKey, repos.verification, challenge, &s.config.Consent, responses.VerificationDataFields, verifySignature, logger),
+		Links:     services.NewLinkService(repos.link, kycService, challenge, &s.config.Links, verifySignature, logger),
+	}
+	// Session tokens, only enabled when a session signing key is configured, are issued by the challenge domain
+	if s.config.Session.SigningKey != "" {
+		network.Sessions = session.NewManager(s.config.Session, challenge.Domain)
+	}
+	return network, nil
 }
 
-func (s *Server) setupRoutes(kycService *services.KYCService, mongoCl *mongo.Client) error {
+func (s *Server) setupRoutes(networks *services.Networks, challengeService *services.ChallengeService, partnerService *services.PartnerService, idenfyClient *idenfy.Idenfy) error {
 	s.logger.Debug("Setting up routes")
 
-	handler := handlers.NewHandler(kycService, s.config, s.logger)
+	handler := handlers.NewHandler(networks, challengeService, partnerService, s.config, s.logger)
 
-	// API routes
-	v1 := s.app.Group("/api/v1")
-	v1.Post("/token", middleware.AuthMiddleware(s.config.Challenge), handler.GetOrCreateVerificationToken())
-	v1.Get("/data", middleware.AuthMiddleware(s.config.Challenge), handler.GetVerificationData())
-	v1.Get("/status", handler.GetVerificationStatus())
-	v1.Get("/health", handler.HealthCheck(mongoCl))
-	v1.Get("/configs", handler.GetServiceConfigs())
-	v1.Get("/version", handler.GetServiceVersion())
+	// only the databases of the storage backend are checked
+	healthChecks := []handlers.DependencyCheck{}
+	if s.mongo != nil {
+		healthChecks = append(healthChecks, func(ctx context.Context) error { return s.mongo.Client().Ping(ctx, readpref.Primary()) })
+	}
+	if s.postgres != nil {
+		healthChecks = append(healthChecks, func(ctx context.Context) error {
+			if err := s.postgres.Ping(ctx); err != nil {
+				return fmt.Errorf("pinging PostgreSQL: %w", err)
+			}
+			return nil
+		})
+	}
+	if s.embedded != nil {
+		healthChecks = append(healthChecks, func(ctx context.Context) error {
+			return s.embedded.View(func(tx *bolt.Tx) error { return nil })
+		})
+	}
+
+	// API routes, the network is selected by the network header or by the /networks/:network path prefix
+	for _, prefix := range []string{"/api/v1", "/networks/:network/api/v1"} {
+		v1 := s.app.Group(prefix, middleware.NetworkMiddleware(networks, s.config.Networks.Header))
+		s.setupAPIRoutes(v1, handler, networks.Default, challengeService, partnerService, idenfyClient.Stats, healthChecks)
+	}
 
-	// Webhook routes
+	// Webhook routes, the network is selected by the clientId suffix
 	webhooks := s.app.Group("/webhooks/idenfy")
 	webhooks.Post("/verification-update", handler.ProcessVerificationResult())
 	webhooks.Post("/id-expiration", handler.ProcessDocExpirationNotification())
 
+	// Metrics, in the Prometheus text format
+	s.app.Get("/metrics", handler.Metrics(idenfyClient.Stats))
+
 	// Documentation
 	s.app.Get("/docs/*", swagger.HandlerDefault)
 
 	return nil
 }
 
+// setupAPIRoutes registers the API routes, the auth middleware uses the challenge domain and session tokens of the request network
+func (s *Server) setupAPIRoutes(v1 fiber.Router, handler *handlers.Handler, defaultNetwork *services.Network, challengeService *services.ChallengeService, partnerService *services.PartnerService, idenfyStats handlers.IdenfyStats, healthChecks []handlers.DependencyCheck) {
+	// Session tokens, only enabled when a session signing key is configured
+	var sessionVerifier middleware.SessionVerifier
+	if defaultNetwork.Sessions != nil {
+		sessionVerifier = defaultNetwork.Sessions
+	}
+
+	v1.Post("/challenge/nonce", handler.IssueChallengeNonce())
+	if sessionVerifier != nil {
+		// login requires a signed challenge, so a session can't be extended with its own token
+		v1.Post("/auth/login", middleware.AuthMiddleware(defaultNetwork.Challenge, challengeService, nil), handler.Login())
+	}
+	v1.Post("/token", middleware.AuthMiddleware(defaultNetwork.Challenge, challengeService, sessionVerifier), handler.GetOrCreateVerificationToken())
+	v1.Get("/data", middleware.AuthMiddleware(defaultNetwork.Challenge, challengeService, sessionVerifier), handler.GetVerificationData())
+	v1.Get("/claims", middleware.AuthMiddleware(defaultNetwork.Challenge, challengeService, sessionVerifier), handler.GetVerificationClaims())
+	v1.Get("/status", middleware.PartnerAPIKeyMiddleware(s.config.Partner.AllowAnonymousStatus, partnerService), handler.GetVerificationStatus())
+
+	// Consent routes, clients grant partners access to part of their verification data
+	consents := v1.Group("/consents", middleware.AuthMiddleware(defaultNetwork.Challenge, challengeService, sessionVerifier))
+	consents.Post("/", handler.CreateConsentGrant())
+	consents.Get("/", handler.ListConsentGrants())
+	consents.Delete("/:id", handler.RevokeConsentGrant())
+	consents.Get("/:id/accesses", handler.ListConsentAccesses())
+	v1.Get("/partner/data", middleware.PartnerAPIKeyMiddleware(false, partnerService), handler.GetConsentedVerificationData())
+
+	// Account link routes, verified clients link other accounts they control
+	links := v1.Group("/links", middleware.AuthMiddleware(defaultNetwork.Challenge, challengeService, sessionVerifier))
+	links.Post("/", handler.CreateAccountLink())
+	links.Get("/", handler.ListAccountLinks())
+	links.Get("/events", handler.ListAccountLinkEvents())
+	links.Delete("/:id", handler.RevokeAccountLink())
+	v1.Get("/health", handler.HealthCheck(idenfyStats, healthChecks...))
+	v1.Get("/configs", handler.GetServiceConfigs())
+	v1.Get("/version", handler.GetServiceVersion())
+
+	// Admin routes, only enabled when an admin API key is configured
+	if s.config.Admin.APIKey != "" {
+		admin := v1.Group("/admin", middleware.AdminAuthMiddleware(s.config.Admin.APIKey))
+		admin.Get("/attempts/:client_id", handler.GetVerificationAttempts())
+		admin.Post("/attempts/:client_id/reset", handler.ResetVerificationAttempts())
+		admin.Post("/partner-keys", handler.CreatePartnerAPIKey())
+		admin.Get("/partner-keys", handler.ListPartnerAPIKeys())
+		admin.Delete("/partner-keys/:id", handler.RevokePartnerAPIKey())
+	}
+}
+
 func extractIPFromRequest(c *fiber.Ctx) string {
 	// Check for X-Forwarded-For header
 	if ip := c.Get("X-Forwarded-For"); ip != "" {
@@ -277,18 +674,59 @@ func extractIPFromRequest(c *fiber.Ctx) string {
 	return LOOPBACK
 }
 
+func (s *Server) setupJobs(networks *services.Networks) {
+	s.logger.Debug("Setting up background jobs")
+	s.scheduler = jobs.NewScheduler(s.logger)
+	for _, network := range networks.List() {
+		s.scheduler.Every(
+			time.Duration(s.config.Verification.DocumentExpiryCheckInterval)*time.Minute,
+			jobs.NewDocumentExpiryJob(network.KYC, s.config.Verification.DocumentExpiryWarningDays, s.logger.With("network", network.Name)),
+		)
+		s.scheduler.Every(
+			time.Duration(s.config.Verification.ReconciliationInterval)*time.Minute,
+			jobs.NewReconciliationJob(network.KYC, s.logger.With("network", network.Name)),
+		)
+	}
+	if len(s.expiryCleaners) > 0 {
+		cleanupInterval := s.config.Postgres.CleanupInterval
+		if s.embedded != nil {
+			cleanupInterval = s.config.Embedded.CleanupInterval
+		}
+		s.scheduler.Every(
+			time.Duration(cleanupInterval)*time.Minute,
+			jobs.NewExpiryCleanupJob(s.config.Storage.Backend+"-expiry-cleanup", s.logger, s.expiryCleaners...),
+		)
+	}
+}
+
 func (s *Server) Run() error {
+	s.scheduler.Start()
+
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 		<-sigChan
 		// Graceful shutdown
 		s.logger.Info("Shutting down server...")
+		s.scheduler.Stop()
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 		if err := s.app.ShutdownWithContext(ctx); err != nil {
 			s.logger.Error("Server forced to shutdown:", slog.String("error", err.Error()))
 		}
+		if s.mongo != nil {
+			if err := s.mongo.Client().Disconnect(context.Background()); err != nil {
+				s.logger.Error("Failed to disconnect from MongoDB", slog.String("error", err.Error()))
+			}
+		}
+		if s.postgres != nil {
+			s.postgres.Close()
+		}
+		if s.embedded != nil {
+			if err := s.embedded.Close(); err != nil {
+				s.logger.Error("Failed to close embedded database", slog.String("error", err.Error()))
+			}
+		}
 	}()
 
 	// Start server
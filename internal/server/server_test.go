@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/responses"
+)
+
+type fakeSubstrate struct{}
+
+func (f *fakeSubstrate) GetChainName() (string, error)                     { return "TFChain Devnet", nil }
+func (f *fakeSubstrate) GetAddressByTwinID(twinID uint32) (string, error)  { return "", nil }
+func (f *fakeSubstrate) GetTwinIDByAddress(address string) (uint32, error) { return 0, nil }
+func (f *fakeSubstrate) GetAccountBalance(address string) (uint64, error)  { return 0, nil }
+
+func TestEmbeddedStartupWithoutMongo(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "embedded")
+	t.Setenv("EMBEDDED_PATH", filepath.Join(t.TempDir(), "tf-kyc.db"))
+	t.Setenv("MONGO_URI", "")
+	t.Setenv("IDENFY_API_KEY", "key")
+	t.Setenv("IDENFY_API_SECRET", "secret")
+	t.Setenv("IDENFY_CALLBACK_SIGN_KEY", "callback-sign-key-of-32-characters")
+	t.Setenv("IDENFY_CALLBACK_URL", "https://kyc.dev.grid.tf/webhooks/idenfy/verification-update")
+	t.Setenv("CHALLENGE_DOMAIN", "kyc.dev.grid.tf")
+	cfg, err := config.LoadConfigFromEnv()
+	require.NoError(t, err)
+
+	s := &Server{config: cfg, logger: slog.New(slog.NewTextHandler(io.Discard, nil)), app: fiber.New()}
+	require.NoError(t, s.initializeStorage(context.Background(), &fakeSubstrate{}, repository.Scope{Network: "devnet"}))
+	t.Cleanup(func() { s.embedded.Close() })
+	assert.Nil(t, s.mongo)
+	assert.Nil(t, s.postgres)
+
+	// the health check only covers the embedded database
+	resp, err := s.app.Test(httptest.NewRequest(fiber.MethodGet, "/api/v1/health", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	var health struct {
+		Result responses.HealthResponse `json:"result"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.Equal(t, responses.HealthStatusHealthy, health.Result.Status)
+	assert.Empty(t, health.Result.Errors)
+
+	// the challenge nonces are stored in the embedded database too
+	resp, err = s.app.Test(httptest.NewRequest(fiber.MethodPost, "/api/v1/challenge/nonce", nil))
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusCreated, resp.StatusCode)
+}
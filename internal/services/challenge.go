@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+// NONCE_SIZE is the number of random bytes in a challenge nonce
+const NONCE_SIZE = 16
+
+// ChallengeService issues the nonces signed by clients and makes sure each of them is accepted only once
+type ChallengeService struct {
+	challengeRepo repository.ChallengeRepository
+	config        *config.Challenge
+	logger        *slog.Logger
+}
+
+func NewChallengeService(challengeRepo repository.ChallengeRepository, config *config.Challenge, logger *slog.Logger) *ChallengeService {
+	return &ChallengeService{challengeRepo: challengeRepo, config: config, logger: logger}
+}
+
+// IssueNonce creates a new nonce, valid for the configured nonce TTL
+func (s *ChallengeService) IssueNonce(ctx context.Context) (*models.ChallengeNonce, error) {
+	nonceBytes := make([]byte, NONCE_SIZE)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		return nil, errors.NewInternalError("generating nonce", err)
+	}
+	now := time.Now()
+	nonce := &models.ChallengeNonce{
+		Nonce:     hex.EncodeToString(nonceBytes),
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(s.config.NonceTTL) * time.Second),
+	}
+	if err := s.challengeRepo.SaveNonce(ctx, nonce); err != nil {
+		s.logger.Error("Error saving nonce to database", "error", err)
+		return nil, errors.NewInternalError("saving nonce to database", err)
+	}
+	return nonce, nil
+}
+
+// ConsumeNonce marks the nonce as used by the client, it fails if the nonce was not issued, expired or was already used
+func (s *ChallengeService) ConsumeNonce(ctx context.Context, clientID string, nonce string) error {
+	consumed, err := s.challengeRepo.ConsumeNonce(ctx, nonce, clientID, time.Now())
+	if err != nil {
+		s.logger.Error("Error consuming nonce in database", "clientID", clientID, "error", err)
+		return errors.NewInternalError("consuming nonce in database", err)
+	}
+	if !consumed {
+		s.logger.Warn("Rejected challenge with unknown, expired or already used nonce", "clientID", clientID)
+		return errors.NewAuthorizationError("bad challenge: nonce is unknown, expired or already used", nil)
+	}
+	return nil
+}
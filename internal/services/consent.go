@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/claims"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CONSENT_STATEMENT_ACTION is the action of the `{api-domain}:{timestamp}:consent:{partnerId}:{fields}` consent statements
+const CONSENT_STATEMENT_ACTION = "consent"
+
+// ConsentRequest is a consent statement signed by the client, granting the partner the comma-separated fields for the duration
+type ConsentRequest struct {
+	Statement       string
+	Signature       string
+	SignatureScheme string
+	Duration        time.Duration
+}
+
+// ConsentService manages the consent grants clients give partner services to fetch part of their verification data
+type ConsentService struct {
+	consentRepo      repository.ConsentRepository
+	partnerKeyRepo   repository.PartnerKeyRepository
+	verificationRepo repository.VerificationRepository
+	challenge        config.Challenge
+	config           *config.Consent
+	// dataFields are the raw verification data fields a grant can name, next to the derived claims
+	dataFields      []string
+	verifySignature SignatureVerifier
+	logger          *slog.Logger
+}
+
+func NewConsentService(consentRepo repository.ConsentRepository, partnerKeyRepo repository.PartnerKeyRepository, verificationRepo repository.VerificationRepository, challenge config.Challenge, config *config.Consent, dataFields []string, verifySignature SignatureVerifier, logger *slog.Logger) *ConsentService {
+	return &ConsentService{
+		consentRepo:      consentRepo,
+		partnerKeyRepo:   partnerKeyRepo,
+		verificationRepo: verificationRepo,
+		challenge:        challenge,
+		config:           config,
+		dataFields:       dataFields,
+		verifySignature:  verifySignature,
+		logger:           logger,
+	}
+}
+
+// CreateGrant lets the partner named by the statement fetch its fields of the client for the requested duration.
+// The client must sign the statement, a field is either a raw verification data field or a claim derived from the verification.
+func (s *ConsentService) CreateGrant(ctx context.Context, clientID string, request ConsentRequest) (*models.ConsentGrant, error) {
+	maxDuration := time.Duration(s.config.MaxDuration) * time.Minute
+	if request.Duration <= 0 || request.Duration > maxDuration {
+		return nil, errors.NewValidationError(fmt.Sprintf("consent duration should be between 1 minute and %s", maxDuration), nil)
+	}
+	partnerID, fields, err := s.parseStatement(request.Statement)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(clientID, request.Signature, request.Statement, request.SignatureScheme); err != nil {
+		return nil, err
+	}
+	partnerKey, err := s.partnerKeyRepo.GetKey(ctx, partnerID)
+	if err != nil {
+		s.logger.Error("Error getting partner API key from database", "partnerID", partnerID.Hex(), "error", err)
+		return nil, errors.NewInternalError("getting partner API key from database", err)
+	}
+	if partnerKey == nil || partnerKey.Revoked() {
+		return nil, errors.NewNotFoundError("partner not found", nil)
+	}
+	now := time.Now()
+	grant := &models.ConsentGrant{
+		ClientID:     clientID,
+		PartnerKeyID: partnerKey.ID,
+		PartnerName:  partnerKey.Name,
+		Fields:       fields,
+		Statement:    request.Statement,
+		Signature:    request.Signature,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(request.Duration),
+	}
+	if err := s.consentRepo.SaveGrant(ctx, grant); err != nil {
+		s.logger.Error("Error saving consent grant to database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("saving consent grant to database", err)
+	}
+	s.logger.Info("Consent granted", "clientID", clientID, "partner", partnerKey.Name, "fields", fields, "expiresAt", grant.ExpiresAt)
+	return grant, nil
+}
+
+// parseStatement checks the statement is a fresh consent statement for this domain, and returns the partner and the granted fields
+func (s *ConsentService) parseStatement(statement string) (primitive.ObjectID, []string, error) {
+	statementBytes, err := hex.DecodeString(strings.TrimPrefix(statement, "0x"))
+	if err != nil {
+		return primitive.NilObjectID, nil, errors.NewValidationError("malformed statement: failed to decode hex-encoded statement", err)
+	}
+	parts := strings.Split(string(statementBytes), ":")
+	if len(parts) != 5 || parts[2] != CONSENT_STATEMENT_ACTION {
+		return primitive.NilObjectID, nil, errors.NewValidationError("malformed statement: expected `{api-domain}:{timestamp}:consent:{partnerId}:{fields}`", nil)
+	}
+	if parts[0] != s.challenge.Domain {
+		return primitive.NilObjectID, nil, errors.NewValidationError("bad statement: unexpected domain", nil)
+	}
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return primitive.NilObjectID, nil, errors.NewValidationError("bad statement: invalid timestamp", nil)
+	}
+	age := time.Now().Unix() - timestamp
+	if age > s.challenge.Window+s.challenge.ClockSkew || -age > s.challenge.ClockSkew {
+		return primitive.NilObjectID, nil, errors.NewValidationError("bad statement: statement expired or timestamp in the future", nil)
+	}
+	partnerID, err := primitive.ObjectIDFromHex(parts[3])
+	if err != nil {
+		return primitive.NilObjectID, nil, errors.NewValidationError("bad statement: invalid partner id", err)
+	}
+	fields := []string{}
+	for _, field := range strings.Split(parts[4], ",") {
+		if !slices.Contains(s.dataFields, field) && !slices.Contains(claims.Supported, field) {
+			return primitive.NilObjectID, nil, errors.NewValidationError(fmt.Sprintf("bad statement: unknown field %q, allowed fields are %v and claims are %v", field, s.dataFields, claims.Supported), nil)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return partnerID, fields, nil
+}
+
+func (s *ConsentService) ListGrants(ctx context.Context, clientID string) ([]models.ConsentGrant, error) {
+	grants, err := s.consentRepo.ListGrants(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error listing consent grants from database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("listing consent grants from database", err)
+	}
+	return grants, nil
+}
+
+func (s *ConsentService) RevokeGrant(ctx context.Context, clientID string, grantID string) error {
+	id, err := primitive.ObjectIDFromHex(grantID)
+	if err != nil {
+		return errors.NewValidationError("invalid consent grant id", err)
+	}
+	revoked, err := s.consentRepo.RevokeGrant(ctx, id, clientID, time.Now())
+	if err != nil {
+		s.logger.Error("Error revoking consent grant in database", "clientID", clientID, "grantID", grantID, "error", err)
+		return errors.NewInternalError("revoking consent grant in database", err)
+	}
+	if !revoked {
+		return errors.NewNotFoundError("consent grant not found or already revoked", nil)
+	}
+	s.logger.Info("Consent revoked", "clientID", clientID, "grantID", grantID)
+	return nil
+}
+
+// ListAccesses returns the accesses of the partner under a grant of the client
+func (s *ConsentService) ListAccesses(ctx context.Context, clientID string, grantID string) ([]models.ConsentAccess, error) {
+	id, err := primitive.ObjectIDFromHex(grantID)
+	if err != nil {
+		return nil, errors.NewValidationError("invalid consent grant id", err)
+	}
+	grant, err := s.consentRepo.GetGrant(ctx, id)
+	if err != nil {
+		s.logger.Error("Error getting consent grant from database", "grantID", grantID, "error", err)
+		return nil, errors.NewInternalError("getting consent grant from database", err)
+	}
+	if grant == nil || grant.ClientID != clientID {
+		return nil, errors.NewNotFoundError("consent grant not found", nil)
+	}
+	accesses, err := s.consentRepo.ListAccesses(ctx, id)
+	if err != nil {
+		s.logger.Error("Error listing consent accesses from database", "grantID", grantID, "error", err)
+		return nil, errors.NewInternalError("listing consent accesses from database", err)
+	}
+	return accesses, nil
+}
+
+// GetGrantedVerificationData returns the verification data of the client and the grant allowing the partner to fetch it.
+// Every access is logged, the caller must only disclose the fields of the grant.
+func (s *ConsentService) GetGrantedVerificationData(ctx context.Context, partnerKey *models.PartnerAPIKey, clientID string) (*models.Verification, *models.ConsentGrant, error) {
+	now := time.Now()
+	grant, err := s.consentRepo.GetActiveGrant(ctx, clientID, partnerKey.ID, now)
+	if err != nil {
+		s.logger.Error("Error getting consent grant from database", "clientID", clientID, "partner", partnerKey.Name, "error", err)
+		return nil, nil, errors.NewInternalError("getting consent grant from database", err)
+	}
+	if grant == nil {
+		return nil, nil, errors.NewDeniedError("no active consent grant from this client", nil)
+	}
+	verification, err := s.verificationRepo.GetVerification(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error getting verification from database", "clientID", clientID, "error", err)
+		return nil, nil, errors.NewInternalError("getting verification from database", err)
+	}
+	if verification == nil {
+		return nil, nil, errors.NewNotFoundError("verification not found for client", nil)
+	}
+	access := &models.ConsentAccess{
+		GrantID:      grant.ID,
+		ClientID:     clientID,
+		PartnerKeyID: partnerKey.ID,
+		PartnerName:  partnerKey.Name,
+		Fields:       grant.Fields,
+		AccessedAt:   now,
+	}
+	// data is only disclosed once the access is logged
+	if err := s.consentRepo.LogAccess(ctx, access); err != nil {
+		s.logger.Error("Error logging consent access to database", "clientID", clientID, "partner", partnerKey.Name, "error", err)
+		return nil, nil, errors.NewInternalError("logging consent access to database", err)
+	}
+	s.logger.Info("Verification data accessed under consent", "clientID", clientID, "partner", partnerKey.Name, "grantID", grant.ID.Hex(), "fields", grant.Fields)
+	return verification, grant, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/claims"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeConsentRepo struct {
+	mu       sync.Mutex
+	grants   []*models.ConsentGrant
+	accesses []models.ConsentAccess
+}
+
+func (f *fakeConsentRepo) SaveGrant(ctx context.Context, grant *models.ConsentGrant) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	grant.ID = primitive.NewObjectID()
+	f.grants = append(f.grants, grant)
+	return nil
+}
+
+func (f *fakeConsentRepo) GetGrant(ctx context.Context, id primitive.ObjectID) (*models.ConsentGrant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, grant := range f.grants {
+		if grant.ID == id {
+			copied := *grant
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeConsentRepo) ListGrants(ctx context.Context, clientID string) ([]models.ConsentGrant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	grants := []models.ConsentGrant{}
+	for _, grant := range f.grants {
+		if grant.ClientID == clientID {
+			grants = append(grants, *grant)
+		}
+	}
+	return grants, nil
+}
+
+func (f *fakeConsentRepo) GetActiveGrant(ctx context.Context, clientID string, partnerKeyID primitive.ObjectID, now time.Time) (*models.ConsentGrant, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.grants) - 1; i >= 0; i-- {
+		grant := f.grants[i]
+		if grant.ClientID == clientID && grant.PartnerKeyID == partnerKeyID && grant.Active(now) {
+			copied := *grant
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeConsentRepo) RevokeGrant(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, grant := range f.grants {
+		if grant.ID == id && grant.ClientID == clientID && grant.RevokedAt == nil {
+			grant.RevokedAt = &revokedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeConsentRepo) LogAccess(ctx context.Context, access *models.ConsentAccess) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	access.ID = primitive.NewObjectID()
+	f.accesses = append(f.accesses, *access)
+	return nil
+}
+
+func (f *fakeConsentRepo) ListAccesses(ctx context.Context, grantID primitive.ObjectID) ([]models.ConsentAccess, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	accesses := []models.ConsentAccess{}
+	for _, access := range f.accesses {
+		if access.GrantID == grantID {
+			accesses = append(accesses, access)
+		}
+	}
+	return accesses, nil
+}
+
+func consentRequest(clientID string, partnerID string, fields string, duration time.Duration) ConsentRequest {
+	statement := fmt.Sprintf("kyc.test:%d:consent:%s:%s", time.Now().Unix(), partnerID, fields)
+	return ConsentRequest{
+		Statement: hex.EncodeToString([]byte(statement)),
+		Signature: fakeSignature(clientID),
+		Duration:  duration,
+	}
+}
+
+func TestConsentService(t *testing.T) {
+	ctx := context.Background()
+	partnerKeys := newFakePartnerKeyRepo()
+	partner := &models.PartnerAPIKey{Name: "partner"}
+	assert.NoError(t, partnerKeys.SaveKey(ctx, partner))
+	verifications := &fakeVerificationRepo{}
+	verification := newApprovedVerification("client", "scan", "doc")
+	assert.NoError(t, verifications.SaveVerification(ctx, &verification))
+	consents := &fakeConsentRepo{}
+	challenge := config.Challenge{Domain: "kyc.test", Window: 8, ClockSkew: 2}
+	service := NewConsentService(consents, partnerKeys, verifications, challenge, &config.Consent{MaxDuration: 60}, []string{"docFirstName", "docDob"}, fakeVerifySignature, slog.Default())
+
+	var serviceError *errors.ServiceError
+	_, _, err := service.GetGrantedVerificationData(ctx, partner, "client")
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeDenied, serviceError.Type)
+
+	_, err = service.CreateGrant(ctx, "client", consentRequest("client", partner.ID.Hex(), "docFirstName", 2*time.Hour))
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeValidation, serviceError.Type)
+	_, err = service.CreateGrant(ctx, "client", consentRequest("client", primitive.NewObjectID().Hex(), "docFirstName", time.Hour))
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeNotFound, serviceError.Type)
+	_, err = service.CreateGrant(ctx, "client", consentRequest("client", partner.ID.Hex(), "docNumber", time.Hour))
+	assert.ErrorContains(t, err, "unknown field")
+
+	// the client must sign a fresh statement of this domain
+	_, err = service.CreateGrant(ctx, "client", consentRequest("other", partner.ID.Hex(), "docFirstName", time.Hour))
+	assert.ErrorContains(t, err, "bad signature")
+	expired := consentRequest("client", partner.ID.Hex(), "docFirstName", time.Hour)
+	expired.Statement = hex.EncodeToString([]byte(fmt.Sprintf("kyc.test:%d:consent:%s:docFirstName", time.Now().Add(-time.Minute).Unix(), partner.ID.Hex())))
+	_, err = service.CreateGrant(ctx, "client", expired)
+	assert.ErrorContains(t, err, "statement expired")
+	otherDomain := consentRequest("client", partner.ID.Hex(), "docFirstName", time.Hour)
+	otherDomain.Statement = hex.EncodeToString([]byte(fmt.Sprintf("other.test:%d:consent:%s:docFirstName", time.Now().Unix(), partner.ID.Hex())))
+	_, err = service.CreateGrant(ctx, "client", otherDomain)
+	assert.ErrorContains(t, err, "unexpected domain")
+	assert.Empty(t, consents.grants)
+
+	request := consentRequest("client", partner.ID.Hex(), "docFirstName,isAdult", time.Hour)
+	grant, err := service.CreateGrant(ctx, "client", request)
+	assert.NoError(t, err)
+	assert.Equal(t, "partner", grant.PartnerName)
+	assert.Equal(t, request.Statement, grant.Statement)
+	assert.Equal(t, request.Signature, grant.Signature)
+
+	_, granted, err := service.GetGrantedVerificationData(ctx, partner, "client")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docFirstName", claims.IsAdult}, granted.Fields)
+
+	// another client can neither see the accesses nor revoke the grant
+	_, err = service.ListAccesses(ctx, "other", grant.ID.Hex())
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeNotFound, serviceError.Type)
+	assert.Error(t, service.RevokeGrant(ctx, "other", grant.ID.Hex()))
+
+	accesses, err := service.ListAccesses(ctx, "client", grant.ID.Hex())
+	assert.NoError(t, err)
+	assert.Len(t, accesses, 1)
+	assert.Equal(t, partner.ID, accesses[0].PartnerKeyID)
+
+	assert.NoError(t, service.RevokeGrant(ctx, "client", grant.ID.Hex()))
+	_, _, err = service.GetGrantedVerificationData(ctx, partner, "client")
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeDenied, serviceError.Type)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"strings"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+// IdentityFingerprint returns a keyed hash of the document number, issuing country and date of birth.
+// It returns an empty string if no key is configured or the document data is incomplete.
+func IdentityFingerprint(key string, data models.PersonData) string {
+	docNumber := normalizeIdentityField(data.DocNumber)
+	issuingCountry := normalizeIdentityField(data.DocIssuingCountry)
+	dob := normalizeIdentityField(data.DocDOB)
+	if key == "" || docNumber == "" || issuingCountry == "" || dob == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(docNumber + "|" + issuingCountry + "|" + dob))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalizeIdentityField(value string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(value), ""))
+}
+
+// checkDuplicateIdentity looks for another client already verified with the same identity, either by the
+// document fingerprint or by iDenfy duplicate faces, and applies the configured outcome to the result.
+func (s *KYCService) checkDuplicateIdentity(ctx context.Context, result *models.Verification) error {
+	fingerprint := IdentityFingerprint(s.config.FingerprintKey, result.Data)
+	var existing *models.IdentityFingerprint
+	var duplicate *models.DuplicateIdentity
+	if fingerprint != "" {
+		var err error
+		existing, err = s.fingerprintRepo.GetFingerprint(ctx, fingerprint)
+		if err != nil {
+			return err
+		}
+		if existing != nil && !existing.Shares(result.ClientID) {
+			duplicate = &models.DuplicateIdentity{ClientID: existing.ClientID, ScanRef: existing.ScanRef, Source: models.DuplicateSourceFingerprint}
+		}
+	}
+	if duplicate == nil && len(result.Data.DuplicateFaces) > 0 {
+		verifications, err := s.verificationRepo.GetVerificationsByScanRefs(ctx, result.Data.DuplicateFaces)
+		if err != nil {
+			return err
+		}
+		for _, verification := range verifications {
+			// clients sharing the fingerprint of this identity (owner and linked clients) are not duplicates of each other
+			if verification.ClientID != result.ClientID && (existing == nil || !existing.Shares(verification.ClientID)) {
+				duplicate = &models.DuplicateIdentity{ClientID: verification.ClientID, ScanRef: verification.IdenfyRef, Source: models.DuplicateSourceDuplicateFaces}
+				break
+			}
+		}
+	}
+	if duplicate == nil && fingerprint != "" && existing == nil {
+		err := s.fingerprintRepo.SaveFingerprint(ctx, &models.IdentityFingerprint{
+			Fingerprint: fingerprint,
+			ClientID:    result.ClientID,
+			ScanRef:     result.IdenfyRef,
+		})
+		if !stderrors.Is(err, repository.ErrDuplicateKey) {
+			return err
+		}
+		// another verification of the same identity saved the fingerprint first, it is a duplicate unless it is this client
+		existing, err = s.fingerprintRepo.GetFingerprint(ctx, fingerprint)
+		if err != nil {
+			return err
+		}
+		if existing != nil && !existing.Shares(result.ClientID) {
+			duplicate = &models.DuplicateIdentity{ClientID: existing.ClientID, ScanRef: existing.ScanRef, Source: models.DuplicateSourceFingerprint}
+		}
+	}
+	if duplicate == nil {
+		return nil
+	}
+
+	duplicate.Outcome = models.DuplicateIdentityOutcome(s.config.DuplicateIdentityOutcome)
+	result.Duplicate = duplicate
+	s.logger.Warn("Duplicate identity detected", "clientID", result.ClientID, "scanRef", result.IdenfyRef, "duplicateOf", duplicate.ClientID, "source", duplicate.Source, "outcome", duplicate.Outcome)
+	switch duplicate.Outcome {
+	case models.DuplicateIdentityReject:
+		denied := models.OverallDenied
+		result.Status.Overall = &denied
+		result.Status.DenyReasons = append(result.Status.DenyReasons, models.DenyReasonDuplicateIdentity)
+	case models.DuplicateIdentityLink:
+		if existing != nil {
+			return s.fingerprintRepo.LinkClient(ctx, fingerprint, result.ClientID)
+		}
+	}
+	return nil
+}
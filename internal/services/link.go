@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LINK_STATEMENT_ACTION is the action of the `{api-domain}:{timestamp}:link:{primaryClientId}:{linkedClientId}` link statements
+const LINK_STATEMENT_ACTION = "link"
+
+// SignatureVerifier verifies the signature of a hex-encoded message by the client, with the declared scheme if any
+type SignatureVerifier func(clientID string, signature string, message string, scheme string) error
+
+// LinkRequest is a link statement signed by both the primary and the linked account
+type LinkRequest struct {
+	Statement              string
+	PrimarySignature       string
+	PrimarySignatureScheme string
+	LinkedSignature        string
+	LinkedSignatureScheme  string
+}
+
+// LinkService manages the links of other accounts controlled by a verified client, the linked accounts inherit its verification
+type LinkService struct {
+	linkRepo        repository.LinkRepository
+	kyc             *KYCService
+	challenge       config.Challenge
+	config          *config.Links
+	verifySignature SignatureVerifier
+	logger          *slog.Logger
+}
+
+func NewLinkService(linkRepo repository.LinkRepository, kyc *KYCService, challenge config.Challenge, config *config.Links, verifySignature SignatureVerifier, logger *slog.Logger) *LinkService {
+	return &LinkService{linkRepo: linkRepo, kyc: kyc, challenge: challenge, config: config, verifySignature: verifySignature, logger: logger}
+}
+
+// CreateLink links the account named by the statement to the verified primary client.
+// Both accounts must sign the statement, and links can't be chained.
+func (s *LinkService) CreateLink(ctx context.Context, primaryClientID string, request LinkRequest) (*models.AccountLink, error) {
+	if s.config.MaxLinks == 0 {
+		return nil, errors.NewDeniedError("account linking is disabled", nil)
+	}
+	linkedClientID, err := s.parseStatement(request.Statement, primaryClientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(primaryClientID, request.PrimarySignature, request.Statement, request.PrimarySignatureScheme); err != nil {
+		return nil, err
+	}
+	if err := s.verifySignature(linkedClientID, request.LinkedSignature, request.Statement, request.LinkedSignatureScheme); err != nil {
+		return nil, err
+	}
+	// the checks below and the save must not interleave with another link of either account,
+	// otherwise concurrent links could chain accounts or exceed the maximum
+	release, err := s.lockLinks(ctx, primaryClientID, linkedClientID)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// the primary account must have its own verification, not one inherited from another link
+	primaryLink, err := s.linkRepo.GetActiveLink(ctx, primaryClientID)
+	if err != nil {
+		s.logger.Error("Error getting account link from database", "clientID", primaryClientID, "error", err)
+		return nil, errors.NewInternalError("getting account link from database", err)
+	}
+	if primaryLink != nil {
+		return nil, errors.NewConflictError("account is itself linked to another account", nil)
+	}
+	verified, err := s.kyc.IsUserVerified(ctx, primaryClientID)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, errors.NewDeniedError("only verified accounts can link other accounts", nil)
+	}
+	linkedLinks, err := s.linkRepo.CountActiveLinks(ctx, linkedClientID)
+	if err != nil {
+		s.logger.Error("Error counting account links in database", "clientID", linkedClientID, "error", err)
+		return nil, errors.NewInternalError("counting account links in database", err)
+	}
+	if linkedLinks > 0 {
+		return nil, errors.NewConflictError("linked account has accounts linked to it", nil)
+	}
+	primaryLinks, err := s.linkRepo.CountActiveLinks(ctx, primaryClientID)
+	if err != nil {
+		s.logger.Error("Error counting account links in database", "clientID", primaryClientID, "error", err)
+		return nil, errors.NewInternalError("counting account links in database", err)
+	}
+	if primaryLinks >= int64(s.config.MaxLinks) {
+		return nil, errors.NewConflictError(fmt.Sprintf("account already has the maximum of %d linked accounts", s.config.MaxLinks), nil)
+	}
+
+	link := &models.AccountLink{
+		PrimaryClientID:  primaryClientID,
+		LinkedClientID:   linkedClientID,
+		Statement:        request.Statement,
+		PrimarySignature: request.PrimarySignature,
+		LinkedSignature:  request.LinkedSignature,
+		Active:           true,
+		CreatedAt:        time.Now(),
+	}
+	if err := s.linkRepo.SaveLink(ctx, link); err != nil {
+		if stderrors.Is(err, repository.ErrDuplicateKey) {
+			return nil, errors.NewConflictError("account is already linked", err)
+		}
+		s.logger.Error("Error saving account link to database", "clientID", primaryClientID, "linkedClientID", linkedClientID, "error", err)
+		return nil, errors.NewInternalError("saving account link to database", err)
+	}
+	if err := s.logEvent(ctx, link, models.AccountLinkCreated, primaryClientID, link.CreatedAt); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Account linked", "clientID", primaryClientID, "linkedClientID", linkedClientID, "linkID", link.ID.Hex())
+	return link, nil
+}
+
+// lockLinks waits until it holds the link leases of both accounts and returns the function releasing them.
+// The leases are taken in a fixed order so two requests linking the same accounts both ways can't deadlock.
+func (s *LinkService) lockLinks(ctx context.Context, clientIDs ...string) (func(), error) {
+	slices.Sort(clientIDs)
+	releases := make([]func(), 0, len(clientIDs))
+	release := func() {
+		for i := len(releases) - 1; i >= 0; i-- {
+			releases[i]()
+		}
+	}
+	for _, clientID := range clientIDs {
+		releaseClient, err := s.kyc.lock(ctx, s.kyc.lockName("account-link", clientID), "account link", "a link of this account is already being created, retry later")
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, releaseClient)
+	}
+	return release, nil
+}
+
+// parseStatement checks the statement is a fresh link statement of the primary client for this domain, and returns the linked client
+func (s *LinkService) parseStatement(statement string, primaryClientID string) (string, error) {
+	statementBytes, err := hex.DecodeString(strings.TrimPrefix(statement, "0x"))
+	if err != nil {
+		return "", errors.NewValidationError("malformed statement: failed to decode hex-encoded statement", err)
+	}
+	parts := strings.Split(string(statementBytes), ":")
+	if len(parts) != 5 || parts[2] != LINK_STATEMENT_ACTION {
+		return "", errors.NewValidationError("malformed statement: expected `{api-domain}:{timestamp}:link:{primaryClientId}:{linkedClientId}`", nil)
+	}
+	if parts[0] != s.challenge.Domain {
+		return "", errors.NewValidationError("bad statement: unexpected domain", nil)
+	}
+	timestamp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", errors.NewValidationError("bad statement: invalid timestamp", nil)
+	}
+	age := time.Now().Unix() - timestamp
+	if age > s.challenge.Window+s.challenge.ClockSkew || -age > s.challenge.ClockSkew {
+		return "", errors.NewValidationError("bad statement: statement expired or timestamp in the future", nil)
+	}
+	statementPrimary, err := address.Normalize(parts[3], s.challenge.AllowedSS58Prefixes)
+	if err != nil {
+		return "", err
+	}
+	if statementPrimary != primaryClientID {
+		return "", errors.NewAuthorizationError("bad statement: primary account is not the authenticated client", nil)
+	}
+	linkedClientID, err := address.Normalize(parts[4], s.challenge.AllowedSS58Prefixes)
+	if err != nil {
+		return "", err
+	}
+	if linkedClientID == primaryClientID {
+		return "", errors.NewValidationError("bad statement: an account can't be linked to itself", nil)
+	}
+	return linkedClientID, nil
+}
+
+// ListLinks returns the links of the client, as primary or linked account, including the revoked ones
+func (s *LinkService) ListLinks(ctx context.Context, clientID string) ([]models.AccountLink, error) {
+	links, err := s.linkRepo.ListLinks(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error listing account links from database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("listing account links from database", err)
+	}
+	return links, nil
+}
+
+// RevokeLink revokes a link, either of its accounts can revoke it
+func (s *LinkService) RevokeLink(ctx context.Context, clientID string, linkID string) error {
+	id, err := primitive.ObjectIDFromHex(linkID)
+	if err != nil {
+		return errors.NewValidationError("invalid account link id", err)
+	}
+	now := time.Now()
+	link, err := s.linkRepo.RevokeLink(ctx, id, clientID, now)
+	if err != nil {
+		s.logger.Error("Error revoking account link in database", "clientID", clientID, "linkID", linkID, "error", err)
+		return errors.NewInternalError("revoking account link in database", err)
+	}
+	if link == nil {
+		return errors.NewNotFoundError("account link not found or already revoked", nil)
+	}
+	if err := s.logEvent(ctx, link, models.AccountLinkRevoked, clientID, now); err != nil {
+		return err
+	}
+	s.logger.Info("Account link revoked", "clientID", clientID, "linkID", linkID, "primaryClientID", link.PrimaryClientID, "linkedClientID", link.LinkedClientID)
+	return nil
+}
+
+// ListEvents returns the audit trail of the links of the client
+func (s *LinkService) ListEvents(ctx context.Context, clientID string) ([]models.AccountLinkEvent, error) {
+	events, err := s.linkRepo.ListEvents(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error listing account link events from database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("listing account link events from database", err)
+	}
+	return events, nil
+}
+
+func (s *LinkService) logEvent(ctx context.Context, link *models.AccountLink, action models.AccountLinkAction, actor string, at time.Time) error {
+	event := &models.AccountLinkEvent{
+		LinkID:          link.ID,
+		PrimaryClientID: link.PrimaryClientID,
+		LinkedClientID:  link.LinkedClientID,
+		Action:          action,
+		Actor:           actor,
+		At:              at,
+	}
+	if err := s.linkRepo.LogEvent(ctx, event); err != nil {
+		s.logger.Error("Error logging account link event to database", "linkID", link.ID.Hex(), "action", action, "error", err)
+		return errors.NewInternalError("logging account link event to database", err)
+	}
+	return nil
+}
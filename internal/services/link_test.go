@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/outcome"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	alice   = "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+	bob     = "5FHneW46xGXgs5mUiveU4sbTyGBzmstUspZC92UhjJM694ty"
+	charlie = "5FLSigC9HGRKVhB9FiEo4Y3koPsNmBmLJbpXg2mp1hXcS59Y"
+)
+
+type fakeLinkRepo struct {
+	mu     sync.Mutex
+	links  []*models.AccountLink
+	events []models.AccountLinkEvent
+	// countDelay widens the window between the checks and the save of a link
+	countDelay time.Duration
+}
+
+func (f *fakeLinkRepo) SaveLink(ctx context.Context, link *models.AccountLink) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	link.ID = primitive.NewObjectID()
+	copied := *link
+	f.links = append(f.links, &copied)
+	return nil
+}
+
+func (f *fakeLinkRepo) GetActiveLink(ctx context.Context, linkedClientID string) (*models.AccountLink, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, link := range f.links {
+		if link.LinkedClientID == linkedClientID && link.Active {
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeLinkRepo) CountActiveLinks(ctx context.Context, primaryClientID string) (int64, error) {
+	time.Sleep(f.countDelay)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var count int64
+	for _, link := range f.links {
+		if link.PrimaryClientID == primaryClientID && link.Active {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeLinkRepo) ListLinks(ctx context.Context, clientID string) ([]models.AccountLink, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	links := []models.AccountLink{}
+	for _, link := range f.links {
+		if link.PrimaryClientID == clientID || link.LinkedClientID == clientID {
+			links = append(links, *link)
+		}
+	}
+	return links, nil
+}
+
+func (f *fakeLinkRepo) RevokeLink(ctx context.Context, id primitive.ObjectID, clientID string, revokedAt time.Time) (*models.AccountLink, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, link := range f.links {
+		if link.ID == id && link.Active && (link.PrimaryClientID == clientID || link.LinkedClientID == clientID) {
+			link.Active = false
+			link.RevokedAt = &revokedAt
+			link.RevokedBy = clientID
+			copied := *link
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeLinkRepo) LogEvent(ctx context.Context, event *models.AccountLinkEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, *event)
+	return nil
+}
+
+func (f *fakeLinkRepo) ListEvents(ctx context.Context, clientID string) ([]models.AccountLinkEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := []models.AccountLinkEvent{}
+	for _, event := range f.events {
+		if event.PrimaryClientID == clientID || event.LinkedClientID == clientID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// fakeSignature is accepted by fakeVerifySignature as the signature of the client
+func fakeSignature(clientID string) string {
+	return "signed-by-" + clientID
+}
+
+func fakeVerifySignature(clientID string, signature string, message string, scheme string) error {
+	if signature != fakeSignature(clientID) {
+		return errors.NewAuthorizationError("bad signature: signature does not match", nil)
+	}
+	return nil
+}
+
+func newLinkTestService(maxLinks uint) (*LinkService, *fakeVerificationRepo, *fakeLinkRepo) {
+	verifications := &fakeVerificationRepo{}
+	links := &fakeLinkRepo{}
+	verificationConfig := &config.Verification{SuspiciousVerificationOutcome: "APPROVED", ExpiredDocumentOutcome: "REJECTED", SessionLockTTL: 30}
+	locks := &fakeLockRepo{leases: map[string]fakeLease{}}
+	kyc := &KYCService{verificationRepo: verifications, linkRepo: links, lockRepo: locks, outcome: outcome.New(verificationConfig), config: verificationConfig, logger: slog.Default()}
+	challenge := config.Challenge{Domain: "kyc.test", Window: 8, ClockSkew: 2, AllowedSS58Prefixes: []uint16{42}}
+	return NewLinkService(links, kyc, challenge, &config.Links{MaxLinks: maxLinks}, fakeVerifySignature, slog.Default()), verifications, links
+}
+
+func linkRequest(primary string, linked string) LinkRequest {
+	statement := fmt.Sprintf("kyc.test:%d:link:%s:%s", time.Now().Unix(), primary, linked)
+	return LinkRequest{
+		Statement:        hex.EncodeToString([]byte(statement)),
+		PrimarySignature: fakeSignature(primary),
+		LinkedSignature:  fakeSignature(linked),
+	}
+}
+
+func TestCreateLink(t *testing.T) {
+	ctx := context.Background()
+	service, verifications, links := newLinkTestService(1)
+
+	// only verified accounts can link other accounts
+	_, err := service.CreateLink(ctx, alice, linkRequest(alice, bob))
+	assert.ErrorContains(t, err, "only verified accounts")
+	approved := newApprovedVerification(alice, "scan-1", "AB123")
+	assert.NoError(t, verifications.SaveVerification(ctx, &approved))
+
+	// both accounts must sign the statement of the authenticated client
+	unsigned := linkRequest(alice, bob)
+	unsigned.LinkedSignature = fakeSignature(charlie)
+	_, err = service.CreateLink(ctx, alice, unsigned)
+	assert.ErrorContains(t, err, "bad signature")
+	_, err = service.CreateLink(ctx, charlie, linkRequest(alice, bob))
+	assert.ErrorContains(t, err, "not the authenticated client")
+	_, err = service.CreateLink(ctx, alice, linkRequest(alice, alice))
+	assert.ErrorContains(t, err, "linked to itself")
+	expired := LinkRequest{Statement: hex.EncodeToString([]byte(fmt.Sprintf("kyc.test:%d:link:%s:%s", time.Now().Add(-time.Minute).Unix(), alice, bob)))}
+	_, err = service.CreateLink(ctx, alice, expired)
+	assert.ErrorContains(t, err, "statement expired")
+
+	link, err := service.CreateLink(ctx, alice, linkRequest(alice, bob))
+	assert.NoError(t, err)
+	assert.Equal(t, bob, link.LinkedClientID)
+
+	// the linked account inherits the verification of the primary account
+	status, err := service.kyc.GetVerificationStatus(ctx, bob)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OutcomeApproved, status.Outcome)
+	assert.Equal(t, bob, status.ClientID)
+	assert.Equal(t, alice, status.LinkedTo)
+	verified, err := service.kyc.IsUserVerified(ctx, bob)
+	assert.NoError(t, err)
+	assert.True(t, verified)
+
+	// links are capped and not chained
+	_, err = service.CreateLink(ctx, alice, linkRequest(alice, charlie))
+	assert.ErrorContains(t, err, "maximum of 1 linked accounts")
+	_, err = service.CreateLink(ctx, bob, linkRequest(bob, charlie))
+	assert.ErrorContains(t, err, "itself linked")
+
+	// either account can revoke the link, the linked account loses the inherited status
+	assert.NoError(t, service.RevokeLink(ctx, bob, link.ID.Hex()))
+	assert.Error(t, service.RevokeLink(ctx, bob, link.ID.Hex()))
+	status, err = service.kyc.GetVerificationStatus(ctx, bob)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	events, err := service.ListEvents(ctx, alice)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, models.AccountLinkCreated, events[0].Action)
+	assert.Equal(t, alice, events[0].Actor)
+	assert.Equal(t, models.AccountLinkRevoked, events[1].Action)
+	assert.Equal(t, bob, events[1].Actor)
+	assert.Len(t, links.links, 1)
+}
+
+func TestCreateLinkDisabled(t *testing.T) {
+	service, _, _ := newLinkTestService(0)
+	_, err := service.CreateLink(context.Background(), alice, linkRequest(alice, bob))
+	assert.ErrorContains(t, err, "disabled")
+}
+
+func TestCreateLinkConcurrent(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		maxLinks uint
+		requests [][2]string
+	}{
+		{name: "links are not chained", maxLinks: 2, requests: [][2]string{{alice, bob}, {bob, charlie}}},
+		{name: "links are capped", maxLinks: 1, requests: [][2]string{{alice, bob}, {alice, charlie}}},
+		{name: "accounts are not linked both ways", maxLinks: 1, requests: [][2]string{{alice, bob}, {bob, alice}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, verifications, links := newLinkTestService(tt.maxLinks)
+			links.countDelay = 20 * time.Millisecond
+			for i, clientID := range []string{alice, bob} {
+				approved := newApprovedVerification(clientID, fmt.Sprintf("scan-%d", i), fmt.Sprintf("AB12%d", i))
+				assert.NoError(t, verifications.SaveVerification(ctx, &approved))
+			}
+
+			var wg sync.WaitGroup
+			errs := make([]error, len(tt.requests))
+			for i, request := range tt.requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, errs[i] = service.CreateLink(ctx, request[0], linkRequest(request[0], request[1]))
+				}()
+			}
+			wg.Wait()
+
+			failed := 0
+			for _, err := range errs {
+				if err != nil {
+					assert.ErrorContains(t, err, "link")
+					failed++
+				}
+			}
+			assert.Equal(t, 1, failed)
+			assert.Len(t, links.links, 1)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/session"
+)
+
+// Network is a TFChain network served by the instance, with its own substrate client, challenge domain and iDenfy suffix
+type Network struct {
+	Name      string
+	Challenge config.Challenge
+	// Sessions is nil when session tokens are disabled
+	Sessions *session.Manager
+	KYC      *KYCService
+	Consent  *ConsentService
+	Links    *LinkService
+}
+
+// Networks are the networks served by the instance, requests that don't select one are served by the default network
+type Networks struct {
+	Default *Network
+	list    []*Network
+}
+
+// NewNetworks checks that the networks can be told apart by their name and by their iDenfy suffix
+func NewNetworks(defaultNetwork *Network, others ...*Network) (*Networks, error) {
+	list := append([]*Network{defaultNetwork}, others...)
+	names := map[string]bool{}
+	suffixes := map[string]string{}
+	for _, network := range list {
+		if names[network.Name] {
+			return nil, fmt.Errorf("network %s is configured twice", network.Name)
+		}
+		names[network.Name] = true
+		if other, ok := suffixes[network.KYC.IdenfySuffix]; ok {
+			return nil, fmt.Errorf("networks %s and %s have the same iDenfy suffix %s, they are the same TFChain network", other, network.Name, network.KYC.IdenfySuffix)
+		}
+		suffixes[network.KYC.IdenfySuffix] = network.Name
+	}
+	return &Networks{Default: defaultNetwork, list: list}, nil
+}
+
+// Get returns the network with the given name
+func (n *Networks) Get(name string) (*Network, bool) {
+	for _, network := range n.list {
+		if network.Name == name {
+			return network, true
+		}
+	}
+	return nil, false
+}
+
+// List returns all the networks, the default one first
+func (n *Networks) List() []*Network {
+	return n.list
+}
+
+// ForClientID returns the network of an iDenfy clientId by its suffix.
+// Unknown suffixes return the default network, which rejects the clientId.
+func (n *Networks) ForClientID(clientID string) *Network {
+	_, suffix, _ := strings.Cut(clientID, ":")
+	for _, network := range n.list {
+		if network.KYC.IdenfySuffix == suffix {
+			return network
+		}
+	}
+	return n.Default
+}
+
+// Trust links the networks by name to the networks whose approved verifications their status lookups accept.
+// A linked network is looked up with the client public key encoded with its own canonical SS58 prefix.
+func (n *Networks) Trust(policy map[string][]string) error {
+	for name, trustedNames := range policy {
+		network, ok := n.Get(name)
+		if !ok {
+			return fmt.Errorf("trust policy of unknown network %s", name)
+		}
+		trusted := make([]trustedNetwork, 0, len(trustedNames))
+		for _, trustedName := range trustedNames {
+			if trustedName == name {
+				return fmt.Errorf("network %s can't trust itself", name)
+			}
+			trustedNetwork, ok := n.Get(trustedName)
+			if !ok {
+				return fmt.Errorf("network %s trusts unknown network %s", name, trustedName)
+			}
+			if len(trustedNetwork.Challenge.AllowedSS58Prefixes) == 0 {
+				return fmt.Errorf("network %s has no SS58 prefix", trustedName)
+			}
+			trusted = append(trusted, newTrustedNetwork(trustedNetwork))
+		}
+		network.KYC.trusted = trusted
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+)
+
+func TestNetworks(t *testing.T) {
+	devnet := &Network{Name: "devnet", KYC: &KYCService{IdenfySuffix: "dev"}}
+	qanet := &Network{Name: "qanet", KYC: &KYCService{IdenfySuffix: "ns:qa"}}
+	networks, err := NewNetworks(devnet, qanet)
+	assert.NoError(t, err)
+
+	network, ok := networks.Get("qanet")
+	assert.True(t, ok)
+	assert.Same(t, qanet, network)
+	_, ok = networks.Get("mainnet")
+	assert.False(t, ok)
+
+	// webhooks are routed by the clientId suffix, unknown suffixes go to the default network which rejects them
+	assert.Same(t, qanet, networks.ForClientID("client:ns:qa"))
+	assert.Same(t, devnet, networks.ForClientID("client:dev"))
+	assert.Same(t, devnet, networks.ForClientID("client:main"))
+
+	// networks connected to the same chain can't be told apart by their webhooks
+	_, err = NewNetworks(devnet, &Network{Name: "devnet2", KYC: &KYCService{IdenfySuffix: "dev"}})
+	assert.Error(t, err)
+	_, err = NewNetworks(devnet, &Network{Name: "devnet", KYC: &KYCService{IdenfySuffix: "other"}})
+	assert.Error(t, err)
+}
+
+func TestNetworksTrust(t *testing.T) {
+	newNetwork := func(name string) *Network {
+		return &Network{Name: name, Challenge: config.Challenge{AllowedSS58Prefixes: []uint16{42}}, KYC: &KYCService{IdenfySuffix: name}}
+	}
+	main, test, qa := newNetwork("main"), newNetwork("test"), newNetwork("qa")
+	networks, err := NewNetworks(main, test, qa)
+	assert.NoError(t, err)
+
+	assert.NoError(t, networks.Trust(map[string][]string{"test": {"main"}, "qa": {"main", "test"}}))
+	assert.Empty(t, main.KYC.trusted)
+	assert.Len(t, test.KYC.trusted, 1)
+	assert.Equal(t, "main", test.KYC.trusted[0].name)
+	assert.Len(t, qa.KYC.trusted, 2)
+
+	assert.Error(t, networks.Trust(map[string][]string{"dev": {"main"}}))
+	assert.Error(t, networks.Trust(map[string][]string{"test": {"dev"}}))
+	assert.Error(t, networks.Trust(map[string][]string{"test": {"test"}}))
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// PARTNER_KEY_SIZE is the number of random bytes in a partner API key
+	PARTNER_KEY_SIZE = 32
+	// PARTNER_KEY_PREFIX_LENGTH is the number of key characters kept to identify a key
+	PARTNER_KEY_PREFIX_LENGTH = 8
+	// PARTNER_RATE_LIMIT_WINDOW is the window partner API key rate limits apply to
+	PARTNER_RATE_LIMIT_WINDOW = time.Minute
+)
+
+// PartnerService manages the API keys partner services use to query verification statuses
+type PartnerService struct {
+	partnerKeyRepo repository.PartnerKeyRepository
+	logger         *slog.Logger
+}
+
+func NewPartnerService(partnerKeyRepo repository.PartnerKeyRepository, logger *slog.Logger) *PartnerService {
+	return &PartnerService{partnerKeyRepo: partnerKeyRepo, logger: logger}
+}
+
+// CreateKey creates a partner API key, the returned key is not stored and can't be retrieved later
+func (s *PartnerService) CreateKey(ctx context.Context, name string, rateLimit uint, allowedFields []string) (*models.PartnerAPIKey, string, error) {
+	keyBytes := make([]byte, PARTNER_KEY_SIZE)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, "", errors.NewInternalError("generating partner API key", err)
+	}
+	key := hex.EncodeToString(keyBytes)
+	partnerKey := &models.PartnerAPIKey{
+		Name:          name,
+		KeyHash:       hashPartnerKey(key),
+		Prefix:        key[:PARTNER_KEY_PREFIX_LENGTH],
+		RateLimit:     rateLimit,
+		AllowedFields: allowedFields,
+		CreatedAt:     time.Now(),
+	}
+	if err := s.partnerKeyRepo.SaveKey(ctx, partnerKey); err != nil {
+		s.logger.Error("Error saving partner API key to database", "name", name, "error", err)
+		return nil, "", errors.NewInternalError("saving partner API key to database", err)
+	}
+	s.logger.Info("Partner API key created", "name", name, "prefix", partnerKey.Prefix)
+	return partnerKey, key, nil
+}
+
+func (s *PartnerService) ListKeys(ctx context.Context) ([]models.PartnerAPIKey, error) {
+	keys, err := s.partnerKeyRepo.ListKeys(ctx)
+	if err != nil {
+		s.logger.Error("Error listing partner API keys from database", "error", err)
+		return nil, errors.NewInternalError("listing partner API keys from database", err)
+	}
+	return keys, nil
+}
+
+func (s *PartnerService) RevokeKey(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.NewValidationError("invalid partner API key id", err)
+	}
+	revoked, err := s.partnerKeyRepo.RevokeKey(ctx, objectID, time.Now())
+	if err != nil {
+		s.logger.Error("Error revoking partner API key in database", "id", id, "error", err)
+		return errors.NewInternalError("revoking partner API key in database", err)
+	}
+	if !revoked {
+		return errors.NewNotFoundError("partner API key not found or already revoked", nil)
+	}
+	s.logger.Info("Partner API key revoked", "id", id)
+	return nil
+}
+
+// Authenticate returns the partner API key matching the given key and counts the request against its rate limit
+func (s *PartnerService) Authenticate(ctx context.Context, key string) (*models.PartnerAPIKey, error) {
+	partnerKey, err := s.partnerKeyRepo.GetKeyByHash(ctx, hashPartnerKey(key))
+	if err != nil {
+		s.logger.Error("Error getting partner API key from database", "error", err)
+		return nil, errors.NewInternalError("getting partner API key from database", err)
+	}
+	if partnerKey == nil || partnerKey.Revoked() {
+		return nil, errors.NewAuthorizationError("invalid or revoked API key", nil)
+	}
+	if partnerKey.RateLimit == 0 {
+		return partnerKey, nil
+	}
+	window := time.Now().Truncate(PARTNER_RATE_LIMIT_WINDOW)
+	count, err := s.partnerKeyRepo.IncrementUsage(ctx, partnerKey.ID, window, window.Add(2*PARTNER_RATE_LIMIT_WINDOW))
+	if err != nil {
+		s.logger.Error("Error counting partner API key usage in database", "prefix", partnerKey.Prefix, "error", err)
+		return nil, errors.NewInternalError("counting partner API key usage in database", err)
+	}
+	if count > partnerKey.RateLimit {
+		return nil, errors.NewRateLimitedError("API key rate limit exceeded", nil)
+	}
+	return partnerKey, nil
+}
+
+func hashPartnerKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
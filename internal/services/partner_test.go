@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakePartnerKeyRepo struct {
+	mu    sync.Mutex
+	keys  []*models.PartnerAPIKey
+	usage map[string]uint
+}
+
+func newFakePartnerKeyRepo() *fakePartnerKeyRepo {
+	return &fakePartnerKeyRepo{usage: map[string]uint{}}
+}
+
+func (f *fakePartnerKeyRepo) SaveKey(ctx context.Context, key *models.PartnerAPIKey) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key.ID = primitive.NewObjectID()
+	f.keys = append(f.keys, key)
+	return nil
+}
+
+func (f *fakePartnerKeyRepo) GetKey(ctx context.Context, id primitive.ObjectID) (*models.PartnerAPIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range f.keys {
+		if key.ID == id {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakePartnerKeyRepo) GetKeyByHash(ctx context.Context, keyHash string) (*models.PartnerAPIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range f.keys {
+		if key.KeyHash == keyHash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakePartnerKeyRepo) ListKeys(ctx context.Context) ([]models.PartnerAPIKey, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := []models.PartnerAPIKey{}
+	for _, key := range f.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (f *fakePartnerKeyRepo) RevokeKey(ctx context.Context, id primitive.ObjectID, revokedAt time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range f.keys {
+		if key.ID == id && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakePartnerKeyRepo) IncrementUsage(ctx context.Context, id primitive.ObjectID, window time.Time, expiresAt time.Time) (uint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	usageKey := id.Hex() + window.String()
+	f.usage[usageKey]++
+	return f.usage[usageKey], nil
+}
+
+func TestPartnerServiceAuthenticate(t *testing.T) {
+	service := NewPartnerService(newFakePartnerKeyRepo(), slog.Default())
+	ctx := context.Background()
+
+	partnerKey, key, err := service.CreateKey(ctx, "partner", 2, []string{"status"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, key, partnerKey.KeyHash)
+	assert.Equal(t, key[:PARTNER_KEY_PREFIX_LENGTH], partnerKey.Prefix)
+
+	authenticated, err := service.Authenticate(ctx, key)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"status"}, authenticated.AllowedFields)
+	_, err = service.Authenticate(ctx, key)
+	assert.NoError(t, err)
+
+	// the third request within the minute exceeds the rate limit
+	_, err = service.Authenticate(ctx, key)
+	var serviceError *errors.ServiceError
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeRateLimited, serviceError.Type)
+
+	_, err = service.Authenticate(ctx, "unknown")
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeAuthorization, serviceError.Type)
+
+	assert.NoError(t, service.RevokeKey(ctx, partnerKey.ID.Hex()))
+	_, err = service.Authenticate(ctx, key)
+	assert.ErrorContains(t, err, "invalid or revoked API key")
+
+	err = service.RevokeKey(ctx, partnerKey.ID.Hex())
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeNotFound, serviceError.Type)
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+// RECONCILIATION_BATCH_SIZE is the maximum number of sessions polled by a reconciliation run
+const RECONCILIATION_BATCH_SIZE = 100
+
+// trackSession records the new session, so its result can be polled if its webhook never arrives
+func (s *KYCService) trackSession(ctx context.Context, token *models.Token) {
+	now := time.Now()
+	session := &models.VerificationSession{
+		ScanRef:      token.ScanRef,
+		ClientID:     token.ClientID,
+		IdenfySuffix: s.IdenfySuffix,
+		CreatedAt:    now,
+		NextCheckAt:  now.Add(time.Duration(s.config.ReconciliationTimeout) * time.Minute),
+		ExpiresAt:    now.Add(time.Duration(s.config.ReconciliationMaxAge) * time.Minute),
+	}
+	if err := s.sessionRepo.SaveSession(ctx, session); err != nil {
+		s.logger.Error("Error saving verification session to database", "clientID", token.ClientID, "scanRef", token.ScanRef, "error", err)
+	}
+}
+
+// resolveSession stops the reconciliation of the session once its result is processed
+func (s *KYCService) resolveSession(ctx context.Context, scanRef string, overall models.Overall) {
+	if err := s.sessionRepo.ResolveSession(ctx, scanRef, overall, time.Now()); err != nil {
+		s.logger.Error("Error resolving verification session in database", "scanRef", scanRef, "error", err)
+	}
+}
+
+// ReconcileSessions polls iDenfy for the sessions of the network still without result after the reconciliation timeout,
+// and processes the final results the same way as the verification webhook. It returns the number of processed results.
+// Sessions are claimed before being polled, so concurrent runs on several instances don't poll the same session.
+func (s *KYCService) ReconcileSessions(ctx context.Context, now time.Time) (int, error) {
+	sessions, err := s.sessionRepo.ListDueSessions(ctx, s.IdenfySuffix, now, RECONCILIATION_BATCH_SIZE)
+	if err != nil {
+		s.logger.Error("Error listing due verification sessions from database", "error", err)
+		return 0, errors.NewInternalError("listing due verification sessions from database", err)
+	}
+	// sessions still in progress are polled again at the next run after the interval
+	nextCheckAt := now.Add(time.Duration(s.config.ReconciliationInterval) * time.Minute)
+	processed := 0
+	for _, session := range sessions {
+		claimed, err := s.sessionRepo.ClaimSession(ctx, session.ScanRef, now, nextCheckAt)
+		if err != nil {
+			s.logger.Error("Error claiming verification session in database", "scanRef", session.ScanRef, "error", err)
+			return processed, errors.NewInternalError("claiming verification session in database", err)
+		}
+		if !claimed {
+			continue
+		}
+		reconciled, err := s.reconcileSession(ctx, &session)
+		if err != nil {
+			// one failing session doesn't block the others, it's retried at the next check
+			s.logger.Warn("Error reconciling verification session", "clientID", session.ClientID, "scanRef", session.ScanRef, "error", err)
+			continue
+		}
+		if reconciled {
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// reconcileSession processes the result of the session if iDenfy has a final one
+func (s *KYCService) reconcileSession(ctx context.Context, session *models.VerificationSession) (bool, error) {
+	status, err := s.idenfy.GetSessionStatus(ctx, session.ScanRef)
+	if err != nil {
+		return false, idenfyError("getting verification session status from iDenfy", err)
+	}
+	if !status.Final() {
+		s.logger.Debug("Verification session still in progress", "clientID", session.ClientID, "scanRef", session.ScanRef, "status", status.Status)
+		return false, nil
+	}
+	var data models.PersonData
+	// expired sessions have no data, they are not saved
+	if status.Status != models.OverallExpired {
+		data, err = s.idenfy.GetSessionData(ctx, session.ScanRef)
+		if err != nil {
+			return false, idenfyError("getting verification session data from iDenfy", err)
+		}
+	}
+	result := status.Verification(data)
+	// the result is processed as if sent by the webhook, with the iDenfy clientId of the session
+	result.IdenfyRef = session.ScanRef
+	result.ClientID = session.IdenfyClientID()
+	if err := s.processVerificationResult(ctx, result); err != nil {
+		return false, err
+	}
+	s.logger.Info("Verification session reconciled from iDenfy", "clientID", session.ClientID, "scanRef", session.ScanRef, "status", status.Status)
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+)
+
+type fakeSessionRepo struct {
+	mu       sync.Mutex
+	sessions []*models.VerificationSession
+}
+
+func (f *fakeSessionRepo) SaveSession(ctx context.Context, session *models.VerificationSession) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *session
+	f.sessions = append(f.sessions, &copied)
+	return nil
+}
+
+func (f *fakeSessionRepo) ListDueSessions(ctx context.Context, idenfySuffix string, now time.Time, limit int64) ([]models.VerificationSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	sessions := []models.VerificationSession{}
+	for _, session := range f.sessions {
+		if session.IdenfySuffix == idenfySuffix && session.ResolvedAt == nil && !session.NextCheckAt.After(now) && session.ExpiresAt.After(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (f *fakeSessionRepo) ClaimSession(ctx context.Context, scanRef string, now time.Time, nextCheckAt time.Time) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, session := range f.sessions {
+		if session.ScanRef == scanRef && session.ResolvedAt == nil && !session.NextCheckAt.After(now) {
+			session.NextCheckAt = nextCheckAt
+			session.Checks++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeSessionRepo) ResolveSession(ctx context.Context, scanRef string, overall models.Overall, resolvedAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, session := range f.sessions {
+		if session.ScanRef == scanRef && session.ResolvedAt == nil {
+			session.ResolvedAt = &resolvedAt
+			session.Overall = overall
+		}
+	}
+	return nil
+}
+
+func TestReconcileSessions(t *testing.T) {
+	ctx := context.Background()
+	idenfyClient := &fakeIdenfy{statuses: map[string]models.SessionStatus{}}
+	tokens := &fakeTokenRepo{tokens: map[string]models.Token{}}
+	service := newTokenTestService(t, idenfyClient, tokens)
+	verifications := &fakeVerificationRepo{}
+	service.verificationRepo = verifications
+	sessions := service.sessionRepo.(*fakeSessionRepo)
+
+	for _, clientID := range []string{"client-1", "client-2"} {
+		_, created, err := service.GetOrCreateVerificationToken(ctx, clientID, "")
+		assert.NoError(t, err)
+		assert.True(t, created)
+	}
+	assert.Len(t, sessions.sessions, 2)
+	idenfyClient.statuses["scan-1"] = models.SessionStatus{ScanRef: "scan-1", ClientID: "client-1:devnet", Status: models.OverallApproved}
+	idenfyClient.statuses["scan-2"] = models.SessionStatus{ScanRef: "scan-2", ClientID: "client-2:devnet", Status: models.OverallActive}
+
+	// sessions are only polled after the timeout
+	processed, err := service.ReconcileSessions(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Zero(t, processed)
+
+	later := time.Now().Add(2 * time.Hour)
+	processed, err = service.ReconcileSessions(ctx, later)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, processed)
+
+	// the result is processed like a webhook: the verification is saved and the token deleted
+	status, err := service.GetVerificationStatus(ctx, "client-1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.OutcomeApproved, status.Outcome)
+	assert.Equal(t, "scan-1", status.IdenfyRef)
+	token, err := tokens.GetToken(ctx, "client-1")
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+	assert.NotNil(t, sessions.sessions[0].ResolvedAt)
+
+	// sessions in progress are polled again after the interval
+	assert.Nil(t, sessions.sessions[1].ResolvedAt)
+	processed, err = service.ReconcileSessions(ctx, later)
+	assert.NoError(t, err)
+	assert.Zero(t, processed)
+	assert.Equal(t, uint(1), sessions.sessions[1].Checks)
+
+	// sessions resolved by their webhook are not polled anymore
+	denied := models.OverallDenied
+	final := true
+	err = service.ProcessVerificationResult(ctx, nil, "", models.Verification{ClientID: "client-2:devnet", IdenfyRef: "scan-2", Final: &final, Status: models.Status{Overall: &denied}})
+	assert.NoError(t, err)
+	assert.NotNil(t, sessions.sessions[1].ResolvedAt)
+	processed, err = service.ReconcileSessions(ctx, later.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, processed)
+	assert.Equal(t, uint(1), sessions.sessions[1].Checks)
+}
@@ -6,6 +6,7 @@ package services
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"log/slog"
 	"slices"
@@ -13,43 +14,95 @@ import (
 	"strings"
 	"time"
 
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/claims"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/idenfy"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/substrate"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/eligibility"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/outcome"
 	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-const TFT_CONVERSION_FACTOR = 10000000
+const (
+	// SAVE_TOKEN_ATTEMPTS is how many times saving a new verification token is tried before the session is expired
+	SAVE_TOKEN_ATTEMPTS = 3
+	// SAVE_TOKEN_RETRY_DELAY is the delay before the first retry, it grows linearly with the attempts
+	SAVE_TOKEN_RETRY_DELAY = 200 * time.Millisecond
+)
+
+// SESSION_LOCK_RETRY_INTERVAL is how often a request waiting for a lease held by another request retries
+const SESSION_LOCK_RETRY_INTERVAL = 100 * time.Millisecond
 
 type KYCService struct {
 	verificationRepo repository.VerificationRepository
 	tokenRepo        repository.TokenRepository
+	attemptRepo      repository.AttemptRepository
+	fingerprintRepo  repository.FingerprintRepository
+	lockRepo         repository.LockRepository
+	linkRepo         repository.LinkRepository
+	sessionRepo      repository.SessionRepository
 	idenfy           idenfy.IdenfyClient
 	substrate        substrate.SubstrateClient
+	eligibility      *eligibility.Policy
+	outcome          *outcome.Policy
 	config           *config.Verification
 	logger           *slog.Logger
-	IdenfySuffix     string
+	// scope is the deployment of the service, it keeps the leases of its clients apart from the other networks
+	scope        repository.Scope
+	IdenfySuffix string
+	// trusted are the linked networks whose approved verifications are accepted, in order, see Networks.Trust
+	trusted []trustedNetwork
+}
+
+// trustedNetwork is a linked network, its verifications are looked up by the client public key encoded with its prefix
+type trustedNetwork struct {
+	name             string
+	ss58Prefix       uint16
+	verificationRepo repository.VerificationRepository
+}
+
+func newTrustedNetwork(network *Network) trustedNetwork {
+	return trustedNetwork{name: network.Name, ss58Prefix: network.Challenge.AllowedSS58Prefixes[0], verificationRepo: network.KYC.verificationRepo}
 }
 
-func NewKYCService(verificationRepo repository.VerificationRepository, tokenRepo repository.TokenRepository, idenfy idenfy.IdenfyClient, substrateClient substrate.SubstrateClient, config *config.Config, logger *slog.Logger) (*KYCService, error) {
-	idenfySuffix, err := GetIdenfySuffix(substrateClient, config)
+func NewKYCService(verificationRepo repository.VerificationRepository, tokenRepo repository.TokenRepository, attemptRepo repository.AttemptRepository, fingerprintRepo repository.FingerprintRepository, lockRepo repository.LockRepository, linkRepo repository.LinkRepository, sessionRepo repository.SessionRepository, idenfy idenfy.IdenfyClient, substrateClient substrate.SubstrateClient, allowedSS58Prefixes []uint16, config *config.Config, logger *slog.Logger) (*KYCService, error) {
+	scope, err := GetScope(substrateClient, config)
+	if err != nil {
+		return nil, fmt.Errorf("getting scope: %w", err)
+	}
+	eligibilityPolicy, err := eligibility.New(config.Eligibility.Rules, allowedSS58Prefixes, verificationRepo, attemptRepo, substrateClient, logger)
 	if err != nil {
-		return nil, fmt.Errorf("getting idenfy suffix: %w", err)
+		return nil, fmt.Errorf("creating eligibility policy: %w", err)
 	}
-	return &KYCService{verificationRepo: verificationRepo, tokenRepo: tokenRepo, idenfy: idenfy, substrate: substrateClient, config: &config.Verification, logger: logger, IdenfySuffix: idenfySuffix}, nil
+	return &KYCService{verificationRepo: verificationRepo, tokenRepo: tokenRepo, attemptRepo: attemptRepo, fingerprintRepo: fingerprintRepo, lockRepo: lockRepo, linkRepo: linkRepo, sessionRepo: sessionRepo, idenfy: idenfy, substrate: substrateClient, eligibility: eligibilityPolicy, outcome: outcome.New(&config.Verification), config: &config.Verification, logger: logger, scope: scope, IdenfySuffix: idenfySuffix(scope)}, nil
 }
 
 func GetIdenfySuffix(substrateClient substrate.SubstrateClient, config *config.Config) (string, error) {
-	idenfySuffix, err := GetChainNetworkName(substrateClient)
+	scope, err := GetScope(substrateClient, config)
 	if err != nil {
-		return "", fmt.Errorf("getting chain network name: %w", err)
+		return "", err
+	}
+	return idenfySuffix(scope), nil
+}
+
+func idenfySuffix(scope repository.Scope) string {
+	if scope.Namespace != "" {
+		return scope.Namespace + ":" + scope.Network
 	}
-	if config.Idenfy.Namespace != "" {
-		idenfySuffix = config.Idenfy.Namespace + ":" + idenfySuffix
+	return scope.Network
+}
+
+// GetScope returns the scope of the deployment, its TFChain network and iDenfy namespace, which the iDenfy suffix is made of
+func GetScope(substrateClient substrate.SubstrateClient, config *config.Config) (repository.Scope, error) {
+	network, err := GetChainNetworkName(substrateClient)
+	if err != nil {
+		return repository.Scope{}, fmt.Errorf("getting chain network name: %w", err)
 	}
-	return idenfySuffix, nil
+	return repository.Scope{Network: network, Namespace: config.Idenfy.Namespace}, nil
 }
 
 func GetChainNetworkName(substrateClient substrate.SubstrateClient) (string, error) {
@@ -65,7 +118,7 @@ func GetChainNetworkName(substrateClient substrate.SubstrateClient) (string, err
 // -----------------------------
 // Token related methods
 // -----------------------------
-func (s *KYCService) GetOrCreateVerificationToken(ctx context.Context, clientID string) (*models.Token, bool, error) {
+func (s *KYCService) GetOrCreateVerificationToken(ctx context.Context, clientID string, country string) (*models.Token, bool, error) {
 	isVerified, err := s.IsUserVerified(ctx, clientID)
 	if err != nil {
 		s.logger.Error("Error checking if user is verified", "clientID", clientID, "error", err)
@@ -74,6 +127,12 @@ func (s *KYCService) GetOrCreateVerificationToken(ctx context.Context, clientID
 	if isVerified {
 		return nil, false, errors.NewConflictError("user already verified", nil) // TODO: implement a custom error that can be converted in the handler to a 4xx such 409 status code
 	}
+	// serialize session creation per client across instances, a concurrent request waits and then gets the token created by the first one
+	release, err := s.lockSessionCreation(ctx, clientID)
+	if err != nil {
+		return nil, false, err
+	}
+	defer release()
 	token, err_ := s.tokenRepo.GetToken(ctx, clientID)
 	if err_ != nil {
 		s.logger.Error("Error getting token from database", "clientID", clientID, "error", err_)
@@ -89,54 +148,175 @@ func (s *KYCService) GetOrCreateVerificationToken(ctx context.Context, clientID
 		}
 	}
 
-	// check if user account balance satisfies the minimum required balance, return an error if not
-	hasRequiredBalance, err_ := s.AccountHasRequiredBalance(ctx, clientID)
+	// check if the client is still in the cooldown of a denied verification, the attempts cap is an eligibility rule
+	err_ = s.checkDenialCooldown(ctx, clientID)
 	if err_ != nil {
-		s.logger.Error("Error checking if user account has required balance", "clientID", clientID, "error", err_)
-		return nil, false, errors.NewExternalError("checking if user account has required balance", err_)
+		return nil, false, err_
 	}
-	if !hasRequiredBalance {
-		requiredBalance := s.config.MinBalanceToVerifyAccount / TFT_CONVERSION_FACTOR
-		return nil, false, errors.NewNotSufficientBalanceError(fmt.Sprintf("account does not have the minimum required balance to verify (%d) TFT", requiredBalance), nil)
+	// check if the client is eligible to start a new verification session, return the reason if not
+	err_ = s.eligibility.Evaluate(ctx, eligibility.Subject{ClientID: clientID, Country: country})
+	if err_ != nil {
+		return nil, false, err_
 	}
 	// prefix clientID with tfchain network prefix
 	uniqueClientID := clientID + ":" + s.IdenfySuffix
 	newToken, err_ := s.idenfy.CreateVerificationSession(ctx, uniqueClientID)
 	if err_ != nil {
 		s.logger.Error("Error creating iDenfy verification session", "clientID", clientID, "uniqueClientID", uniqueClientID, "error", err_)
-		return nil, false, errors.NewExternalError("creating iDenfy verification session", err_)
+		return nil, false, idenfyError("creating iDenfy verification session", err_)
 	}
 	// save the token with the original clientID
 	newToken.ClientID = clientID
-	err_ = s.tokenRepo.SaveToken(ctx, &newToken)
+	err_ = s.saveToken(ctx, &newToken)
+	if err_ != nil {
+		s.logger.Error("Error saving verification token to database", "clientID", clientID, "scanRef", newToken.ScanRef, "error", err_)
+		// an unsaved session would be replaced by another paid one on the next request, expire it instead of returning it
+		s.expireOrphanedSession(ctx, &newToken)
+		return nil, false, errors.NewInternalError("saving verification token to database, please retry", err_)
+	}
+	err_ = s.attemptRepo.RecordAttempt(ctx, clientID, newToken.ScanRef)
 	if err_ != nil {
-		s.logger.Error("Error saving verification token to database", "clientID", clientID, "error", err_)
+		s.logger.Error("Error recording verification attempt to database", "clientID", clientID, "scanRef", newToken.ScanRef, "error", err_)
 	}
+	s.trackSession(ctx, &newToken)
 
 	return &newToken, true, nil
 }
 
-func (s *KYCService) DeleteToken(ctx context.Context, clientID string, scanRef string) error {
+// saveToken saves the token, retrying transient database failures. A duplicate token is not transient and is not retried.
+func (s *KYCService) saveToken(ctx context.Context, token *models.Token) error {
+	var err error
+	for attempt := 1; attempt <= SAVE_TOKEN_ATTEMPTS; attempt++ {
+		if err = s.tokenRepo.SaveToken(ctx, token); err == nil {
+			return nil
+		}
+		if attempt == SAVE_TOKEN_ATTEMPTS || stderrors.Is(err, repository.ErrDuplicateKey) {
+			break
+		}
+		s.logger.Warn("Error saving verification token to database, retrying", "clientID", token.ClientID, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * SAVE_TOKEN_RETRY_DELAY):
+		}
+	}
+	return err
+}
 
-	err := s.tokenRepo.DeleteToken(ctx, clientID, scanRef)
+// expireOrphanedSession expires an iDenfy session that could not be saved, so it can not be used nor charged again.
+// Sessions that can not be expired are logged for manual reconciliation.
+func (s *KYCService) expireOrphanedSession(ctx context.Context, token *models.Token) {
+	// the request context may be the reason saving failed
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+	if err := s.idenfy.ExpireVerificationSession(ctx, token.AuthToken); err != nil {
+		s.logger.Error("Error expiring orphaned iDenfy verification session, it should be reconciled manually", "clientID", token.ClientID, "scanRef", token.ScanRef, "error", err)
+		return
+	}
+	s.logger.Info("Expired orphaned iDenfy verification session", "clientID", token.ClientID, "scanRef", token.ScanRef)
+}
+
+// idenfyError maps the errors of the iDenfy client to a service error telling the client and the operator what failed,
+// the network and server errors are external errors
+func idenfyError(msg string, err error) *errors.ServiceError {
+	switch {
+	case stderrors.Is(err, idenfy.ErrAuthFailed):
+		return errors.NewExternalAuthError(msg+": iDenfy rejected the service credentials, please contact the service operator", err)
+	case stderrors.Is(err, idenfy.ErrBadRequest):
+		return errors.NewExternalBadRequestError(msg+": iDenfy rejected the request", err)
+	case stderrors.Is(err, idenfy.ErrQuotaExceeded):
+		return errors.NewExternalQuotaExceededError(msg+": the iDenfy verification quota is exhausted, please retry later", err)
+	case stderrors.Is(err, idenfy.ErrRateLimited):
+		return errors.NewExternalRateLimitedError(msg+": iDenfy is rate limiting the requests, please retry later", err)
+	}
+	return errors.NewExternalError(msg, err)
+}
+
+// lockSessionCreation waits until it holds the session creation lease of the client and returns the function releasing it
+func (s *KYCService) lockSessionCreation(ctx context.Context, clientID string) (func(), error) {
+	return s.lock(ctx, s.lockName("verification-session", clientID), "verification session", "a verification session is already being created for this client, retry later")
+}
+
+// lockName is the name of the lease of the client in the scope, the same client has its own leases on each network
+func (s *KYCService) lockName(kind string, clientID string) string {
+	return fmt.Sprintf("%s:%s:%s:%s", kind, s.scope.Network, s.scope.Namespace, clientID)
+}
+
+// lock waits until it holds the named lease, shared by all instances, and returns the function releasing it.
+// The lease expires after VERIFICATION_SESSION_LOCK_TTL if its instance dies before releasing it.
+func (s *KYCService) lock(ctx context.Context, name string, description string, busy string) (func(), error) {
+	owner := primitive.NewObjectID().Hex()
+	ttl := time.Duration(s.config.SessionLockTTL) * time.Second
+	for {
+		acquired, err := s.lockRepo.AcquireLock(ctx, name, owner, ttl)
+		if err != nil {
+			s.logger.Error("Error acquiring "+description+" lock", "lock", name, "error", err)
+			return nil, errors.NewInternalError("acquiring "+description+" lock", err)
+		}
+		if acquired {
+			return func() {
+				// release even if the request context is done, otherwise the client waits for the lease to expire
+				if err := s.lockRepo.ReleaseLock(context.WithoutCancel(ctx), name, owner); err != nil {
+					s.logger.Warn("Error releasing "+description+" lock", "lock", name, "error", err)
+				}
+			}, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, errors.NewConflictError(busy, ctx.Err())
+		case <-time.After(SESSION_LOCK_RETRY_INTERVAL):
+		}
+	}
+}
+
+func (s *KYCService) checkDenialCooldown(ctx context.Context, clientID string) error {
+	if s.config.DenialCooldown == 0 {
+		return nil
+	}
+	attempts, err := s.attemptRepo.GetAttempts(ctx, clientID)
 	if err != nil {
-		s.logger.Error("Error deleting verification token from database", "clientID", clientID, "scanRef", scanRef, "error", err)
-		return errors.NewInternalError("deleting verification token from database", err)
+		s.logger.Error("Error getting verification attempts from database", "clientID", clientID, "error", err)
+		return errors.NewInternalError("getting verification attempts from database", err)
+	}
+	if attempts == nil {
+		return nil
+	}
+	if attempts.LastDeniedAt != nil {
+		cooldownEndsAt := attempts.LastDeniedAt.Add(time.Duration(s.config.DenialCooldown) * time.Minute)
+		if time.Now().Before(cooldownEndsAt) {
+			return errors.NewTooManyAttemptsError(fmt.Sprintf("last verification was denied, try again after %s", cooldownEndsAt.UTC().Format(time.RFC3339)), nil)
+		}
 	}
 	return nil
 }
 
-func (s *KYCService) AccountHasRequiredBalance(ctx context.Context, address string) (bool, error) {
-	if s.config.MinBalanceToVerifyAccount == 0 {
-		s.logger.Warn("Minimum balance to verify account is 0 which is not recommended", "address", address)
-		return true, nil
+func (s *KYCService) GetVerificationAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	attempts, err := s.attemptRepo.GetAttempts(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error getting verification attempts from database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("getting verification attempts from database", err)
 	}
-	balance, err := s.substrate.GetAccountBalance(address)
+	return attempts, nil
+}
+
+func (s *KYCService) ResetVerificationAttempts(ctx context.Context, clientID string) error {
+	err := s.attemptRepo.ResetAttempts(ctx, clientID)
 	if err != nil {
-		s.logger.Error("Error getting account balance", "address", address, "error", err)
-		return false, errors.NewExternalError("getting account balance", err)
+		s.logger.Error("Error resetting verification attempts in database", "clientID", clientID, "error", err)
+		return errors.NewInternalError("resetting verification attempts in database", err)
 	}
-	return balance >= s.config.MinBalanceToVerifyAccount, nil
+	s.logger.Info("Verification attempts reset", "clientID", clientID)
+	return nil
+}
+
+func (s *KYCService) DeleteToken(ctx context.Context, clientID string, scanRef string) error {
+
+	err := s.tokenRepo.DeleteToken(ctx, clientID, scanRef)
+	if err != nil {
+		s.logger.Error("Error deleting verification token from database", "clientID", clientID, "scanRef", scanRef, "error", err)
+		return errors.NewInternalError("deleting verification token from database", err)
+	}
+	return nil
 }
 
 // -----------------------------
@@ -168,22 +348,129 @@ func (s *KYCService) GetVerificationStatus(ctx context.Context, clientID string)
 		s.logger.Error("Error getting verification from database", "clientID", clientID, "error", err)
 		return nil, errors.NewInternalError("getting verification from database", err)
 	}
-	var outcome models.Outcome
+	now := time.Now()
+	var verificationOutcome *models.VerificationOutcome
 	if verification != nil {
-		if verification.Status.Overall != nil && *verification.Status.Overall == models.OverallApproved || (s.config.SuspiciousVerificationOutcome == "APPROVED" && *verification.Status.Overall == models.OverallSuspected) {
-			outcome = models.OutcomeApproved
-		} else {
-			outcome = models.OutcomeRejected
+		verificationOutcome = s.verificationOutcome(clientID, verification, now)
+		if verificationOutcome.Outcome == models.OutcomeApproved {
+			return verificationOutcome, nil
 		}
-	} else {
+	}
+	// the client has no approved verification of its own, it may inherit one
+	inheritedOutcome, err := s.inheritedVerificationStatus(ctx, clientID, now)
+	if err != nil {
+		return nil, err
+	}
+	if inheritedOutcome != nil {
+		return inheritedOutcome, nil
+	}
+	return verificationOutcome, nil
+}
+
+// inheritedVerificationStatus returns the approved verification the client inherits from the primary account it is linked to,
+// or else from a trusted network
+func (s *KYCService) inheritedVerificationStatus(ctx context.Context, clientID string, now time.Time) (*models.VerificationOutcome, error) {
+	linkedOutcome, err := s.linkedVerificationStatus(ctx, clientID, now)
+	if err != nil || linkedOutcome != nil {
+		return linkedOutcome, err
+	}
+	return s.trustedVerificationStatus(ctx, clientID, now)
+}
+
+// linkedVerificationStatus returns the outcome of the approved verification of the primary account the client is linked to
+func (s *KYCService) linkedVerificationStatus(ctx context.Context, clientID string, now time.Time) (*models.VerificationOutcome, error) {
+	if s.linkRepo == nil {
+		return nil, nil
+	}
+	link, err := s.linkRepo.GetActiveLink(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error getting account link from database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("getting account link from database", err)
+	}
+	if link == nil {
 		return nil, nil
 	}
+	verification, err := s.verificationRepo.GetVerification(ctx, link.PrimaryClientID)
+	if err != nil {
+		s.logger.Error("Error getting verification from database", "clientID", link.PrimaryClientID, "error", err)
+		return nil, errors.NewInternalError("getting verification from database", err)
+	}
+	var primaryOutcome *models.VerificationOutcome
+	if verification != nil {
+		primaryOutcome = s.verificationOutcome(clientID, verification, now)
+	}
+	if primaryOutcome == nil || primaryOutcome.Outcome != models.OutcomeApproved {
+		// links are not chained, but the primary account may itself be verified on a trusted network
+		primaryOutcome, err = s.trustedVerificationStatus(ctx, link.PrimaryClientID, now)
+		if err != nil || primaryOutcome == nil {
+			return nil, err
+		}
+		primaryOutcome.ClientID = clientID
+	}
+	primaryOutcome.LinkedTo = link.PrimaryClientID
+	return primaryOutcome, nil
+}
+
+func (s *KYCService) verificationOutcome(clientID string, verification *models.Verification, now time.Time) *models.VerificationOutcome {
+	evaluation := s.outcome.Evaluate(verification, now)
+	if !evaluation.Approved() {
+		s.logger.Debug("Verification rejected by outcome policy", "clientID", clientID, "reasons", evaluation.Reasons)
+	}
 	return &models.VerificationOutcome{
-		Final:     verification.Final,
-		ClientID:  clientID,
-		IdenfyRef: verification.IdenfyRef,
-		Outcome:   outcome,
-	}, nil
+		Final:                  verification.Final,
+		ClientID:               clientID,
+		IdenfyRef:              verification.IdenfyRef,
+		Outcome:                evaluation.Outcome,
+		DocumentExpiresAt:      evaluation.DocumentExpiresAt,
+		ReverificationRequired: evaluation.DocumentExpired || verification.DocumentExpiryFlaggedAt != nil || s.documentExpiresSoon(evaluation.DocumentExpiresAt, now),
+	}
+}
+
+// trustedVerificationStatus returns the outcome of the first verification of the client public key approved on a linked network.
+// The verifications of the linked networks are evaluated by the outcome policy of this network.
+func (s *KYCService) trustedVerificationStatus(ctx context.Context, clientID string, now time.Time) (*models.VerificationOutcome, error) {
+	for _, network := range s.trusted {
+		// the same key has another address on networks with another prefix
+		trustedClientID, err := address.Reencode(clientID, network.ss58Prefix)
+		if err != nil {
+			return nil, err
+		}
+		verification, err := network.verificationRepo.GetVerification(ctx, trustedClientID)
+		if err != nil {
+			s.logger.Error("Error getting verification of linked network from database", "clientID", trustedClientID, "network", network.name, "error", err)
+			return nil, errors.NewInternalError("getting verification of linked network from database", err)
+		}
+		if verification == nil {
+			continue
+		}
+		verificationOutcome := s.verificationOutcome(clientID, verification, now)
+		if verificationOutcome.Outcome != models.OutcomeApproved {
+			continue
+		}
+		s.logger.Info("Accepting verification approved on linked network", "clientID", clientID, "network", network.name)
+		verificationOutcome.TrustedNetwork = network.name
+		return verificationOutcome, nil
+	}
+	return nil, nil
+}
+
+// GetVerificationClaims derives the requested claims from the verification of the client.
+// Claims are only derived from verifications approved by the outcome policy.
+func (s *KYCService) GetVerificationClaims(ctx context.Context, clientID string, requested []string) (claims.Claims, error) {
+	verification, err := s.verificationRepo.GetVerification(ctx, clientID)
+	if err != nil {
+		s.logger.Error("Error getting verification from database", "clientID", clientID, "error", err)
+		return nil, errors.NewInternalError("getting verification from database", err)
+	}
+	if verification == nil {
+		return nil, errors.NewNotFoundError("verification not found for client", nil)
+	}
+	now := time.Now()
+	if evaluation := s.outcome.Evaluate(verification, now); !evaluation.Approved() {
+		s.logger.Debug("Claims requested for verification rejected by outcome policy", "clientID", clientID, "reasons", evaluation.Reasons)
+		return nil, errors.NewDeniedError("verification is not approved", nil)
+	}
+	return claims.Derive(verification, requested, now), nil
 }
 
 func (s *KYCService) GetVerificationStatusByTwinID(ctx context.Context, twinID string) (*models.VerificationOutcome, error) {
@@ -207,23 +494,37 @@ func (s *KYCService) ProcessVerificationResult(ctx context.Context, body []byte,
 		s.logger.Error("Error verifying callback signature", "sigHeader", sigHeader, "error", err)
 		return errors.NewAuthorizationError("verifying callback signature", err)
 	}
-	clientIDParts := strings.Split(result.ClientID, ":")
-	if len(clientIDParts) < 2 {
+	return s.processVerificationResult(ctx, result)
+}
+
+// processVerificationResult saves the result of a verification session, either sent by the verification webhook or
+// polled by the reconciler
+func (s *KYCService) processVerificationResult(ctx context.Context, result models.Verification) error {
+	clientID, networkSuffix, found := strings.Cut(result.ClientID, ":")
+	if !found {
 		s.logger.Error("clientID have no network suffix", "clientID", result.ClientID)
 		return errors.NewInternalError("invalid clientID", nil)
 	}
-	networkSuffix := clientIDParts[len(clientIDParts)-1]
+	// the suffix is the namespace and the network, the verification is saved in the scope they stand for
 	if networkSuffix != s.IdenfySuffix {
 		s.logger.Error("clientID has different network suffix", "clientID", result.ClientID, "expectedSuffix", s.IdenfySuffix, "actualSuffix", networkSuffix)
 		return errors.NewInternalError("invalid clientID", nil)
 	}
 	// delete the token with the same clientID and same scanRef
-	result.ClientID = clientIDParts[0]
+	result.ClientID = clientID
 
-	err = s.tokenRepo.DeleteToken(ctx, result.ClientID, result.IdenfyRef)
+	err := s.tokenRepo.DeleteToken(ctx, result.ClientID, result.IdenfyRef)
 	if err != nil {
 		s.logger.Warn("Error deleting verification token from database", "clientID", result.ClientID, "scanRef", result.IdenfyRef, "error", err)
 	}
+	// check if the same identity was already used to verify another client
+	if result.Status.Overall != nil && (*result.Status.Overall == models.OverallApproved || *result.Status.Overall == models.OverallSuspected) {
+		err = s.checkDuplicateIdentity(ctx, &result)
+		if err != nil {
+			s.logger.Error("Error checking duplicate identity", "clientID", result.ClientID, "scanRef", result.IdenfyRef, "error", err)
+			return errors.NewInternalError("checking duplicate identity", err)
+		}
+	}
 	// if the verification status is EXPIRED, we don't need to save it
 	if result.Status.Overall != nil && *result.Status.Overall != models.Overall("EXPIRED") {
 		// remove idenfy suffix from clientID
@@ -232,11 +533,39 @@ func (s *KYCService) ProcessVerificationResult(ctx context.Context, body []byte,
 			s.logger.Error("Error saving verification to database", "clientID", result.ClientID, "scanRef", result.IdenfyRef, "error", err)
 			return errors.NewInternalError("saving verification to database", err)
 		}
+		if *result.Status.Overall == models.OverallDenied {
+			err = s.attemptRepo.RecordDenial(ctx, result.ClientID, time.Now())
+			if err != nil {
+				s.logger.Error("Error recording verification denial to database", "clientID", result.ClientID, "scanRef", result.IdenfyRef, "error", err)
+			}
+		}
+	}
+	// results still subject to a manual review keep the session reconciled
+	if result.Status.Overall != nil && result.Status.Overall.Final() && (result.Final == nil || *result.Final) {
+		s.resolveSession(ctx, result.IdenfyRef, *result.Status.Overall)
 	}
 	s.logger.Debug("Verification result processed successfully", "result", result)
 	return nil
 }
 
+func (s *KYCService) documentExpiresSoon(expiresAt *time.Time, now time.Time) bool {
+	if expiresAt == nil || s.config.DocumentExpiryWarningDays == 0 {
+		return false
+	}
+	return expiresAt.Before(now.AddDate(0, 0, int(s.config.DocumentExpiryWarningDays)))
+}
+
+// FlagExpiringDocuments flags the clients whose document expires before the given time, so they can be prompted to re-verify
+func (s *KYCService) FlagExpiringDocuments(ctx context.Context, until time.Time) ([]string, error) {
+	from := time.Now().UTC().Format(outcome.DocExpiryLayout)
+	clientIDs, err := s.verificationRepo.FlagExpiringDocuments(ctx, from, until.UTC().Format(outcome.DocExpiryLayout), time.Now())
+	if err != nil {
+		s.logger.Error("Error flagging expiring documents in database", "until", until, "error", err)
+		return nil, errors.NewInternalError("flagging expiring documents in database", err)
+	}
+	return clientIDs, nil
+}
+
 func (s *KYCService) ProcessDocExpirationNotification(ctx context.Context, clientID string) error {
 	return nil
 }
@@ -247,8 +576,14 @@ func (s *KYCService) IsUserVerified(ctx context.Context, clientID string) (bool,
 		s.logger.Error("Error getting verification from database", "clientID", clientID, "error", err)
 		return false, errors.NewInternalError("getting verification from database", err)
 	}
-	if verification == nil {
-		return false, nil
+	now := time.Now()
+	if verification != nil && s.outcome.Evaluate(verification, now).Approved() {
+		return true, nil
+	}
+	// users inheriting a verification don't pay for another one
+	inheritedOutcome, err := s.inheritedVerificationStatus(ctx, clientID, now)
+	if err != nil {
+		return false, err
 	}
-	return verification.Status.Overall != nil && (*verification.Status.Overall == models.OverallApproved || (s.config.SuspiciousVerificationOutcome == "APPROVED" && *verification.Status.Overall == models.OverallSuspected)), nil
+	return inheritedOutcome != nil, nil
 }
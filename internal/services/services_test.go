@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/address"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/eligibility"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/outcome"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+type fakeVerificationRepo struct {
+	mu            sync.Mutex
+	verifications []models.Verification
+}
+
+func (f *fakeVerificationRepo) SaveVerification(ctx context.Context, verification *models.Verification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.verifications = append(f.verifications, *verification)
+	return nil
+}
+
+func (f *fakeVerificationRepo) GetVerification(ctx context.Context, clientID string) (*models.Verification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := len(f.verifications) - 1; i >= 0; i-- {
+		if f.verifications[i].ClientID == clientID {
+			verification := f.verifications[i]
+			return &verification, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeVerificationRepo) GetVerificationsByScanRefs(ctx context.Context, scanRefs []string) ([]models.Verification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	verifications := []models.Verification{}
+	for _, verification := range f.verifications {
+		if slices.Contains(scanRefs, verification.IdenfyRef) {
+			verifications = append(verifications, verification)
+		}
+	}
+	return verifications, nil
+}
+
+func (f *fakeVerificationRepo) FlagExpiringDocuments(ctx context.Context, from string, until string, flaggedAt time.Time) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clientIDs := []string{}
+	seen := map[string]bool{}
+	// only the latest verification of each client is flagged
+	for i := len(f.verifications) - 1; i >= 0; i-- {
+		verification := &f.verifications[i]
+		if seen[verification.ClientID] {
+			continue
+		}
+		seen[verification.ClientID] = true
+		docExpiry := verification.Data.DocExpiry
+		if verification.DocumentExpiryFlaggedAt != nil || docExpiry < from || docExpiry > until {
+			continue
+		}
+		verification.DocumentExpiryFlaggedAt = &flaggedAt
+		clientIDs = append(clientIDs, verification.ClientID)
+	}
+	return clientIDs, nil
+}
+
+type fakeFingerprintRepo struct {
+	mu           sync.Mutex
+	fingerprints map[string]*models.IdentityFingerprint
+}
+
+func newFakeFingerprintRepo() *fakeFingerprintRepo {
+	return &fakeFingerprintRepo{fingerprints: map[string]*models.IdentityFingerprint{}}
+}
+
+func (f *fakeFingerprintRepo) GetFingerprint(ctx context.Context, fingerprint string) (*models.IdentityFingerprint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if identityFingerprint, ok := f.fingerprints[fingerprint]; ok {
+		copied := *identityFingerprint
+		copied.LinkedClientIDs = slices.Clone(identityFingerprint.LinkedClientIDs)
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+func (f *fakeFingerprintRepo) SaveFingerprint(ctx context.Context, fingerprint *models.IdentityFingerprint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.fingerprints[fingerprint.Fingerprint]; ok {
+		return repository.ErrDuplicateKey
+	}
+	f.fingerprints[fingerprint.Fingerprint] = fingerprint
+	return nil
+}
+
+// racingFingerprintRepo saves the concurrent fingerprint right before the next fingerprint is saved,
+// as another verification of the same identity completing in between would
+type racingFingerprintRepo struct {
+	*fakeFingerprintRepo
+	concurrent *models.IdentityFingerprint
+}
+
+func (f *racingFingerprintRepo) SaveFingerprint(ctx context.Context, fingerprint *models.IdentityFingerprint) error {
+	if f.concurrent != nil {
+		if err := f.fakeFingerprintRepo.SaveFingerprint(ctx, f.concurrent); err != nil {
+			return err
+		}
+		f.concurrent = nil
+	}
+	return f.fakeFingerprintRepo.SaveFingerprint(ctx, fingerprint)
+}
+
+func (f *fakeFingerprintRepo) LinkClient(ctx context.Context, fingerprint string, clientID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fingerprints[fingerprint].LinkedClientIDs = append(f.fingerprints[fingerprint].LinkedClientIDs, clientID)
+	return nil
+}
+
+const testFingerprintKey = "test-fingerprint-key-0123456789abcdef"
+
+func newApprovedVerification(clientID, scanRef, docNumber string) models.Verification {
+	approved := models.OverallApproved
+	return models.Verification{
+		ClientID:  clientID,
+		IdenfyRef: scanRef,
+		Status:    models.Status{Overall: &approved},
+		Data: models.PersonData{
+			DocNumber:         docNumber,
+			DocIssuingCountry: "BE",
+			DocDOB:            "1990-01-01",
+		},
+	}
+}
+
+func TestIdentityFingerprint(t *testing.T) {
+	data := models.PersonData{DocNumber: "ab 123", DocIssuingCountry: "be", DocDOB: "1990-01-01"}
+	normalized := models.PersonData{DocNumber: "AB123", DocIssuingCountry: "BE", DocDOB: "1990-01-01"}
+	assert.Equal(t, IdentityFingerprint(testFingerprintKey, normalized), IdentityFingerprint(testFingerprintKey, data))
+	assert.NotEqual(t, IdentityFingerprint(testFingerprintKey, data), IdentityFingerprint("another-key", data))
+	assert.Empty(t, IdentityFingerprint("", data))
+	assert.Empty(t, IdentityFingerprint(testFingerprintKey, models.PersonData{DocNumber: "AB123"}))
+}
+
+func TestCheckDuplicateIdentity(t *testing.T) {
+	tests := []struct {
+		name            string
+		outcome         models.DuplicateIdentityOutcome
+		expectedOverall models.Overall
+	}{
+		{name: "reject", outcome: models.DuplicateIdentityReject, expectedOverall: models.OverallDenied},
+		{name: "flag", outcome: models.DuplicateIdentityFlag, expectedOverall: models.OverallApproved},
+		{name: "link", outcome: models.DuplicateIdentityLink, expectedOverall: models.OverallApproved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprints := newFakeFingerprintRepo()
+			service := &KYCService{
+				verificationRepo: &fakeVerificationRepo{},
+				fingerprintRepo:  fingerprints,
+				config:           &config.Verification{FingerprintKey: testFingerprintKey, DuplicateIdentityOutcome: string(tt.outcome)},
+				logger:           slog.Default(),
+			}
+			first := newApprovedVerification("client-1", "scan-1", "AB123")
+			assert.NoError(t, service.checkDuplicateIdentity(context.Background(), &first))
+			assert.Nil(t, first.Duplicate)
+
+			second := newApprovedVerification("client-2", "scan-2", "AB123")
+			assert.NoError(t, service.checkDuplicateIdentity(context.Background(), &second))
+			assert.NotNil(t, second.Duplicate)
+			assert.Equal(t, "client-1", second.Duplicate.ClientID)
+			assert.Equal(t, models.DuplicateSourceFingerprint, second.Duplicate.Source)
+			assert.Equal(t, tt.expectedOverall, *second.Status.Overall)
+
+			fingerprint, _ := fingerprints.GetFingerprint(context.Background(), IdentityFingerprint(testFingerprintKey, second.Data))
+			assert.Equal(t, tt.outcome == models.DuplicateIdentityLink, slices.Contains(fingerprint.LinkedClientIDs, "client-2"))
+		})
+	}
+}
+
+func TestCheckDuplicateIdentityConcurrentFingerprint(t *testing.T) {
+	first := newApprovedVerification("client-1", "scan-1", "AB123")
+	fingerprint := IdentityFingerprint(testFingerprintKey, first.Data)
+	tests := []struct {
+		name              string
+		concurrentClient  string
+		expectedDuplicate bool
+	}{
+		{name: "another client", concurrentClient: "client-2", expectedDuplicate: true},
+		{name: "same client", concurrentClient: "client-1", expectedDuplicate: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fingerprints := &racingFingerprintRepo{
+				fakeFingerprintRepo: newFakeFingerprintRepo(),
+				concurrent:          &models.IdentityFingerprint{Fingerprint: fingerprint, ClientID: tt.concurrentClient, ScanRef: "scan-2"},
+			}
+			service := &KYCService{
+				verificationRepo: &fakeVerificationRepo{},
+				fingerprintRepo:  fingerprints,
+				config:           &config.Verification{FingerprintKey: testFingerprintKey, DuplicateIdentityOutcome: string(models.DuplicateIdentityReject)},
+				logger:           slog.Default(),
+			}
+			verification := first
+			assert.NoError(t, service.checkDuplicateIdentity(context.Background(), &verification))
+			if !tt.expectedDuplicate {
+				assert.Nil(t, verification.Duplicate)
+				assert.Equal(t, models.OverallApproved, *verification.Status.Overall)
+				return
+			}
+			assert.NotNil(t, verification.Duplicate)
+			assert.Equal(t, tt.concurrentClient, verification.Duplicate.ClientID)
+			assert.Equal(t, models.OverallDenied, *verification.Status.Overall)
+		})
+	}
+}
+
+func TestCheckDuplicateIdentityDuplicateFaces(t *testing.T) {
+	verifications := &fakeVerificationRepo{}
+	service := &KYCService{
+		verificationRepo: verifications,
+		fingerprintRepo:  newFakeFingerprintRepo(),
+		config:           &config.Verification{FingerprintKey: testFingerprintKey, DuplicateIdentityOutcome: string(models.DuplicateIdentityReject)},
+		logger:           slog.Default(),
+	}
+	first := newApprovedVerification("client-1", "scan-1", "AB123")
+	assert.NoError(t, verifications.SaveVerification(context.Background(), &first))
+
+	// a different document, but iDenfy matched the face with the first verification
+	second := newApprovedVerification("client-2", "scan-2", "CD456")
+	second.Data.DuplicateFaces = []string{"scan-1"}
+	assert.NoError(t, service.checkDuplicateIdentity(context.Background(), &second))
+	assert.NotNil(t, second.Duplicate)
+	assert.Equal(t, models.DuplicateSourceDuplicateFaces, second.Duplicate.Source)
+	assert.Equal(t, models.OverallDenied, *second.Status.Overall)
+	assert.Contains(t, second.Status.DenyReasons, models.DenyReasonDuplicateIdentity)
+}
+
+func TestGetVerificationStatusDocumentExpiry(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name                   string
+		docExpiry              string
+		expectedOutcome        models.Outcome
+		reverificationRequired bool
+	}{
+		{name: "valid document", docExpiry: now.AddDate(1, 0, 0).Format(outcome.DocExpiryLayout), expectedOutcome: models.OutcomeApproved},
+		{name: "document expires soon", docExpiry: now.AddDate(0, 0, 10).Format(outcome.DocExpiryLayout), expectedOutcome: models.OutcomeApproved, reverificationRequired: true},
+		{name: "expired document", docExpiry: now.AddDate(0, 0, -2).Format(outcome.DocExpiryLayout), expectedOutcome: models.OutcomeRejected, reverificationRequired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifications := &fakeVerificationRepo{}
+			verificationConfig := &config.Verification{
+				SuspiciousVerificationOutcome: "APPROVED",
+				ExpiredDocumentOutcome:        "REJECTED",
+				DocumentExpiryWarningDays:     30,
+			}
+			service := &KYCService{
+				verificationRepo: verifications,
+				outcome:          outcome.New(verificationConfig),
+				config:           verificationConfig,
+				logger:           slog.Default(),
+			}
+			verification := newApprovedVerification("client-1", "scan-1", "AB123")
+			verification.Data.DocExpiry = tt.docExpiry
+			assert.NoError(t, verifications.SaveVerification(context.Background(), &verification))
+
+			status, err := service.GetVerificationStatus(context.Background(), "client-1")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOutcome, status.Outcome)
+			assert.Equal(t, tt.reverificationRequired, status.ReverificationRequired)
+			assert.NotNil(t, status.DocumentExpiresAt)
+		})
+	}
+}
+
+func TestFlagExpiringDocuments(t *testing.T) {
+	now := time.Now().UTC()
+	verifications := &fakeVerificationRepo{}
+	service := &KYCService{verificationRepo: verifications, config: &config.Verification{}, logger: slog.Default()}
+	expiring := newApprovedVerification("client-1", "scan-1", "AB123")
+	expiring.Data.DocExpiry = now.AddDate(0, 0, 5).Format(outcome.DocExpiryLayout)
+	valid := newApprovedVerification("client-2", "scan-2", "CD456")
+	valid.Data.DocExpiry = now.AddDate(1, 0, 0).Format(outcome.DocExpiryLayout)
+	assert.NoError(t, verifications.SaveVerification(context.Background(), &expiring))
+	assert.NoError(t, verifications.SaveVerification(context.Background(), &valid))
+	// a client who verified again with a renewed document is not flagged for the previous one
+	previous := newApprovedVerification("client-3", "scan-3", "EF789")
+	previous.Data.DocExpiry = now.AddDate(0, 0, 5).Format(outcome.DocExpiryLayout)
+	renewed := newApprovedVerification("client-3", "scan-4", "GH012")
+	renewed.Data.DocExpiry = now.AddDate(5, 0, 0).Format(outcome.DocExpiryLayout)
+	assert.NoError(t, verifications.SaveVerification(context.Background(), &previous))
+	assert.NoError(t, verifications.SaveVerification(context.Background(), &renewed))
+
+	clientIDs, err := service.FlagExpiringDocuments(context.Background(), now.AddDate(0, 0, 30))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"client-1"}, clientIDs)
+
+	// already flagged clients are not flagged again
+	clientIDs, err = service.FlagExpiringDocuments(context.Background(), now.AddDate(0, 0, 30))
+	assert.NoError(t, err)
+	assert.Empty(t, clientIDs)
+}
+
+func TestProcessVerificationResultNetworkSuffix(t *testing.T) {
+	verifications := &fakeVerificationRepo{}
+	service := newTokenTestService(t, &fakeIdenfy{}, &fakeTokenRepo{tokens: map[string]models.Token{}})
+	service.verificationRepo = verifications
+	// a namespaced deployment, the suffix has several parts
+	service.IdenfySuffix = "qa:devnet"
+	denied := models.OverallDenied
+
+	for _, clientID := range []string{"client", "client:devnet", "client:dev:devnet"} {
+		err := service.ProcessVerificationResult(context.Background(), nil, "", models.Verification{ClientID: clientID, IdenfyRef: "scan", Status: models.Status{Overall: &denied}})
+		assert.Error(t, err, clientID)
+	}
+	assert.Empty(t, verifications.verifications)
+
+	err := service.ProcessVerificationResult(context.Background(), nil, "", models.Verification{ClientID: "client:qa:devnet", IdenfyRef: "scan", Status: models.Status{Overall: &denied}})
+	assert.NoError(t, err)
+	assert.Len(t, verifications.verifications, 1)
+	assert.Equal(t, "client", verifications.verifications[0].ClientID)
+}
+
+func TestGetVerificationStatusTrustedNetwork(t *testing.T) {
+	// the same key is encoded with the substrate prefix on mainnet and with the polkadot prefix on testnet
+	mainnetClientID := "5GrwvaEF5zXb26Fz9rcQpDWS57CtERHpNehXCPcNoHGKutQY"
+	testnetClientID, err := address.Reencode(mainnetClientID, 0)
+	assert.NoError(t, err)
+
+	newNetwork := func(name string, prefix uint16) (*Network, *fakeVerificationRepo) {
+		verifications := &fakeVerificationRepo{}
+		verificationConfig := &config.Verification{SuspiciousVerificationOutcome: "APPROVED", ExpiredDocumentOutcome: "REJECTED"}
+		service := &KYCService{verificationRepo: verifications, outcome: outcome.New(verificationConfig), config: verificationConfig, logger: slog.Default(), IdenfySuffix: name}
+		return &Network{Name: name, Challenge: config.Challenge{AllowedSS58Prefixes: []uint16{prefix}}, KYC: service}, verifications
+	}
+	mainnet, mainnetVerifications := newNetwork("main", 42)
+	testnet, testnetVerifications := newNetwork("test", 0)
+	networks, err := NewNetworks(mainnet, testnet)
+	assert.NoError(t, err)
+	assert.NoError(t, networks.Trust(map[string][]string{"test": {"main"}}))
+
+	// nothing to accept yet
+	status, err := testnet.KYC.GetVerificationStatus(context.Background(), testnetClientID)
+	assert.NoError(t, err)
+	assert.Nil(t, status)
+
+	denied := models.OverallDenied
+	assert.NoError(t, testnetVerifications.SaveVerification(context.Background(), &models.Verification{ClientID: testnetClientID, IdenfyRef: "scan-test", Status: models.Status{Overall: &denied}}))
+	approved := newApprovedVerification(mainnetClientID, "scan-main", "AB123")
+	assert.NoError(t, mainnetVerifications.SaveVerification(context.Background(), &approved))
+
+	status, err = testnet.KYC.GetVerificationStatus(context.Background(), testnetClientID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.OutcomeApproved, status.Outcome)
+	assert.Equal(t, testnetClientID, status.ClientID)
+	assert.Equal(t, "scan-main", status.IdenfyRef)
+	assert.Equal(t, "main", status.TrustedNetwork)
+	verified, err := testnet.KYC.IsUserVerified(context.Background(), testnetClientID)
+	assert.NoError(t, err)
+	assert.True(t, verified)
+
+	// the trust is one way
+	status, err = mainnet.KYC.GetVerificationStatus(context.Background(), mainnetClientID)
+	assert.NoError(t, err)
+	assert.Empty(t, status.TrustedNetwork)
+	verified, err = mainnet.KYC.IsUserVerified(context.Background(), "5FHneW46xGXgs5mUiveU4sbTyGBzmstUspZC92UhjJM694ty")
+	assert.NoError(t, err)
+	assert.False(t, verified)
+}
+
+func TestVerificationAttemptLimits(t *testing.T) {
+	ctx := context.Background()
+	tokens := &fakeTokenRepo{tokens: map[string]models.Token{}}
+	service := newTokenTestService(t, &fakeIdenfy{}, tokens)
+	attempts := &fakeAttemptRepo{}
+	service.attemptRepo = attempts
+	policy, err := eligibility.New([]config.EligibilityRule{{Type: eligibility.RuleMaxAttempts, MaxAttempts: 2}}, nil, nil, attempts, nil, slog.Default())
+	assert.NoError(t, err)
+	service.eligibility = policy
+	service.config.DenialCooldown = 60
+
+	// startNewSession drops the current token, so the next request starts a new session
+	startNewSession := func() error {
+		token, _, err := service.GetOrCreateVerificationToken(ctx, "client", "")
+		if err != nil {
+			return err
+		}
+		return tokens.DeleteToken(ctx, "client", token.ScanRef)
+	}
+	var serviceError *errors.ServiceError
+
+	// the attempts cap
+	assert.NoError(t, startNewSession())
+	assert.NoError(t, startNewSession())
+	err = startNewSession()
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeTooManyAttempts, serviceError.Type)
+	assert.ErrorContains(t, err, "maximum number of verification attempts (2)")
+
+	// an operator reset gives the attempts back
+	assert.NoError(t, service.ResetVerificationAttempts(ctx, "client"))
+	reset, err := service.GetVerificationAttempts(ctx, "client")
+	assert.NoError(t, err)
+	assert.Nil(t, reset)
+	assert.NoError(t, startNewSession())
+
+	// the denial cooldown
+	assert.NoError(t, attempts.RecordDenial(ctx, "client", time.Now().Add(-time.Minute)))
+	err = startNewSession()
+	assert.ErrorAs(t, err, &serviceError)
+	assert.Equal(t, errors.ErrorTypeTooManyAttempts, serviceError.Type)
+	assert.ErrorContains(t, err, "last verification was denied")
+	assert.NoError(t, attempts.RecordDenial(ctx, "client", time.Now().Add(-2*time.Hour)))
+	assert.NoError(t, startNewSession())
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/clients/idenfy"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/eligibility"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/models"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/outcome"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/repository"
+)
+
+type fakeTokenRepo struct {
+	mu     sync.Mutex
+	tokens map[string]models.Token
+	// failures is the number of next saves failing
+	failures int
+	// duplicates counts the saves rejected as duplicates
+	duplicates int
+}
+
+func (f *fakeTokenRepo) SaveToken(ctx context.Context, token *models.Token) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return fmt.Errorf("connection reset")
+	}
+	token.CreatedAt = time.Now()
+	token.ExpiresAt = token.CreatedAt.Add(time.Duration(token.ExpiryTime) * time.Second)
+	// same as the unique clientId index, an expired token not purged yet is replaced
+	if existing, ok := f.tokens[token.ClientID]; ok && existing.ExpiresAt.After(token.CreatedAt) {
+		f.duplicates++
+		return repository.ErrDuplicateKey
+	}
+	f.tokens[token.ClientID] = *token
+	return nil
+}
+
+func (f *fakeTokenRepo) GetToken(ctx context.Context, clientID string) (*models.Token, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	token, ok := f.tokens[clientID]
+	// expired tokens are ignored until they are purged
+	if !ok || !token.ExpiresAt.After(time.Now()) {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (f *fakeTokenRepo) DeleteToken(ctx context.Context, clientID string, scanRef string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.tokens, clientID)
+	return nil
+}
+
+type fakeAttemptRepo struct {
+	mu       sync.Mutex
+	attempts map[string]*models.VerificationAttempts
+}
+
+func (f *fakeAttemptRepo) GetAttempts(ctx context.Context, clientID string) (*models.VerificationAttempts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	attempts, ok := f.attempts[clientID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *attempts
+	return &copied, nil
+}
+func (f *fakeAttemptRepo) RecordAttempt(ctx context.Context, clientID string, scanRef string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	attempts := f.attempt(clientID)
+	attempts.Count++
+	attempts.LastScanRef = scanRef
+	return nil
+}
+func (f *fakeAttemptRepo) RecordDenial(ctx context.Context, clientID string, deniedAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempt(clientID).LastDeniedAt = &deniedAt
+	return nil
+}
+func (f *fakeAttemptRepo) ResetAttempts(ctx context.Context, clientID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.attempts, clientID)
+	return nil
+}
+
+// attempt returns the attempts of the client, created if needed, the lock should be held
+func (f *fakeAttemptRepo) attempt(clientID string) *models.VerificationAttempts {
+	if f.attempts == nil {
+		f.attempts = map[string]*models.VerificationAttempts{}
+	}
+	if _, ok := f.attempts[clientID]; !ok {
+		f.attempts[clientID] = &models.VerificationAttempts{ClientID: clientID}
+	}
+	return f.attempts[clientID]
+}
+
+// fakeLockRepo holds leases in memory, like the lock collection shared by all instances
+type fakeLockRepo struct {
+	mu     sync.Mutex
+	leases map[string]fakeLease
+}
+
+type fakeLease struct {
+	owner     string
+	expiresAt time.Time
+}
+
+func (f *fakeLockRepo) AcquireLock(ctx context.Context, name string, owner string, ttl time.Duration) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if lease, ok := f.leases[name]; ok && time.Now().Before(lease.expiresAt) {
+		return false, nil
+	}
+	f.leases[name] = fakeLease{owner: owner, expiresAt: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (f *fakeLockRepo) ReleaseLock(ctx context.Context, name string, owner string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if lease, ok := f.leases[name]; ok && lease.owner == owner {
+		delete(f.leases, name)
+	}
+	return nil
+}
+
+// fakeIdenfy counts the created sessions, each of them being paid for
+type fakeIdenfy struct {
+	sessions atomic.Int32
+	mu       sync.Mutex
+	expired  []string
+	// statuses are the session statuses returned by the status API, by scanRef
+	statuses map[string]models.SessionStatus
+	// createErr fails the session creation
+	createErr error
+}
+
+func (f *fakeIdenfy) CreateVerificationSession(ctx context.Context, clientID string) (models.Token, error) {
+	if f.createErr != nil {
+		return models.Token{}, f.createErr
+	}
+	n := f.sessions.Add(1)
+	// leave time for concurrent requests to miss the token
+	time.Sleep(50 * time.Millisecond)
+	return models.Token{AuthToken: fmt.Sprintf("auth-%d", n), ScanRef: fmt.Sprintf("scan-%d", n), ExpiryTime: 3600}, nil
+}
+
+func (f *fakeIdenfy) ExpireVerificationSession(ctx context.Context, authToken string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expired = append(f.expired, authToken)
+	return nil
+}
+
+func (f *fakeIdenfy) GetSessionStatus(ctx context.Context, scanRef string) (models.SessionStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	status, ok := f.statuses[scanRef]
+	if !ok {
+		return models.SessionStatus{}, fmt.Errorf("unexpected status code from iDenfy: 404")
+	}
+	return status, nil
+}
+
+func (f *fakeIdenfy) GetSessionData(ctx context.Context, scanRef string) (models.PersonData, error) {
+	return models.PersonData{DocNumber: "AB123", DocIssuingCountry: "BE", DocDOB: "1990-01-01"}, nil
+}
+
+func (f *fakeIdenfy) VerifyCallbackSignature(ctx context.Context, body []byte, sigHeader string) error {
+	return nil
+}
+
+func newTokenTestService(t *testing.T, idenfyClient *fakeIdenfy, tokens *fakeTokenRepo) *KYCService {
+	eligibilityPolicy, err := eligibility.New(nil, nil, nil, nil, nil, slog.Default())
+	assert.NoError(t, err)
+	verificationConfig := &config.Verification{
+		SuspiciousVerificationOutcome: "APPROVED",
+		ExpiredDocumentOutcome:        "REJECTED",
+		SessionLockTTL:                30,
+		ReconciliationInterval:        15,
+		ReconciliationTimeout:         60,
+		ReconciliationMaxAge:          10080,
+	}
+	return &KYCService{
+		verificationRepo: &fakeVerificationRepo{},
+		tokenRepo:        tokens,
+		attemptRepo:      &fakeAttemptRepo{},
+		lockRepo:         &fakeLockRepo{leases: map[string]fakeLease{}},
+		sessionRepo:      &fakeSessionRepo{},
+		idenfy:           idenfyClient,
+		eligibility:      eligibilityPolicy,
+		outcome:          outcome.New(verificationConfig),
+		config:           verificationConfig,
+		logger:           slog.Default(),
+		scope:            repository.Scope{Network: "devnet"},
+		IdenfySuffix:     "devnet",
+	}
+}
+
+func TestGetOrCreateVerificationTokenConcurrent(t *testing.T) {
+	idenfyClient := &fakeIdenfy{}
+	tokens := &fakeTokenRepo{tokens: map[string]models.Token{}}
+	service := newTokenTestService(t, idenfyClient, tokens)
+
+	const requests = 10
+	var wg sync.WaitGroup
+	var created atomic.Int32
+	authTokens := make([]string, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			token, isNew, err := service.GetOrCreateVerificationToken(ctx, "client", "")
+			if !assert.NoError(t, err) {
+				return
+			}
+			if isNew {
+				created.Add(1)
+			}
+			authTokens[i] = token.AuthToken
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), idenfyClient.sessions.Load(), "only one iDenfy session should be created")
+	assert.Equal(t, int32(1), created.Load())
+	for _, authToken := range authTokens {
+		assert.Equal(t, "auth-1", authToken)
+	}
+}
+
+func TestGetOrCreateVerificationTokenIdenfyErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		expectedType errors.ErrorType
+	}{
+		{name: "auth failure", err: &idenfy.APIError{StatusCode: 401, Identifier: "UNAUTHORIZED"}, expectedType: errors.ErrorTypeExternalAuth},
+		{name: "bad request", err: &idenfy.APIError{StatusCode: 400, Identifier: "MISSING_PARAMETER", Parameters: []string{"clientId"}}, expectedType: errors.ErrorTypeExternalBadRequest},
+		{name: "quota exceeded", err: &idenfy.APIError{StatusCode: 402}, expectedType: errors.ErrorTypeExternalQuotaExceeded},
+		{name: "rate limited", err: &idenfy.APIError{StatusCode: 429}, expectedType: errors.ErrorTypeExternalRateLimited},
+		{name: "server error", err: &idenfy.APIError{StatusCode: 500}, expectedType: errors.ErrorTypeExternal},
+		{name: "open circuit breaker", err: idenfy.ErrCircuitOpen, expectedType: errors.ErrorTypeExternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newTokenTestService(t, &fakeIdenfy{createErr: fmt.Errorf("sending token request to iDenfy: %w", tt.err)}, &fakeTokenRepo{tokens: map[string]models.Token{}})
+
+			_, _, err := service.GetOrCreateVerificationToken(context.Background(), "client", "")
+			var serviceError *errors.ServiceError
+			assert.ErrorAs(t, err, &serviceError)
+			assert.Equal(t, tt.expectedType, serviceError.Type)
+			// the iDenfy error payload is reported
+			assert.ErrorContains(t, err, tt.err.Error())
+		})
+	}
+}
+
+func TestGetOrCreateVerificationTokenLockTimeout(t *testing.T) {
+	service := newTokenTestService(t, &fakeIdenfy{}, &fakeTokenRepo{tokens: map[string]models.Token{}})
+	// another instance holds the lease
+	acquired, err := service.lockRepo.AcquireLock(context.Background(), "verification-session:devnet::client", "other", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, _, err = service.GetOrCreateVerificationToken(ctx, "client", "")
+	assert.ErrorContains(t, err, "already being created")
+}
+
+func TestGetOrCreateVerificationTokenLockScope(t *testing.T) {
+	service := newTokenTestService(t, &fakeIdenfy{}, &fakeTokenRepo{tokens: map[string]models.Token{}})
+	// the same client creating a session on another network doesn't hold the lease of this one
+	acquired, err := service.lockRepo.AcquireLock(context.Background(), "verification-session:qanet::client", "other", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, _, err = service.GetOrCreateVerificationToken(ctx, "client", "")
+	assert.NoError(t, err)
+}
+
+func TestGetOrCreateVerificationTokenSaveFailure(t *testing.T) {
+	tests := []struct {
+		name            string
+		failures        int
+		expectedErr     bool
+		expectedExpired []string
+	}{
+		{name: "transient failure is retried", failures: SAVE_TOKEN_ATTEMPTS - 1},
+		{name: "unsaved session is expired", failures: SAVE_TOKEN_ATTEMPTS, expectedErr: true, expectedExpired: []string{"auth-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idenfyClient := &fakeIdenfy{}
+			tokens := &fakeTokenRepo{tokens: map[string]models.Token{}, failures: tt.failures}
+			service := newTokenTestService(t, idenfyClient, tokens)
+
+			token, isNew, err := service.GetOrCreateVerificationToken(context.Background(), "client", "")
+			if tt.expectedErr {
+				assert.ErrorContains(t, err, "please retry")
+				assert.Nil(t, token)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, isNew)
+				saved, err := tokens.GetToken(context.Background(), "client")
+				assert.NoError(t, err)
+				assert.Equal(t, token.AuthToken, saved.AuthToken)
+			}
+			assert.Equal(t, tt.expectedExpired, idenfyClient.expired)
+		})
+	}
+}
+
+func TestGetOrCreateVerificationTokenExpiredToken(t *testing.T) {
+	idenfyClient := &fakeIdenfy{}
+	// the expired token is still stored, the TTL monitor hasn't deleted it yet
+	createdAt := time.Now().Add(-2 * time.Hour)
+	tokens := &fakeTokenRepo{tokens: map[string]models.Token{
+		"client": {ClientID: "client", AuthToken: "expired", ScanRef: "expired", ExpiryTime: 3600, CreatedAt: createdAt, ExpiresAt: createdAt.Add(time.Hour)},
+	}}
+	service := newTokenTestService(t, idenfyClient, tokens)
+
+	token, isNew, err := service.GetOrCreateVerificationToken(context.Background(), "client", "")
+	assert.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, "auth-1", token.AuthToken)
+	assert.Equal(t, int32(1), idenfyClient.sessions.Load())
+	assert.Empty(t, idenfyClient.expired)
+	saved, err := tokens.GetToken(context.Background(), "client")
+	assert.NoError(t, err)
+	assert.Equal(t, "auth-1", saved.AuthToken)
+
+	// a live token is a duplicate, it is not retried
+	err = service.saveToken(context.Background(), &models.Token{ClientID: "client", AuthToken: "auth-2", ScanRef: "scan-2", ExpiryTime: 3600})
+	assert.ErrorIs(t, err, repository.ErrDuplicateKey)
+	assert.Equal(t, 1, tokens.duplicates)
+}
@@ -0,0 +1,87 @@
+/*
+Package session contains the session tokens for the application.
+This layer is responsible for issuing and verifying the short-lived tokens a client gets after signing a challenge,
+so web frontends don't need a fresh signature for every authenticated call.
+*/
+package session
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/errors"
+)
+
+// Token is a signed session token bound to a client SS58 address
+type Token struct {
+	Token     string
+	ClientID  string
+	ExpiresAt time.Time
+}
+
+// Manager issues and verifies HMAC-SHA256 signed JWT session tokens
+type Manager struct {
+	signingKey []byte
+	ttl        time.Duration
+	issuer     string
+}
+
+// NewManager creates a session manager, the issuer is the challenge domain so tokens of other deployments are rejected
+func NewManager(config config.Session, issuer string) *Manager {
+	return &Manager{
+		signingKey: []byte(config.SigningKey),
+		ttl:        time.Duration(config.TTL) * time.Minute,
+		issuer:     issuer,
+	}
+}
+
+// Issue issues a session token for the client
+func (m *Manager) Issue(clientID string) (*Token, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.ttl)
+	claims := jwt.RegisteredClaims{
+		Issuer:    m.issuer,
+		Subject:   clientID,
+		Audience:  jwt.ClaimStrings{m.issuer},
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.signingKey)
+	if err != nil {
+		return nil, errors.NewInternalError("signing session token", err)
+	}
+	return &Token{Token: signed, ClientID: clientID, ExpiresAt: expiresAt}, nil
+}
+
+// Verify verifies the session token and returns the client SS58 address it is bound to
+func (m *Manager) Verify(token string) (string, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return m.signingKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithAudience(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", errors.NewAuthorizationError(fmt.Sprintf("bad session token: %v", err), nil)
+	}
+	if claims.Subject == "" {
+		return "", errors.NewAuthorizationError("bad session token: missing subject", nil)
+	}
+	return claims.Subject, nil
+}
+
+// UnverifiedClientID returns the client ID a token claims to be bound to, without verifying it.
+// It must only be used where the request is authenticated afterwards, e.g. as a rate limiter key.
+func UnverifiedClientID(token string) string {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return ""
+	}
+	return claims.Subject
+}
@@ -0,0 +1,56 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/threefoldtech/tf-kyc-verifier/internal/config"
+)
+
+const testSigningKey = "test-session-signing-key-0123456789"
+
+func TestManager(t *testing.T) {
+	manager := NewManager(config.Session{SigningKey: testSigningKey, TTL: 15}, "test.grid.tf")
+	token, err := manager.Issue("client-1")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), token.ExpiresAt, time.Second)
+
+	clientID, err := manager.Verify(token.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1", clientID)
+
+	tests := []struct {
+		name    string
+		manager *Manager
+		token   string
+	}{
+		{name: "wrong signing key", manager: NewManager(config.Session{SigningKey: "another-session-signing-key-0123456", TTL: 15}, "test.grid.tf"), token: token.Token},
+		{name: "wrong issuer", manager: NewManager(config.Session{SigningKey: testSigningKey, TTL: 15}, "other.grid.tf"), token: token.Token},
+		{name: "malformed token", manager: manager, token: "not-a-token"},
+		{name: "unsigned token", manager: manager, token: "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJzdWIiOiJjbGllbnQtMSJ9."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.manager.Verify(tt.token)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestManagerExpiredToken(t *testing.T) {
+	manager := NewManager(config.Session{SigningKey: testSigningKey, TTL: 15}, "test.grid.tf")
+	manager.ttl = -time.Minute
+	token, err := manager.Issue("client-1")
+	assert.NoError(t, err)
+	_, err = manager.Verify(token.Token)
+	assert.ErrorContains(t, err, "expired")
+}
+
+func TestUnverifiedClientID(t *testing.T) {
+	manager := NewManager(config.Session{SigningKey: testSigningKey, TTL: 15}, "test.grid.tf")
+	token, err := manager.Issue("client-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "client-1", UnverifiedClientID(token.Token))
+	assert.Empty(t, UnverifiedClientID("not-a-token"))
+}
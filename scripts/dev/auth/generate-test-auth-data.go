@@ -58,8 +58,11 @@ func main() {
 }
 
 func createSignMessage(domain string) string {
-	// return a message with the domain and the current timestamp in hex
+	// return a message with the domain, the current timestamp and the nonce from the NONCE env var if set
 	message := fmt.Sprintf("%s:%d", domain, time.Now().Unix())
+	if nonce := os.Getenv("NONCE"); nonce != "" {
+		message = fmt.Sprintf("%s:%s", message, nonce)
+	}
 	fmt.Println("message: ", message)
 	return message
 }